@@ -14,96 +14,515 @@ limitations under the License.
 */
 
 // Accumulator.go tracks running state for aggregate function computation.
-// Supports count, sum, avg, min, and max over numeric types (int32, int64, float32, float64).
+// Results keep the type of the aggregated values where possible: sums of integers are
+// exact and fail on overflow, min and max order numbers, strings, bools and timestamps,
+// and functions over a group without values report nil.
+// The functions beyond count, sum, avg, min and max are implemented in Aggregators.go.
 package interpreter
 
 import (
+	"errors"
 	"math"
+	"reflect"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// ErrOverflow is the underlying error of an integer sum or arithmetic operation that does not fit its type.
+var ErrOverflow = errors.New("Integer overflow")
+
+// Result types of the aggregate functions.
+var (
+	int64Type   = reflect.TypeOf(int64(0))
+	uint64Type  = reflect.TypeOf(uint64(0))
+	float64Type = reflect.TypeOf(float64(0))
+	arrayType   = reflect.TypeOf([]interface{}{})
+)
+
+// aggregator computes a single aggregate function over the values added to it.
+type aggregator interface {
+	add(value interface{}) error       // Incorporates a value, failing if it cannot be aggregated
+	result() interface{}               // The result, or nil if there is none
+	resultType() reflect.Type          // The type of the result, or nil if it is not known yet
+	partial() (*structpb.Value, error) // The state, to be merged into an aggregator of the same function
+	merge(state *structpb.Value) error // Incorporates the state of an aggregator of the same function
+}
+
+// aggregators maps the aggregate function names to the constructors of their aggregators.
+// A constructor receives the modifier and constant arguments of the call.
+var aggregators = map[string]func(fn string, args []string) (aggregator, error){
+	"count":      newCountAggregator,
+	"sum":        newSumAggregator,
+	"avg":        newAvgAggregator,
+	"min":        newMinMaxAggregator,
+	"max":        newMinMaxAggregator,
+	"median":     newPercentileAggregator,
+	"percentile": newPercentileAggregator,
+	"stddev":     newVarianceAggregator,
+	"variance":   newVarianceAggregator,
+	"first":      newFirstAggregator,
+	"last":       newLastAggregator,
+	"collect":    newCollectAggregator,
+
+	"approx_count_distinct": newHllAggregator,
+	"approx_percentile":     newTdigestAggregator,
+}
+
 // Accumulator tracks running state for a single aggregate function.
 type Accumulator struct {
-	fn    string  // "count", "sum", "avg", "min", "max"
-	count int64   // Number of values added
-	sum   float64 // Running sum (for sum, avg)
-	min   float64 // Running minimum
-	max   float64 // Running maximum
-	hasValue bool // Whether any non-nil value has been added
+	fn  string     // The function, e.g. "count", "sum" or "percentile:95"
+	agg aggregator // The aggregator computing the function, nil if the function is unknown
+	err error      // The error that stopped the accumulation, if any
 }
 
 // NewAccumulator creates a new Accumulator for the given function name.
+// An unknown function accumulates nothing and has a nil result.
 func NewAccumulator(fn string) *Accumulator {
-	return &Accumulator{
-		fn:  fn,
-		min: math.MaxFloat64,
-		max: -math.MaxFloat64,
+	acc, _ := newAccumulator(fn, nil)
+	return acc
+}
+
+// newAccumulator creates a new Accumulator for the given function name, a built-in or
+// user-defined aggregate function of the registry, returning an error if the function
+// or its arguments are not supported.
+func newAccumulator(fn string, r *Registry) (*Accumulator, error) {
+	name, args := parser.SplitAggregateFunction(fn)
+	constructor, ok := aggregators[name]
+	if user := r.aggregate(name); !ok && user != nil {
+		constructor, ok = user.newAggregator, true
+	}
+	if !ok {
+		return &Accumulator{fn: fn}, errors.New("Unknown aggregate function: " + name)
 	}
+	agg, e := constructor(name, args)
+	if e != nil {
+		return &Accumulator{fn: fn}, e
+	}
+	return &Accumulator{fn: fn, agg: agg}, nil
 }
 
 // Add incorporates a value into the accumulator.
 // For count(*), pass nil to count all records.
-// Handles int32, int64, float32, float64 value types.
+// A value that cannot be aggregated, such as a string for sum or an integer sum that
+// overflows, stops the accumulation; Err returns the reason and Result returns nil.
 func (a *Accumulator) Add(value interface{}) {
-	a.count++
+	if a.agg != nil && a.err == nil {
+		a.err = a.agg.add(value)
+	}
+}
 
-	if value == nil {
-		return
+// Result returns the final computed value for this accumulator,
+// or nil if no value was aggregated or the accumulation failed.
+func (a *Accumulator) Result() interface{} {
+	if a.agg == nil || a.err != nil {
+		return nil
 	}
+	return a.agg.result()
+}
 
-	num, ok := toFloat64(value)
-	if !ok {
+// ResultType returns the type of the result: int64 for counts, int64, uint64 or float64
+// for sums depending on the values, float64 for averages and statistics, the type of
+// the values for min, max, first and last, and []interface{} for collect.
+// Returns nil while the type depends on values that were not added yet.
+func (a *Accumulator) ResultType() reflect.Type {
+	if a.agg == nil {
+		return nil
+	}
+	return a.agg.resultType()
+}
+
+// Partial returns the state of the accumulator as a protobuf value, to be merged into an
+// accumulator of the same function on another node. Returns the error that stopped the
+// accumulation, if any.
+func (a *Accumulator) Partial() (*structpb.Value, error) {
+	if a.agg == nil {
+		return nil, errors.New("Unknown aggregate function: " + a.fn)
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	return a.agg.partial()
+}
+
+// Merge incorporates the state of an accumulator of the same function, as returned by
+// its Partial method. A state that cannot be merged, or that carries the error of an
+// accumulator that failed on its node, stops the accumulation like Add does.
+func (a *Accumulator) Merge(state *structpb.Value) {
+	if a.agg == nil || a.err != nil {
 		return
 	}
+	if message, ok := state.GetStructValue().GetFields()[partialError]; ok {
+		a.err = &EvaluationError{Message: message.GetStringValue()}
+		return
+	}
+	a.err = a.agg.merge(state)
+}
 
-	a.hasValue = true
-	a.sum += num
-	if num < a.min {
-		a.min = num
+// Err returns the error that stopped the accumulation, or nil.
+// The error is an *EvaluationError without the property and operator set.
+func (a *Accumulator) Err() error {
+	return a.err
+}
+
+// aggregateError creates the error of a value that cannot be aggregated.
+func aggregateError(message string, value interface{}, kind reflect.Kind, err error) error {
+	return &EvaluationError{Message: message, LeftKind: reflect.ValueOf(value).Kind(), RightKind: kind, Err: err}
+}
+
+// countAggregator counts the values added, including nil values.
+type countAggregator struct {
+	count int64 // Number of values added
+}
+
+// newCountAggregator creates the aggregator of count(*), count(field) or count(distinct field).
+func newCountAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) == 1 && args[0] == parser.Distinct {
+		return &distinctAggregator{values: make(map[interface{}]bool)}, nil
 	}
-	if num > a.max {
-		a.max = num
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function count does not accept arguments")
 	}
+	return &countAggregator{}, nil
 }
 
-// Result returns the final computed value for this accumulator.
-func (a *Accumulator) Result() interface{} {
-	switch a.fn {
-	case "count":
-		return a.count
-	case "sum":
-		return a.sum
-	case "avg":
-		if a.count == 0 {
-			return float64(0)
+func (a *countAggregator) add(value interface{}) error {
+	a.count++
+	return nil
+}
+
+func (a *countAggregator) result() interface{} {
+	return a.count
+}
+
+func (a *countAggregator) resultType() reflect.Type {
+	return int64Type
+}
+
+func (a *countAggregator) partial() (*structpb.Value, error) {
+	return encodeValue(a.count)
+}
+
+func (a *countAggregator) merge(state *structpb.Value) error {
+	count, err := decodeValue(state)
+	if err != nil {
+		return err
+	}
+	n, ok := count.(int64)
+	if !ok {
+		return errors.New("Invalid count state")
+	}
+	a.count += n
+	return nil
+}
+
+// sumAggregator sums numeric values. Signed integers are summed as int64 and unsigned
+// integers as uint64, exactly; once a floating point value is added the sum is float64.
+type sumAggregator struct {
+	kind     reflect.Kind // reflect.Int64, reflect.Uint64 or reflect.Float64, reflect.Invalid before any value
+	intSum   int64        // The sum while kind is reflect.Int64
+	uintSum  uint64       // The sum while kind is reflect.Uint64
+	floatSum float64      // The sum while kind is reflect.Float64
+	promote  bool         // Whether an integer overflow switches to a float64 sum instead of failing
+}
+
+// newSumAggregator creates the aggregator of sum(field).
+func newSumAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function sum does not accept arguments")
+	}
+	return &sumAggregator{}, nil
+}
+
+func (a *sumAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v, ok := numberOf(value)
+	if !ok {
+		return aggregateError("Cannot sum a non-numeric value", value, a.kind, nil)
+	}
+	switch {
+	case isFloatValue(v):
+		a.toFloat()
+		a.floatSum += v.Float()
+	case a.kind == reflect.Float64:
+		a.floatSum += floatOf(v)
+	case isIntValue(v) && (a.kind == reflect.Int64 || a.kind == reflect.Invalid || v.Int() < 0):
+		if a.kind == reflect.Uint64 && !a.toInt() {
+			return a.overflow(value, v)
+		}
+		a.kind = reflect.Int64
+		sum := a.intSum + v.Int()
+		if (v.Int() > 0 && sum < a.intSum) || (v.Int() < 0 && sum > a.intSum) {
+			return a.overflow(value, v)
+		}
+		a.intSum = sum
+	case a.kind == reflect.Int64:
+		if v.Uint() > math.MaxInt64 || a.intSum+int64(v.Uint()) < a.intSum {
+			return a.overflow(value, v)
 		}
-		return a.sum / float64(a.count)
-	case "min":
-		if !a.hasValue {
-			return float64(0)
+		a.intSum += int64(v.Uint())
+	default:
+		// An unsigned value, or a non-negative signed value, added to an unsigned sum
+		u := v.Uint()
+		if isIntValue(v) {
+			u = uint64(v.Int())
 		}
-		return a.min
-	case "max":
-		if !a.hasValue {
-			return float64(0)
+		a.kind = reflect.Uint64
+		if a.uintSum+u < a.uintSum {
+			return a.overflow(value, v)
 		}
-		return a.max
+		a.uintSum += u
 	}
 	return nil
 }
 
-// toFloat64 converts a numeric value to float64 for accumulation.
+// toInt switches an unsigned sum to a signed sum, returning false if it does not fit.
+func (a *sumAggregator) toInt() bool {
+	if a.uintSum > math.MaxInt64 {
+		return false
+	}
+	a.kind = reflect.Int64
+	a.intSum = int64(a.uintSum)
+	return true
+}
+
+// toFloat switches the sum to a float64 sum.
+func (a *sumAggregator) toFloat() {
+	switch a.kind {
+	case reflect.Int64:
+		a.floatSum = float64(a.intSum)
+	case reflect.Uint64:
+		a.floatSum = float64(a.uintSum)
+	}
+	a.kind = reflect.Float64
+}
+
+// overflow handles an integer overflow when adding the value: the sum continues as a
+// float64 sum if promotion is enabled, otherwise an error wrapping ErrOverflow is returned.
+func (a *sumAggregator) overflow(value interface{}, v reflect.Value) error {
+	if !a.promote {
+		return aggregateError("Integer sum does not fit in "+a.kind.String(), value, a.kind, ErrOverflow)
+	}
+	a.toFloat()
+	a.floatSum += floatOf(v)
+	return nil
+}
+
+func (a *sumAggregator) result() interface{} {
+	switch a.kind {
+	case reflect.Int64:
+		return a.intSum
+	case reflect.Uint64:
+		return a.uintSum
+	case reflect.Float64:
+		return a.floatSum
+	}
+	return nil
+}
+
+func (a *sumAggregator) resultType() reflect.Type {
+	switch a.kind {
+	case reflect.Int64:
+		return int64Type
+	case reflect.Uint64:
+		return uint64Type
+	case reflect.Float64:
+		return float64Type
+	}
+	return nil
+}
+
+func (a *sumAggregator) partial() (*structpb.Value, error) {
+	return encodeValue(a.result())
+}
+
+// merge adds the sum of another aggregator, with the same overflow detection as add.
+func (a *sumAggregator) merge(state *structpb.Value) error {
+	sum, err := decodeValue(state)
+	if err != nil {
+		return err
+	}
+	return a.add(sum)
+}
+
+// floatValue returns the sum as a float64.
+func (a *sumAggregator) floatValue() float64 {
+	switch a.kind {
+	case reflect.Int64:
+		return float64(a.intSum)
+	case reflect.Uint64:
+		return float64(a.uintSum)
+	}
+	return a.floatSum
+}
+
+// avgAggregator computes the average of the numeric values. Integers are summed exactly
+// while the sum fits in 64 bits.
+type avgAggregator struct {
+	sum   sumAggregator // The sum of the values
+	count int64         // Number of values added
+}
+
+// newAvgAggregator creates the aggregator of avg(field).
+func newAvgAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function avg does not accept arguments")
+	}
+	return &avgAggregator{sum: sumAggregator{promote: true}}, nil
+}
+
+func (a *avgAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if e := a.sum.add(value); e != nil {
+		return e
+	}
+	a.count++
+	return nil
+}
+
+func (a *avgAggregator) result() interface{} {
+	if a.count == 0 {
+		return nil
+	}
+	return a.sum.floatValue() / float64(a.count)
+}
+
+func (a *avgAggregator) resultType() reflect.Type {
+	return float64Type
+}
+
+func (a *avgAggregator) partial() (*structpb.Value, error) {
+	sum, err := a.sum.partial()
+	if err != nil {
+		return nil, err
+	}
+	count, err := encodeValue(a.count)
+	if err != nil {
+		return nil, err
+	}
+	return newState(map[string]*structpb.Value{"sum": sum, "count": count}), nil
+}
+
+func (a *avgAggregator) merge(state *structpb.Value) error {
+	fields := state.GetStructValue().GetFields()
+	if err := a.sum.merge(fields["sum"]); err != nil {
+		return err
+	}
+	count := countAggregator{}
+	if err := count.merge(fields["count"]); err != nil {
+		return err
+	}
+	a.count += count.count
+	return nil
+}
+
+// orderClass identifies the values that min and max can order against each other.
+type orderClass int
+
+const (
+	unordered    orderClass = iota // Values that have no order
+	orderNumbers                   // Signed, unsigned and floating point numbers
+	orderStrings                   // Strings
+	orderBools                     // Booleans, false before true
+	orderTimes                     // time.Time, *time.Time and timestamps with an AsTime method
+)
+
+// orderClassOf returns the order class of a non-nil value.
+func orderClassOf(value interface{}) orderClass {
+	if _, ok := timeOf(value); ok {
+		return orderTimes
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case isNumberValue(v):
+		return orderNumbers
+	case v.Kind() == reflect.String:
+		return orderStrings
+	case v.Kind() == reflect.Bool:
+		return orderBools
+	}
+	return unordered
+}
+
+// minMaxAggregator keeps the smallest or largest value, with its original type.
+type minMaxAggregator struct {
+	max   bool        // Whether the largest rather than the smallest value is kept
+	value interface{} // The smallest or largest value so far, or nil
+	class orderClass  // The order class of the values
+}
+
+// newMinMaxAggregator creates the aggregator of min(field) or max(field).
+func newMinMaxAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function " + fn + " does not accept arguments")
+	}
+	return &minMaxAggregator{max: fn == "max"}, nil
+}
+
+func (a *minMaxAggregator) add(value interface{}) error {
+	if isNilValue(reflect.ValueOf(value)) {
+		return nil
+	}
+	class := orderClassOf(value)
+	if class == unordered {
+		return aggregateError("Value has no order", value, reflect.ValueOf(a.value).Kind(), nil)
+	}
+	if a.value == nil {
+		a.value = value
+		a.class = class
+		return nil
+	}
+	if class != a.class {
+		return aggregateError("Cannot order values of different types", value, reflect.ValueOf(a.value).Kind(), nil)
+	}
+	c := compareValues(value, a.value)
+	if (a.max && c > 0) || (!a.max && c < 0) {
+		a.value = value
+	}
+	return nil
+}
+
+func (a *minMaxAggregator) result() interface{} {
+	return a.value
+}
+
+func (a *minMaxAggregator) resultType() reflect.Type {
+	return reflect.TypeOf(a.value)
+}
+
+func (a *minMaxAggregator) partial() (*structpb.Value, error) {
+	return encodeValue(a.value)
+}
+
+func (a *minMaxAggregator) merge(state *structpb.Value) error {
+	value, err := decodeValue(state)
+	if err != nil {
+		return err
+	}
+	return a.add(value)
+}
+
+// numberOf returns the value, dereferencing a pointer, if it is numeric.
+func numberOf(value interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v, isNumberValue(v)
+}
+
+// toFloat64 converts a numeric value of any integer or floating point type to float64.
 func toFloat64(value interface{}) (float64, bool) {
-	switch v := value.(type) {
-	case int32:
-		return float64(v), true
-	case int64:
-		return float64(v), true
-	case float32:
-		return float64(v), true
-	case float64:
-		return v, true
-	case int:
-		return float64(v), true
-	}
-	return 0, false
+	v, ok := numberOf(value)
+	if !ok {
+		return 0, false
+	}
+	return floatOf(v), true
 }
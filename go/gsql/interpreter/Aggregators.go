@@ -0,0 +1,354 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Aggregators.go implements the aggregate functions beyond the numeric count, sum, avg,
+// min and max: count(distinct), median, percentile, stddev, variance, first, last and collect.
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// distinctAggregator counts the distinct non-nil values.
+type distinctAggregator struct {
+	values map[interface{}]bool // The distinct values seen, keyed by distinctKey
+}
+
+func (a *distinctAggregator) add(value interface{}) error {
+	if value != nil {
+		a.values[distinctKey(value)] = true
+	}
+	return nil
+}
+
+func (a *distinctAggregator) result() interface{} {
+	return int64(len(a.values))
+}
+
+func (a *distinctAggregator) resultType() reflect.Type {
+	return int64Type
+}
+
+// partial returns the distinct values, sorted by their string representation.
+func (a *distinctAggregator) partial() (*structpb.Value, error) {
+	values := make([]interface{}, 0, len(a.values))
+	for value := range a.values {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return fmt.Sprint(values[i]) < fmt.Sprint(values[j])
+	})
+	return encodeValues(values)
+}
+
+func (a *distinctAggregator) merge(state *structpb.Value) error {
+	values, err := decodeValues(state)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		a.add(value)
+	}
+	return nil
+}
+
+// distinctKey returns a map key identifying the value. Scalars are their own key,
+// other values are keyed by their type and string representation.
+func distinctKey(value interface{}) interface{} {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return value
+	}
+	return fmt.Sprintf("%T:%v", value, value)
+}
+
+// percentileAggregator computes the median or a percentile of the numeric values,
+// interpolating linearly between the two closest ranks. The result is nil without values.
+type percentileAggregator struct {
+	percentile float64   // The percentile to compute, between 0 and 100
+	values     []float64 // The numeric values added
+}
+
+// newPercentileAggregator creates the aggregator of median(field) or percentile(field, p).
+func newPercentileAggregator(fn string, args []string) (aggregator, error) {
+	if fn == "median" {
+		if len(args) > 0 {
+			return nil, errors.New("Aggregate function median does not accept arguments")
+		}
+		return &percentileAggregator{percentile: 50}, nil
+	}
+	if len(args) != 1 {
+		return nil, errors.New("Aggregate function percentile expects a percentile argument")
+	}
+	p, e := strconv.ParseFloat(args[0], 64)
+	if e != nil || p < 0 || p > 100 {
+		return nil, errors.New("Percentile must be a number between 0 and 100: " + args[0])
+	}
+	return &percentileAggregator{percentile: p}, nil
+}
+
+func (a *percentileAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	num, ok := toFloat64(value)
+	if !ok {
+		return aggregateError("Cannot compute a percentile of a non-numeric value", value, reflect.Float64, nil)
+	}
+	a.values = append(a.values, num)
+	return nil
+}
+
+func (a *percentileAggregator) result() interface{} {
+	if len(a.values) == 0 {
+		return nil
+	}
+	sort.Float64s(a.values)
+	rank := a.percentile / 100 * float64(len(a.values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return a.values[lower] + (a.values[upper]-a.values[lower])*(rank-float64(lower))
+}
+
+func (a *percentileAggregator) resultType() reflect.Type {
+	return float64Type
+}
+
+// partial returns all the values, as the exact percentile cannot be computed from less.
+func (a *percentileAggregator) partial() (*structpb.Value, error) {
+	list := make([]*structpb.Value, 0, len(a.values))
+	for _, value := range a.values {
+		list = append(list, structpb.NewNumberValue(value))
+	}
+	return structpb.NewListValue(&structpb.ListValue{Values: list}), nil
+}
+
+func (a *percentileAggregator) merge(state *structpb.Value) error {
+	for _, value := range state.GetListValue().GetValues() {
+		a.values = append(a.values, value.GetNumberValue())
+	}
+	return nil
+}
+
+// varianceAggregator computes the sample variance or standard deviation of the numeric
+// values, using Welford's online algorithm. The result is nil for fewer than two values.
+type varianceAggregator struct {
+	stddev bool    // Whether the result is the standard deviation rather than the variance
+	count  int64   // Number of numeric values added
+	mean   float64 // Running mean
+	m2     float64 // Running sum of squared differences from the mean
+}
+
+// newVarianceAggregator creates the aggregator of stddev(field) or variance(field).
+func newVarianceAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function " + fn + " does not accept arguments")
+	}
+	return &varianceAggregator{stddev: fn == "stddev"}, nil
+}
+
+func (a *varianceAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	num, ok := toFloat64(value)
+	if !ok {
+		return aggregateError("Cannot compute the variance of a non-numeric value", value, reflect.Float64, nil)
+	}
+	a.count++
+	delta := num - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (num - a.mean)
+	return nil
+}
+
+func (a *varianceAggregator) result() interface{} {
+	if a.count < 2 {
+		return nil
+	}
+	variance := a.m2 / float64(a.count-1)
+	if a.stddev {
+		return math.Sqrt(variance)
+	}
+	return variance
+}
+
+func (a *varianceAggregator) resultType() reflect.Type {
+	return float64Type
+}
+
+func (a *varianceAggregator) partial() (*structpb.Value, error) {
+	count, err := encodeValue(a.count)
+	if err != nil {
+		return nil, err
+	}
+	return newState(map[string]*structpb.Value{
+		"count": count,
+		"mean":  structpb.NewNumberValue(a.mean),
+		"m2":    structpb.NewNumberValue(a.m2),
+	}), nil
+}
+
+// merge combines the state of another aggregator using the parallel variant of
+// Welford's algorithm.
+func (a *varianceAggregator) merge(state *structpb.Value) error {
+	fields := state.GetStructValue().GetFields()
+	count := countAggregator{}
+	if err := count.merge(fields["count"]); err != nil {
+		return err
+	}
+	if count.count == 0 {
+		return nil
+	}
+	total := a.count + count.count
+	mean := fields["mean"].GetNumberValue()
+	delta := mean - a.mean
+	a.m2 += fields["m2"].GetNumberValue() + delta*delta*float64(a.count)*float64(count.count)/float64(total)
+	a.mean += delta * float64(count.count) / float64(total)
+	a.count = total
+	return nil
+}
+
+// firstAggregator keeps the first non-nil value, in the order the values are added.
+type firstAggregator struct {
+	value interface{} // The first non-nil value, or nil
+}
+
+// newFirstAggregator creates the aggregator of first(field).
+func newFirstAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function first does not accept arguments")
+	}
+	return &firstAggregator{}, nil
+}
+
+func (a *firstAggregator) add(value interface{}) error {
+	if a.value == nil {
+		a.value = value
+	}
+	return nil
+}
+
+func (a *firstAggregator) result() interface{} {
+	return a.value
+}
+
+func (a *firstAggregator) resultType() reflect.Type {
+	return reflect.TypeOf(a.value)
+}
+
+func (a *firstAggregator) partial() (*structpb.Value, error) {
+	return encodeValue(a.value)
+}
+
+// merge keeps the value of this aggregator, unless it has none.
+func (a *firstAggregator) merge(state *structpb.Value) error {
+	value, err := decodeValue(state)
+	if err != nil {
+		return err
+	}
+	return a.add(value)
+}
+
+// lastAggregator keeps the last non-nil value, in the order the values are added.
+type lastAggregator struct {
+	value interface{} // The last non-nil value, or nil
+}
+
+// newLastAggregator creates the aggregator of last(field).
+func newLastAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function last does not accept arguments")
+	}
+	return &lastAggregator{}, nil
+}
+
+func (a *lastAggregator) add(value interface{}) error {
+	if value != nil {
+		a.value = value
+	}
+	return nil
+}
+
+func (a *lastAggregator) result() interface{} {
+	return a.value
+}
+
+func (a *lastAggregator) resultType() reflect.Type {
+	return reflect.TypeOf(a.value)
+}
+
+func (a *lastAggregator) partial() (*structpb.Value, error) {
+	return encodeValue(a.value)
+}
+
+// merge takes the value of the other aggregator, unless it has none.
+func (a *lastAggregator) merge(state *structpb.Value) error {
+	value, err := decodeValue(state)
+	if err != nil {
+		return err
+	}
+	return a.add(value)
+}
+
+// collectAggregator collects the non-nil values into an array, in the order they are added.
+type collectAggregator struct {
+	values []interface{} // The values added
+}
+
+// newCollectAggregator creates the aggregator of collect(field).
+func newCollectAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, errors.New("Aggregate function collect does not accept arguments")
+	}
+	return &collectAggregator{values: make([]interface{}, 0)}, nil
+}
+
+func (a *collectAggregator) add(value interface{}) error {
+	if value != nil {
+		a.values = append(a.values, value)
+	}
+	return nil
+}
+
+func (a *collectAggregator) result() interface{} {
+	return a.values
+}
+
+func (a *collectAggregator) resultType() reflect.Type {
+	return arrayType
+}
+
+func (a *collectAggregator) partial() (*structpb.Value, error) {
+	return encodeValues(a.values)
+}
+
+func (a *collectAggregator) merge(state *structpb.Value) error {
+	values, err := decodeValues(state)
+	if err != nil {
+		return err
+	}
+	a.values = append(a.values, values...)
+	return nil
+}
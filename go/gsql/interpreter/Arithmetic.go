@@ -0,0 +1,380 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Arithmetic.go evaluates the arithmetic expressions and scalar function calls of computed
+// columns, comparison operands, group-by columns and sort keys over the values of resolved
+// properties or result row columns.
+package interpreter
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"github.com/saichler/l8reflect/go/reflect/properties"
+)
+
+// ErrDivisionByZero is the error of a division or remainder by zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Arithmetic is an interpreted arithmetic expression. Integer operands are promoted to
+// int64 and unsigned operands to uint64; an operation over a signed and an unsigned
+// operand is signed when the unsigned value fits int64, and any float operand promotes
+// the operation to float64. Division always returns a float64, so a ratio of integers
+// keeps its fraction, while the remainder of integers is an integer.
+// A nil operand makes the whole operation nil, while the scalar functions define their own
+// handling of nil arguments.
+type Arithmetic struct {
+	operation parser.ArithmeticOperation // The operator, empty for an operand or call
+	function  scalarFunction             // The implementation of a scalar function call
+	operands  []*Arithmetic              // The operands of an operation, or the arguments of a call
+	text      string                     // The text of the expression
+	property  *properties.Property       // Resolved property of a property operand
+	column    string                     // Resolved row column of a column operand
+	value     interface{}                // The int64, uint64, float64, bool or string value of a literal operand
+}
+
+// newArithmetic creates an interpreted arithmetic expression, resolving its operands
+// against the scope. Returns an error if an operand is neither a number nor a property
+// or column of the scope.
+func newArithmetic(expr *parser.ArithmeticExpression, scope *expressionScope) (*Arithmetic, error) {
+	this := &Arithmetic{operation: expr.Operation, text: expr.String()}
+	if scope.columns != nil && scope.columns[columnReference(this.text)] != "" {
+		// A computed group-by column of the aggregate rows
+		this.column = scope.columns[columnReference(this.text)]
+		return this, nil
+	}
+	var user *UserFunction
+	if expr.Function != "" {
+		this.function = scalarFunctions[expr.Function]
+		if this.function == nil {
+			user = scope.registry.function(expr.Function)
+			if user == nil {
+				return nil, errors.New("Unknown function " + expr.Function)
+			}
+			if err := user.checkArgs(expr.Function, len(expr.Operands)); err != nil {
+				return nil, err
+			}
+			this.function = user.scalar(expr.Function)
+		}
+	}
+	if expr.Operation != "" || expr.Function != "" {
+		for i, operand := range expr.Operands {
+			a, err := newArithmetic(operand, scope)
+			if err != nil {
+				return nil, err
+			}
+			if user != nil && a.isLiteral() {
+				typ, _ := user.argType(i)
+				if _, err = argumentOf(a.value, typ); err != nil {
+					return nil, errors.New("Invalid argument " + strconv.Itoa(i+1) + " of " + expr.Function + ": " + err.Error())
+				}
+			}
+			this.operands = append(this.operands, a)
+		}
+		return this, nil
+	}
+	if expr.Quoted {
+		this.value = expr.Operand
+		return this, nil
+	}
+	if expr.Literal && (expr.Operand == "true" || expr.Operand == "false") {
+		this.value = expr.Operand == "true"
+		return this, nil
+	}
+	if expr.Literal {
+		value, err := numberLiteral(expr.Operand)
+		if err != nil {
+			return nil, err
+		}
+		this.value = value
+		return this, nil
+	}
+	if scope.columns != nil {
+		this.column = scope.columns[columnReference(expr.Operand)]
+		if this.column == "" {
+			return nil, errors.New("No Column was found for operand " + expr.Operand)
+		}
+		return this, nil
+	}
+	prop, err := properties.PropertyOf(propertyPath(expr.Operand, scope.rootTable.TypeName), scope.resources)
+	if err != nil || prop == nil {
+		return nil, errors.New("No Field was found for operand " + expr.Operand)
+	}
+	this.property = prop
+	return this, nil
+}
+
+// numberLiteral returns the int64, uint64 or float64 value of a numeric literal.
+func numberLiteral(text string) (interface{}, error) {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return u, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, errors.New("Invalid number " + text)
+	}
+	return f, nil
+}
+
+// computed reports whether the expression is an operation or a function call,
+// rather than a single property, column or literal.
+func (this *Arithmetic) computed() bool {
+	return this.operation != "" || this.function != nil
+}
+
+// isLiteral reports whether the expression is a single literal value.
+func (this *Arithmetic) isLiteral() bool {
+	return !this.computed() && this.property == nil && this.column == ""
+}
+
+// String returns the canonical text of the expression.
+func (this *Arithmetic) String() string {
+	return this.text
+}
+
+// Evaluate returns the value of the expression for the object or result row.
+// Returns an error for operands that are not numbers, for repeated values,
+// for a division by zero, for integer overflows and for invalid function arguments.
+func (this *Arithmetic) Evaluate(root interface{}) (interface{}, error) {
+	switch {
+	case this.function != nil:
+		args := make([]interface{}, len(this.operands))
+		for i, operand := range this.operands {
+			v, err := operand.Evaluate(root)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		return this.function(args)
+	case this.operation != "":
+		values := make([]interface{}, len(this.operands))
+		for i, operand := range this.operands {
+			v, err := operand.Evaluate(root)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		if len(values) == 1 {
+			return negate(values[0])
+		}
+		return calculate(this.operation, values[0], values[1])
+	case this.property != nil:
+		return this.property.Get(root)
+	case this.column != "":
+		row, ok := root.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Column " + this.column + " requires a result row")
+		}
+		return row[this.column], nil
+	}
+	return this.value, nil
+}
+
+// numberClass is the promoted type of an arithmetic operand.
+type numberClass int
+
+const (
+	signedNumber   numberClass = iota // Promoted to int64
+	unsignedNumber                    // Promoted to uint64
+	floatNumber                       // Promoted to float64
+)
+
+// number is an arithmetic operand promoted to its class.
+type number struct {
+	class numberClass
+	i     int64
+	u     uint64
+	f     float64
+}
+
+// arithmeticOperand promotes a value to an arithmetic operand. Returns an error for values that are not numbers.
+func arithmeticOperand(value interface{}) (number, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{class: signedNumber, i: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{class: unsignedNumber, u: v.Uint()}, nil
+	case reflect.Float32, reflect.Float64:
+		return number{class: floatNumber, f: v.Float()}, nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return number{}, errors.New("Cannot use repeated value of kind " + v.Kind().String() + " in arithmetic")
+	}
+	return number{}, errors.New("Cannot use value of kind " + v.Kind().String() + " in arithmetic")
+}
+
+// float returns the operand as a float64.
+func (this number) float() float64 {
+	switch this.class {
+	case signedNumber:
+		return float64(this.i)
+	case unsignedNumber:
+		return float64(this.u)
+	}
+	return this.f
+}
+
+// promote converts both operands to the class of the operation over them.
+func promote(a, b number) (number, number) {
+	if a.class == b.class {
+		return a, b
+	}
+	if a.class == floatNumber || b.class == floatNumber {
+		return number{class: floatNumber, f: a.float()}, number{class: floatNumber, f: b.float()}
+	}
+	if a.class == unsignedNumber {
+		b, a = promote(b, a)
+		return a, b
+	}
+	// a is signed and b is unsigned
+	if b.u <= math.MaxInt64 {
+		return a, number{class: signedNumber, i: int64(b.u)}
+	}
+	if a.i >= 0 {
+		return number{class: unsignedNumber, u: uint64(a.i)}, b
+	}
+	return number{class: floatNumber, f: a.float()}, number{class: floatNumber, f: b.float()}
+}
+
+// negate returns the negation of the value. A negated unsigned value is an int64.
+func negate(value interface{}) (interface{}, error) {
+	n, err := arithmeticOperand(value)
+	if err != nil {
+		return nil, err
+	}
+	switch n.class {
+	case signedNumber:
+		if n.i == math.MinInt64 {
+			return nil, ErrOverflow
+		}
+		return -n.i, nil
+	case unsignedNumber:
+		if n.u > 1<<63 {
+			return nil, ErrOverflow
+		}
+		return int64(-n.u), nil
+	}
+	return -n.f, nil
+}
+
+// calculate applies a binary operation to the values.
+func calculate(op parser.ArithmeticOperation, left, right interface{}) (interface{}, error) {
+	a, err := arithmeticOperand(left)
+	if err != nil {
+		return nil, err
+	}
+	b, err := arithmeticOperand(right)
+	if err != nil {
+		return nil, err
+	}
+	if op == parser.Divide {
+		if b.float() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a.float() / b.float(), nil
+	}
+	a, b = promote(a, b)
+	switch a.class {
+	case signedNumber:
+		return calculateSigned(op, a.i, b.i)
+	case unsignedNumber:
+		return calculateUnsigned(op, a.u, b.u)
+	}
+	return calculateFloat(op, a.f, b.f)
+}
+
+// calculateSigned applies a binary operation to int64 operands.
+func calculateSigned(op parser.ArithmeticOperation, a, b int64) (interface{}, error) {
+	switch op {
+	case parser.Plus:
+		if b > 0 && a > math.MaxInt64-b || b < 0 && a < math.MinInt64-b {
+			return nil, ErrOverflow
+		}
+		return a + b, nil
+	case parser.Minus:
+		if b < 0 && a > math.MaxInt64+b || b > 0 && a < math.MinInt64+b {
+			return nil, ErrOverflow
+		}
+		return a - b, nil
+	case parser.Times:
+		if a != 0 && ((a*b)/a != b || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64) {
+			return nil, ErrOverflow
+		}
+		return a * b, nil
+	case parser.Modulo:
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a % b, nil
+	}
+	return nil, errors.New("Unsupported arithmetic operation " + string(op))
+}
+
+// calculateUnsigned applies a binary operation to uint64 operands.
+// A subtraction with a negative result returns an int64.
+func calculateUnsigned(op parser.ArithmeticOperation, a, b uint64) (interface{}, error) {
+	switch op {
+	case parser.Plus:
+		if a+b < a {
+			return nil, ErrOverflow
+		}
+		return a + b, nil
+	case parser.Minus:
+		if a >= b {
+			return a - b, nil
+		}
+		if b-a > 1<<63 {
+			return nil, ErrOverflow
+		}
+		return int64(-(b - a)), nil
+	case parser.Times:
+		if a != 0 && (a*b)/a != b {
+			return nil, ErrOverflow
+		}
+		return a * b, nil
+	case parser.Modulo:
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a % b, nil
+	}
+	return nil, errors.New("Unsupported arithmetic operation " + string(op))
+}
+
+// calculateFloat applies a binary operation to float64 operands.
+func calculateFloat(op parser.ArithmeticOperation, a, b float64) (interface{}, error) {
+	switch op {
+	case parser.Plus:
+		return a + b, nil
+	case parser.Minus:
+		return a - b, nil
+	case parser.Times:
+		return a * b, nil
+	case parser.Modulo:
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, errors.New("Unsupported arithmetic operation " + string(op))
+}
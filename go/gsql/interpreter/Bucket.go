@@ -0,0 +1,210 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Bucket.go groups timestamps into the intervals of the bucket and date_trunc group-by
+// functions, and fills the gaps between the buckets of the aggregate rows.
+package interpreter
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+)
+
+// MaxFilledBuckets is the largest number of empty buckets gap filling adds to the rows.
+const MaxFilledBuckets = 100000
+
+// millisThreshold is the magnitude from which numeric timestamps of unknown unit are taken
+// as milliseconds; as seconds it would be past the year 5000.
+const millisThreshold = 100000000000
+
+// bucketOf returns the start of the bucket of a timestamp. Timestamps are time.Time values,
+// timestamp messages with an AsTime method, or integers counting seconds or milliseconds
+// since the epoch. Integer buckets have the type and unit of the value, and other buckets
+// are time.Time values in UTC. The bucket of nil is nil.
+func bucketOf(expr *parser.GroupExpression, value interface{}) (interface{}, error) {
+	if isNilValue(reflect.ValueOf(value)) {
+		return nil, nil
+	}
+	if t, ok := timeOf(value); ok {
+		return truncate(expr, t), nil
+	}
+	v := reflect.ValueOf(value)
+	var epoch int64
+	switch {
+	case isIntValue(v):
+		epoch = v.Int()
+	case isUintValue(v):
+		epoch = int64(v.Uint())
+	default:
+		return nil, errors.New("Value of type " + v.Type().String() + " is not a timestamp")
+	}
+	millis := expr.Epoch == parser.EpochMillis
+	if expr.Epoch == "" {
+		millis = epoch >= millisThreshold || epoch <= -millisThreshold
+	}
+	if millis {
+		bucket := truncate(expr, time.UnixMilli(epoch)).UnixMilli()
+		return reflect.ValueOf(bucket).Convert(v.Type()).Interface(), nil
+	}
+	bucket := truncate(expr, time.Unix(epoch, 0)).Unix()
+	return reflect.ValueOf(bucket).Convert(v.Type()).Interface(), nil
+}
+
+// truncate returns the start of the bucket of a time, in UTC. Buckets of bucket() are
+// aligned to the epoch, and weeks of date_trunc() start on Monday.
+func truncate(expr *parser.GroupExpression, t time.Time) time.Time {
+	t = t.UTC()
+	if expr.Function == parser.BucketFunction {
+		nanos := t.UnixNano()
+		offset := nanos % int64(expr.Width)
+		if offset < 0 {
+			offset += int64(expr.Width)
+		}
+		return time.Unix(0, nanos-offset).UTC()
+	}
+	switch expr.Unit {
+	case "second":
+		return t.Truncate(time.Second)
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextBucket returns the start of the bucket following the given bucket.
+func nextBucket(expr *parser.GroupExpression, bucket interface{}) interface{} {
+	if t, ok := bucket.(time.Time); ok {
+		return advance(expr, t)
+	}
+	v := reflect.ValueOf(bucket)
+	epoch := int64(0)
+	if isIntValue(v) {
+		epoch = v.Int()
+	} else {
+		epoch = int64(v.Uint())
+	}
+	millis := expr.Epoch == parser.EpochMillis
+	if expr.Epoch == "" {
+		millis = epoch >= millisThreshold || epoch <= -millisThreshold
+	}
+	if millis {
+		return reflect.ValueOf(advance(expr, time.UnixMilli(epoch)).UnixMilli()).Convert(v.Type()).Interface()
+	}
+	return reflect.ValueOf(advance(expr, time.Unix(epoch, 0)).Unix()).Convert(v.Type()).Interface()
+}
+
+// advance returns the start of the bucket following the bucket starting at t.
+func advance(expr *parser.GroupExpression, t time.Time) time.Time {
+	t = t.UTC()
+	if expr.Function == parser.BucketFunction {
+		return t.Add(expr.Width)
+	}
+	switch expr.Unit {
+	case "second":
+		return t.Add(time.Second)
+	case "minute":
+		return t.Add(time.Minute)
+	case "hour":
+		return t.Add(time.Hour)
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(1, 0, 0)
+}
+
+// fillGaps adds an empty group for each missing bucket of the group-by columns that fill
+// gaps. Buckets are filled between the first and last bucket of each combination of the
+// values of the other group-by columns, in each grouping set where the column is not rolled up.
+func (this *Query) fillGaps(groups []*aggregateGroup) ([]*aggregateGroup, error) {
+	filled := 0
+	for i, expr := range this.groupByExprs {
+		if !expr.Fill {
+			continue
+		}
+		column := this.groupBy[i]
+		// Partition the groups by the values of the other group-by columns
+		partitions := make(map[string][]*aggregateGroup)
+		order := make([]string, 0)
+		for _, group := range groups {
+			if this.isRolledUp(group, i) {
+				continue
+			}
+			key := strconv.FormatInt(group.grouping, 10) + "|"
+			for j, gb := range this.groupBy {
+				if j != i {
+					key += groupValueKey(group.keys[gb]) + "|"
+				}
+			}
+			if _, ok := partitions[key]; !ok {
+				order = append(order, key)
+			}
+			partitions[key] = append(partitions[key], group)
+		}
+		for _, key := range order {
+			present := make(map[string]bool)
+			var first, last interface{}
+			for _, group := range partitions[key] {
+				bucket := group.keys[column]
+				if bucket == nil {
+					continue
+				}
+				present[groupValueKey(bucket)] = true
+				if first == nil || compareValues(bucket, first) < 0 {
+					first = bucket
+				}
+				if last == nil || compareValues(bucket, last) > 0 {
+					last = bucket
+				}
+			}
+			if first == nil {
+				continue
+			}
+			template := partitions[key][0]
+			for bucket := nextBucket(expr, first); compareValues(bucket, last) < 0; bucket = nextBucket(expr, bucket) {
+				if present[groupValueKey(bucket)] {
+					continue
+				}
+				filled++
+				if filled > MaxFilledBuckets {
+					return nil, errors.New("Gap filling of " + column + " exceeds " + strconv.Itoa(MaxFilledBuckets) + " buckets")
+				}
+				keys := make(map[string]interface{}, len(template.keys))
+				for k, v := range template.keys {
+					keys[k] = v
+				}
+				keys[column] = bucket
+				groups = append(groups, this.newAggregateGroup(this.groupId(keys), keys, template.grouping))
+			}
+		}
+	}
+	return groups, nil
+}
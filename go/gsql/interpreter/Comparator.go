@@ -31,17 +31,50 @@ import (
 // It holds the left and right operands (either literal values or property references)
 // and the comparison operation to perform.
 type Comparator struct {
-	left          string                     // Left operand as string (property name or literal)
-	leftProperty  *properties.Property       // Resolved property for left operand (if applicable)
-	operation     parser.ComparatorOperation // The comparison operation (=, !=, >, <, etc.)
-	right         string                     // Right operand as string (property name or literal)
-	rightProperty *properties.Property       // Resolved property for right operand (if applicable)
+	left            string                     // Left operand as string (property name or literal)
+	leftProperty    *properties.Property       // Resolved property for left operand (if applicable)
+	leftColumn      string                     // Resolved row column for left operand (if applicable)
+	leftArithmetic  *Arithmetic                // Arithmetic expression of the left operand (if applicable)
+	operation       parser.ComparatorOperation // The comparison operation (=, !=, >, <, etc.)
+	right           string                     // Right operand as string (property name or literal)
+	rightProperty   *properties.Property       // Resolved property for right operand (if applicable)
+	rightColumn     string                     // Resolved row column for right operand (if applicable)
+	rightArithmetic *Arithmetic                // Arithmetic expression of the right operand (if applicable)
+	user            Comparable                 // The user-defined comparator of the operation (if applicable)
+	like            *comparators.Like          // The like, ilike or glob comparator, with the escape of the comparison (if applicable)
+	pattern         *comparators.Pattern       // Compiled pattern of a literal right operand of like, ilike or glob
+	notLike         bool                       // Whether the like, ilike or glob comparison is negated by not
+	strict          bool                       // Compare strings exactly with = and != if true, see Query.SetStrictEquality
+}
+
+// expressionScope holds what the operands of an expression are resolved against while
+// the expression is created, and collects the interpreted comparators.
+type expressionScope struct {
+	rootTable   *l8reflect.L8Node                   // The root type for property operands
+	resources   ifs.IResources                      // Resources for introspection
+	registry    *Registry                           // User-defined extensions, nil if there are none
+	columns     map[string]string                   // Row columns by lowercase reference, for expressions over rows
+	comparators map[*l8api.L8Comparator]*Comparator // The interpreted comparators by parsed comparator
+}
+
+// newExpressionScope creates a scope that resolves operands as properties of the root type.
+func newExpressionScope(rootTable *l8reflect.L8Node, resources ifs.IResources, registry *Registry) *expressionScope {
+	return &expressionScope{rootTable: rootTable, resources: resources, registry: registry,
+		comparators: make(map[*l8api.L8Comparator]*Comparator)}
+}
+
+// newRowScope creates a scope that resolves operands as columns of result rows,
+// such as the aggregate rows filtered by HAVING.
+func newRowScope(columns map[string]string, resources ifs.IResources, registry *Registry) *expressionScope {
+	return &expressionScope{resources: resources, registry: registry, columns: columns,
+		comparators: make(map[*l8api.L8Comparator]*Comparator)}
 }
 
 // Comparable is the interface implemented by comparison operators.
 // Each comparator operation has a corresponding Comparable implementation.
+// Compare returns an error when it cannot compare the operands.
 type Comparable interface {
-	Compare(interface{}, interface{}) bool
+	Compare(interface{}, interface{}) (bool, error)
 }
 
 // comparables maps comparison operations to their implementations.
@@ -57,6 +90,33 @@ func init() {
 	comparables[parser.LT] = comparators.NewLessThan()
 	comparables[parser.GTEQ] = comparators.NewGreaterThanOrEqual()
 	comparables[parser.LTEQ] = comparators.NewLessThanOrEqual()
+	comparables[parser.LIKE] = comparators.NewLike()
+	comparables[parser.ILIKE] = comparators.NewILike()
+	comparables[parser.GLOB] = comparators.NewGlob()
+}
+
+// strictEqual and strictNotEqual are the comparators of = and != in strict mode, without
+// wildcard patterns.
+var strictEqual = comparators.NewStrictEqual()
+var strictNotEqual = comparators.NewStrictNotEqual()
+
+// negatedPatterns maps the negated pattern operations to their positive forms.
+var negatedPatterns = map[parser.ComparatorOperation]parser.ComparatorOperation{
+	parser.NOTLIKE: parser.LIKE, parser.NOTILIKE: parser.ILIKE, parser.NOTGLOB: parser.GLOB,
+}
+
+// negatedComparable reports the opposite of a Comparable, for not like, not ilike and not glob.
+type negatedComparable struct {
+	Comparable
+}
+
+// Compare reports the opposite of the comparison.
+func (this *negatedComparable) Compare(left, right interface{}) (bool, error) {
+	match, err := this.Comparable.Compare(left, right)
+	if err != nil {
+		return false, err
+	}
+	return !match, nil
 }
 
 // String returns the string representation of this comparator.
@@ -65,6 +125,8 @@ func (this *Comparator) String() string {
 	if this.leftProperty != nil {
 		pid, _ := this.leftProperty.PropertyId()
 		buff.WriteString(pid)
+	} else if this.leftArithmetic != nil {
+		buff.WriteString(this.leftArithmetic.String())
 	} else {
 		buff.WriteString(this.left)
 	}
@@ -72,16 +134,116 @@ func (this *Comparator) String() string {
 	if this.rightProperty != nil {
 		pid, _ := this.rightProperty.PropertyId()
 		buff.WriteString(pid)
+	} else if this.rightArithmetic != nil {
+		buff.WriteString(this.rightArithmetic.String())
 	} else {
 		buff.WriteString(this.right)
 	}
+	if this.like != nil && this.like.Escape() != comparators.DefaultEscape {
+		buff.WriteString(" " + parser.Escape + " '" + strings.ReplaceAll(this.like.Escape(), "'", "''") + "'")
+	}
 	return buff.String()
 }
 
 // CreateComparator creates an interpreted Comparator from a parsed L8Comparator.
-// It attempts to resolve both operands as property references; at least one must resolve.
+// It attempts to resolve both operands as property references or arithmetic expressions
+// over properties; at least one must resolve.
 // Returns an error if neither operand can be resolved to a property.
 func CreateComparator(c *l8api.L8Comparator, rootTable *l8reflect.L8Node, resources ifs.IResources) (*Comparator, error) {
+	return createComparator(c, newExpressionScope(rootTable, resources, nil))
+}
+
+// createComparator creates an interpreted Comparator, resolving its operands against the
+// scope and its operation against the built-in and user-defined comparators.
+func createComparator(c *l8api.L8Comparator, scope *expressionScope) (*Comparator, error) {
+	escape := comparators.DefaultEscape
+	if op := parser.ComparatorOperation(c.Oper); op == parser.LIKE || op == parser.ILIKE || op == parser.NOTLIKE ||
+		op == parser.NOTILIKE {
+		if operand, e, ok := parser.PatternOperand(c.Right); ok {
+			c = &l8api.L8Comparator{Left: c.Left, Oper: c.Oper, Right: operand}
+			escape = e
+		}
+	}
+	ormComp, err := createOperands(c, scope)
+	if err != nil {
+		return nil, err
+	}
+	if err = ormComp.resolveOperation(scope); err != nil {
+		return nil, err
+	}
+	if err = ormComp.compilePattern(escape); err != nil {
+		return nil, err
+	}
+	return ormComp, nil
+}
+
+// compilePattern sets the like, ilike or glob comparator of the operation with the escape,
+// and compiles a literal right operand once, so it is not compiled again for every object.
+// The comparators of not like, not ilike and not glob are those of their positive forms.
+func (this *Comparator) compilePattern(escape string) error {
+	operation := this.operation
+	if positive, ok := negatedPatterns[operation]; ok {
+		operation, this.notLike = positive, true
+	}
+	like, ok := comparables[operation].(*comparators.Like)
+	if !ok {
+		return nil
+	}
+	this.like = like.WithEscape(escape)
+	if this.rightProperty != nil || this.rightColumn != "" || this.rightArithmetic != nil {
+		return nil
+	}
+	var err error
+	pattern := unquote(this.right)
+	if this.pattern, err = this.like.Compile(pattern, escape, false); err != nil {
+		return errors.New("Invalid pattern in " + this.String() + ": " + err.Error())
+	}
+	return nil
+}
+
+// resolveOperation resolves an operation that is not built in as a user-defined comparator
+// of the scope's registry, possibly negated by not, checking that its literal operands
+// convert to its types.
+func (this *Comparator) resolveOperation(scope *expressionScope) error {
+	if _, ok := negatedPatterns[this.operation]; ok || comparables[this.operation] != nil {
+		return nil
+	}
+	name := strings.TrimSpace(string(this.operation))
+	negated := strings.HasPrefix(name, "not ")
+	if negated {
+		name = strings.TrimSpace(name[len("not "):])
+	}
+	user := scope.registry.comparator(name)
+	if user == nil {
+		return errors.New("Unknown comparator " + name + " in: " + this.String())
+	}
+	comparable := &userComparable{name: name, comparator: user, negated: negated}
+	comparable.leftLiteral = this.leftProperty == nil && this.leftColumn == "" && this.leftArithmetic == nil
+	comparable.rightLiteral = this.rightProperty == nil && this.rightColumn == "" && this.rightArithmetic == nil
+	if comparable.leftLiteral {
+		if _, err := argumentOf(unquote(this.left), user.Left); err != nil {
+			return errors.New("Invalid left operand of " + name + " in " + this.String() + ": " + err.Error())
+		}
+	}
+	if comparable.rightLiteral {
+		if _, err := argumentOf(unquote(this.right), user.Right); err != nil {
+			return errors.New("Invalid right operand of " + name + " in " + this.String() + ": " + err.Error())
+		}
+	}
+	this.user = comparable
+	return nil
+}
+
+// createOperands creates a Comparator with its operands resolved against the scope.
+func createOperands(c *l8api.L8Comparator, scope *expressionScope) (*Comparator, error) {
+	if scope.columns != nil {
+		return createRowComparator(c, scope)
+	}
+	if IsArithmeticOperand(c) {
+		return createArithmeticComparator(c, scope)
+	}
+	rootTable := scope.rootTable
+	resources := scope.resources
 	ormComp := &Comparator{}
 	ormComp.operation = parser.ComparatorOperation(c.Oper)
 	ormComp.left = c.Left
@@ -96,8 +258,84 @@ func CreateComparator(c *l8api.L8Comparator, rootTable *l8reflect.L8Node, resour
 	return ormComp, nil
 }
 
+// createRowComparator creates a Comparator whose operands are resolved as row columns.
+// At least one operand must be a column; the other may be a column or a literal.
+func createRowComparator(c *l8api.L8Comparator, scope *expressionScope) (*Comparator, error) {
+	if IsArithmeticOperand(c) {
+		return createArithmeticComparator(c, scope)
+	}
+	ormComp := &Comparator{}
+	ormComp.operation = parser.ComparatorOperation(c.Oper)
+	ormComp.left = c.Left
+	ormComp.right = c.Right
+	ormComp.leftColumn = scope.columns[columnReference(c.Left)]
+	ormComp.rightColumn = scope.columns[columnReference(c.Right)]
+	if ormComp.leftColumn == "" && ormComp.rightColumn == "" {
+		return nil, errors.New("No Column was found for comparator: " + c.String())
+	}
+	return ormComp, nil
+}
+
+// IsArithmeticOperand reports whether either operand of the comparator is an arithmetic expression.
+func IsArithmeticOperand(c *l8api.L8Comparator) bool {
+	return parser.IsArithmetic(c.Left) || parser.IsArithmetic(c.Right)
+}
+
+// createArithmeticComparator creates a Comparator with an arithmetic operand. An arithmetic
+// left operand must resolve, while a right operand that cannot be resolved, such as the
+// wildcard pattern "a*b", is a literal. Single operands are resolved as properties or
+// columns, as without arithmetic.
+func createArithmeticComparator(c *l8api.L8Comparator, scope *expressionScope) (*Comparator, error) {
+	ormComp := &Comparator{}
+	ormComp.operation = parser.ComparatorOperation(c.Oper)
+	ormComp.left = c.Left
+	ormComp.right = c.Right
+	left, err := newOperand(c.Left, scope)
+	if err != nil && parser.IsArithmetic(c.Left) {
+		return nil, err
+	}
+	right, _ := newOperand(c.Right, scope)
+	if left == nil && right == nil {
+		return nil, errors.New("No Field was found for comparator: " + c.String())
+	}
+	if left != nil {
+		ormComp.leftProperty, ormComp.leftColumn = left.property, left.column
+		if left.computed() {
+			ormComp.leftArithmetic = left
+		}
+	}
+	if right != nil {
+		ormComp.rightProperty, ormComp.rightColumn = right.property, right.column
+		if right.computed() {
+			ormComp.rightArithmetic = right
+		}
+	}
+	return ormComp, nil
+}
+
+// newOperand resolves a comparison operand against the scope, as an arithmetic expression,
+// or as a single property or column.
+func newOperand(operand string, scope *expressionScope) (*Arithmetic, error) {
+	expr, ok, err := parser.ParseArithmetic(operand)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		expr = &parser.ArithmeticExpression{Operand: operand}
+	}
+	return newArithmetic(expr, scope)
+}
+
+// columnReference normalizes an operand so it can be looked up among the row columns.
+func columnReference(operand string) string {
+	return strings.ReplaceAll(strings.ToLower(operand), " ", "")
+}
+
 // Match evaluates this comparison against the given object.
 // It retrieves the property values and delegates to the appropriate Comparable implementation.
+// Unless matchCase is true, strings are compared in lowercase, except by like and glob,
+// which are always case-sensitive, and ilike, which never is.
+// Failures are returned as an *EvaluationError.
 func (this *Comparator) Match(root interface{}, matchCase bool) (bool, error) {
 	var leftValue interface{}
 	var rightValue interface{}
@@ -105,26 +343,151 @@ func (this *Comparator) Match(root interface{}, matchCase bool) (bool, error) {
 	if this.leftProperty != nil {
 		leftValue, err = this.leftProperty.Get(root)
 		if err != nil {
-			return false, err
+			return false, this.evaluationError(nil, nil, "Cannot get value", err)
+		}
+	} else if this.leftArithmetic != nil {
+		leftValue, err = this.leftArithmetic.Evaluate(root)
+		if err != nil {
+			return false, this.evaluationError(nil, nil, "Cannot evaluate expression", err)
+		}
+	} else if this.leftColumn != "" {
+		leftValue, err = this.columnValue(root, this.leftColumn)
+		if err != nil {
+			return false, this.evaluationError(root, nil, "Cannot get value", err)
 		}
 	} else {
 		leftValue = this.left
 	}
 	if this.rightProperty != nil {
 		rightValue, err = this.rightProperty.Get(root)
-		return false, err
+		if err != nil {
+			return false, this.evaluationError(leftValue, nil, "Cannot get value", err)
+		}
+	} else if this.rightArithmetic != nil {
+		rightValue, err = this.rightArithmetic.Evaluate(root)
+		if err != nil {
+			return false, this.evaluationError(leftValue, nil, "Cannot evaluate expression", err)
+		}
+	} else if this.rightColumn != "" {
+		rightValue, err = this.columnValue(root, this.rightColumn)
+		if err != nil {
+			return false, this.evaluationError(leftValue, root, "Cannot get value", err)
+		}
 	} else {
 		rightValue = this.right
 	}
-	if !matchCase {
+	if !matchCase && this.like == nil {
 		leftValue = toLowerValue(leftValue)
 		rightValue = toLowerValue(rightValue)
 	}
-	matcher := comparables[this.operation]
+	operation := this.operation
+	if this.rightProperty != nil || this.rightColumn != "" || this.rightArithmetic != nil {
+		// The right property holds the values themselves rather than a bracketed list,
+		// so membership is equality with any of its values.
+		if operation == parser.IN {
+			operation = parser.Eq
+		} else if operation == parser.NOTIN {
+			operation = parser.Neq
+		}
+	}
+	if this.user != nil {
+		// User-defined comparators receive repeated operands as they are
+		m, err := this.user.Compare(leftValue, rightValue)
+		if err != nil {
+			return false, this.evaluationError(leftValue, rightValue, "Cannot compare", err)
+		}
+		return m, nil
+	}
+	matcher := comparables[operation]
+	switch {
+	case this.pattern != nil:
+		matcher = this.pattern
+	case this.like != nil:
+		matcher = this.like
+	case operation == parser.Eq && this.strict:
+		matcher = strictEqual
+	case operation == parser.Neq && this.strict:
+		matcher = strictNotEqual
+	}
 	if matcher == nil {
-		panic("No Matcher for: " + this.operation + " operation.")
+		return false, this.evaluationError(leftValue, rightValue, "No matcher for operation", nil)
+	}
+	if this.notLike {
+		matcher = &negatedComparable{matcher}
+	}
+	negative := operation == parser.Neq || operation == parser.NOTIN || this.notLike
+	return this.compareValues(matcher, negative, leftValue, rightValue)
+}
+
+// compareValues compares the values of both sides. Slices and maps on either side are
+// compared element by element: a positive operator matches if any pair of values matches,
+// while a negative operator (!=, not in, not like) matches only if all pairs match, so that
+// "a != b" is always the opposite of "a = b".
+func (this *Comparator) compareValues(matcher Comparable, negative bool, leftValue, rightValue interface{}) (bool, error) {
+	leftValues := valuesOf(leftValue, nil)
+	rightValues := valuesOf(rightValue, nil)
+	for _, l := range leftValues {
+		for _, r := range rightValues {
+			m, err := matcher.Compare(l, r)
+			if err != nil {
+				return false, this.evaluationError(l, r, "Cannot compare", err)
+			}
+			if m != negative {
+				return m, nil
+			}
+		}
+	}
+	return negative, nil
+}
+
+// valuesOf appends the values of a slice, array or map, recursively, to the result.
+// Any other value, including nil and a []byte, is appended as a single value.
+func valuesOf(value interface{}, result []interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			result = valuesOf(v.Index(i).Interface(), result)
+		}
+		return result
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			result = valuesOf(v.MapIndex(key).Interface(), result)
+		}
+		return result
+	}
+	return append(result, value)
+}
+
+// columnValue returns the value of a column of a result row.
+func (this *Comparator) columnValue(root interface{}, column string) (interface{}, error) {
+	row, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Column " + column + " requires a result row")
+	}
+	return row[column], nil
+}
+
+// evaluationError creates an EvaluationError for this comparison and the given operand values.
+func (this *Comparator) evaluationError(leftValue, rightValue interface{}, message string, err error) *EvaluationError {
+	property := this.left
+	if this.leftProperty != nil {
+		property, _ = this.leftProperty.PropertyId()
+	} else if this.leftArithmetic != nil {
+		property = this.leftArithmetic.String()
+	} else if this.leftColumn != "" {
+		property = this.leftColumn
+	} else if this.rightProperty != nil {
+		property, _ = this.rightProperty.PropertyId()
+	} else if this.rightColumn != "" {
+		property = this.rightColumn
 	}
-	return matcher.Compare(leftValue, rightValue), nil
+	return &EvaluationError{Property: property, Operator: string(this.operation),
+		LeftKind: reflect.ValueOf(leftValue).Kind(), RightKind: reflect.ValueOf(rightValue).Kind(),
+		Message: message, Err: err}
 }
 
 // Left returns the left operand as a string.
@@ -154,10 +517,10 @@ func (this *Comparator) Operator() string {
 
 // keyOf returns the literal operand value if one side is a literal and the other is a property.
 func (this *Comparator) keyOf() string {
-	if this.leftProperty == nil {
+	if this.leftProperty == nil && this.leftColumn == "" && this.leftArithmetic == nil {
 		return this.left
 	}
-	if this.rightProperty == nil {
+	if this.rightProperty == nil && this.rightColumn == "" && this.rightArithmetic == nil {
 		return this.right
 	}
 	return ""
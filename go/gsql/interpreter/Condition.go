@@ -36,15 +36,22 @@ type Condition struct {
 // CreateCondition creates an interpreted Condition from a parsed L8Condition.
 // It recursively processes linked conditions and resolves property references.
 func CreateCondition(c *l8api.L8Condition, rootTable *l8reflect.L8Node, resources ifs.IResources) (*Condition, error) {
+	return createCondition(c, newExpressionScope(rootTable, resources, nil))
+}
+
+// createCondition creates the interpreted Condition chain, registering every interpreted
+// Comparator in the scope.
+func createCondition(c *l8api.L8Condition, scope *expressionScope) (*Condition, error) {
 	condition := &Condition{}
 	condition.operation = parser.ConditionOperation(c.Oper)
-	comp, e := CreateComparator(c.Comparator, rootTable, resources)
+	comp, e := createComparator(c.Comparator, scope)
 	if e != nil {
 		return nil, e
 	}
+	scope.comparators[c.Comparator] = comp
 	condition.comparator = comp
 	if c.Next != nil {
-		next, e := CreateCondition(c.Next, rootTable, resources)
+		next, e := createCondition(c.Next, scope)
 		if e != nil {
 			return nil, e
 		}
@@ -73,33 +80,29 @@ func (this *Condition) toString(buff *bytes.Buffer) {
 	}
 }
 
-// Match evaluates this condition chain against the given object.
-// For AND operations, all conditions must match. For OR operations, any match is sufficient.
+// Match evaluates this condition chain against the given object. AND binds tighter
+// than OR: the chain matches if all comparisons of any AND run match. Comparisons are
+// skipped once the outcome of their run is known.
 func (this *Condition) Match(root interface{}, matchCase bool) (bool, error) {
-	comp, e := this.comparator.Match(root, matchCase)
-	if e != nil {
-		return false, e
-	}
-	next := true
-	if this.operation == parser.Or {
-		next = false
-	}
-	if this.next != nil {
-		next, e = this.next.Match(root, matchCase)
-		if e != nil {
-			return false, e
+	run := true
+	for c := this; c != nil; c = c.next {
+		if run {
+			m, e := c.comparator.Match(root, matchCase)
+			if e != nil {
+				return false, e
+			}
+			run = m
+		}
+		if c.next == nil || c.operation == parser.Or {
+			if run {
+				return true, nil
+			}
+			run = true
+		} else if c.operation != parser.And && c.operation != "" {
+			return false, errors.New("Unsupported operation in match:" + string(c.operation))
 		}
 	}
-	if this.operation == "" {
-		return next && comp, nil
-	}
-	if this.operation == parser.And {
-		return comp && next, nil
-	}
-	if this.operation == parser.Or {
-		return comp || next, nil
-	}
-	return false, errors.New("Unsupported operation in match:" + string(this.operation))
+	return false, nil
 }
 
 // Comparator returns the comparator for this condition.
@@ -0,0 +1,135 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Distinct.go computes the unique rows of a distinct projection, comparing nested messages,
+// repeated fields and maps by their contents.
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// distinctRows returns a row for each unique combination of the values of the selected
+// columns over the list, in the order of the first object of each combination, along with
+// the type of each column. When count(*) is selected, each row also holds the number of
+// objects of its combination under the alias of count(*).
+func (this *Query) distinctRows(list []interface{}) ([]map[string]interface{}, map[string]reflect.Type, error) {
+	rows := make([]map[string]interface{}, 0)
+	index := make(map[string]int)
+	types := make(map[string]reflect.Type)
+	for _, item := range list {
+		row := make(map[string]interface{}, len(this.columns)+len(this.aggregates))
+		keys := make([]string, 0, len(this.columns))
+		for _, col := range this.columns {
+			v, err := this.selectedValue(col, item)
+			if err != nil {
+				return nil, nil, err
+			}
+			row[col.name] = v
+			keys = append(keys, projectionKey(v))
+		}
+		key := strings.Join(keys, "|")
+		i, exists := index[key]
+		if !exists {
+			i = len(rows)
+			index[key] = i
+			for col, v := range row {
+				if types[col] == nil && !isNilValue(reflect.ValueOf(v)) {
+					types[col] = reflect.TypeOf(v)
+				}
+			}
+			for _, agg := range this.aggregates {
+				row[agg.Alias] = int64(0)
+				types[agg.Alias] = int64Type
+			}
+			rows = append(rows, row)
+		}
+		for _, agg := range this.aggregates {
+			rows[i][agg.Alias] = rows[i][agg.Alias].(int64) + 1
+		}
+	}
+	return rows, types, nil
+}
+
+// projectionKey returns a key identifying a value by its contents. Messages are keyed by
+// their exported fields, repeated fields by their elements in order and maps by their
+// entries in key order, recursively, so equal values have equal keys whatever their
+// addresses. A nil message, a nil or empty repeated field or map and nil share the key of nil.
+func projectionKey(value interface{}) string {
+	buff := &strings.Builder{}
+	writeProjectionKey(buff, reflect.ValueOf(value))
+	return buff.String()
+}
+
+// writeProjectionKey writes the key of a value to the buffer.
+func writeProjectionKey(buff *strings.Builder, v reflect.Value) {
+	if isNilValue(v) || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+		buff.WriteString("<nil>")
+		return
+	}
+	if v.CanInterface() {
+		if t, ok := timeOf(v.Interface()); ok {
+			buff.WriteString("time:")
+			buff.WriteString(t.UTC().Format(time.RFC3339Nano))
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		writeProjectionKey(buff, v.Elem())
+	case reflect.Struct:
+		buff.WriteString(v.Type().String())
+		buff.WriteString("{")
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			buff.WriteString(v.Type().Field(i).Name)
+			buff.WriteString(":")
+			writeProjectionKey(buff, v.Field(i))
+			buff.WriteString(",")
+		}
+		buff.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		buff.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			writeProjectionKey(buff, v.Index(i))
+			buff.WriteString(",")
+		}
+		buff.WriteString("]")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entry := &strings.Builder{}
+			writeProjectionKey(entry, iter.Key())
+			entry.WriteString("=")
+			writeProjectionKey(entry, iter.Value())
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		buff.WriteString("map[")
+		buff.WriteString(strings.Join(entries, ","))
+		buff.WriteString("]")
+	case reflect.String:
+		buff.WriteString(fmt.Sprintf("%q", v.String()))
+	default:
+		buff.WriteString(fmt.Sprintf("%s:%v", v.Type().String(), v))
+	}
+}
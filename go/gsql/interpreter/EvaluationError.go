@@ -0,0 +1,57 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// EvaluationError.go defines the error returned when a query cannot be evaluated
+// against an object, and the policy that decides how Filter handles it.
+package interpreter
+
+import (
+	"reflect"
+)
+
+// EvaluationError describes a comparison that could not be evaluated against an object,
+// for example a property whose value kind has no matcher for the operator.
+type EvaluationError struct {
+	Property  string       // The property path of the comparison
+	Operator  string       // The comparison operator
+	LeftKind  reflect.Kind // The kind of the left value (reflect.Invalid for nil or unknown)
+	RightKind reflect.Kind // The kind of the right value (reflect.Invalid for nil or unknown)
+	Message   string       // Description of the problem
+	Err       error        // The underlying error, if any
+}
+
+// Error returns the message with the property, operator and value kinds.
+func (this *EvaluationError) Error() string {
+	msg := "Evaluation error: " + this.Message + " for '" + this.Property + "' operator '" + this.Operator +
+		"' (left " + this.LeftKind.String() + ", right " + this.RightKind.String() + ")"
+	if this.Err != nil {
+		msg += ": " + this.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (this *EvaluationError) Unwrap() error {
+	return this.Err
+}
+
+// ErrorPolicy determines how Filter handles objects that fail evaluation.
+type ErrorPolicy int
+
+// Error policies for Filter.
+const (
+	SkipOnError  ErrorPolicy = iota // Objects that fail evaluation are logged and treated as non-matching
+	AbortOnError                    // The first object that fails evaluation aborts the whole Filter
+)
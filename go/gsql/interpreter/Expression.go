@@ -17,6 +17,7 @@ package interpreter
 import (
 	"bytes"
 	"errors"
+	"strconv"
 
 	"github.com/saichler/l8ql/go/gsql/parser"
 	"github.com/saichler/l8types/go/ifs"
@@ -27,11 +28,22 @@ import (
 // Expression represents an interpreted WHERE clause expression that can be evaluated
 // against data objects. Expressions form a tree structure with conditions, child
 // expressions (for grouped/parenthesized expressions), and next expressions (for chained conditions).
+// The linked structure mirrors the parsed L8Expression, while matching uses the
+// precedence-correct boolean tree of the expression.
 type Expression struct {
 	condition *Condition                // The condition at this node (leaf expression)
 	operation parser.ConditionOperation // AND/OR operator connecting to next expression
 	next      *Expression               // Next expression in the chain
 	child     *Expression               // Child expression (for parenthesized groups)
+	tree      *boolExpression           // Boolean tree of this node and the nodes linked after it
+}
+
+// boolExpression is a node of the precedence-correct boolean tree used for matching.
+// It shares the Comparator instances of the linked Expression structure.
+type boolExpression struct {
+	operation  parser.BoolOperation // The kind of this node
+	comparator *Comparator          // The comparison of a compare node
+	operands   []*boolExpression    // The operands of AND/OR/NOT/group nodes
 }
 
 // String returns the string representation of this expression tree.
@@ -59,13 +71,19 @@ func (this *Expression) String() string {
 // It recursively processes the expression tree and resolves property references.
 // Returns nil for nil input without error.
 func CreateExpression(expr *l8api.L8Expression, rootTable *l8reflect.L8Node, resources ifs.IResources) (*Expression, error) {
+	return createExpression(expr, newExpressionScope(rootTable, resources, nil))
+}
+
+// createExpression creates the interpreted Expression and its boolean tree, registering
+// every interpreted Comparator in the scope so the tree can share them.
+func createExpression(expr *l8api.L8Expression, scope *expressionScope) (*Expression, error) {
 	if expr == nil {
 		return nil, nil
 	}
 	ormExpr := &Expression{}
 	ormExpr.operation = parser.ConditionOperation(expr.AndOr)
 	if expr.Condition != nil {
-		cond, e := CreateCondition(expr.Condition, rootTable, resources)
+		cond, e := createCondition(expr.Condition, scope)
 		if e != nil {
 			return nil, e
 		}
@@ -73,7 +91,7 @@ func CreateExpression(expr *l8api.L8Expression, rootTable *l8reflect.L8Node, res
 	}
 
 	if expr.Child != nil {
-		child, e := CreateExpression(expr.Child, rootTable, resources)
+		child, e := createExpression(expr.Child, scope)
 		if e != nil {
 			return nil, e
 		}
@@ -81,58 +99,93 @@ func CreateExpression(expr *l8api.L8Expression, rootTable *l8reflect.L8Node, res
 	}
 
 	if expr.Next != nil {
-		next, e := CreateExpression(expr.Next, rootTable, resources)
+		next, e := createExpression(expr.Next, scope)
 		if e != nil {
 			return nil, e
 		}
 		ormExpr.next = next
 	}
 
+	tree, e := parser.BoolExpressionOf(expr)
+	if e != nil {
+		return nil, e
+	}
+	ormExpr.tree = newBoolExpression(tree, scope.comparators)
 	return ormExpr, nil
 }
 
-// Match evaluates this expression tree against the given object.
-// For AND operations, all parts must match. For OR operations, any match is sufficient.
-// The expression evaluates its condition, child expression, and next expression.
-func (this *Expression) Match(root interface{}, matchCase bool) (bool, error) {
-	cond := true
-	child := true
-	next := true
-	var e error
-	if this.operation == parser.Or {
-		cond = false
-		child = false
-		next = false
+// newBoolExpression creates the matching tree for a parsed boolean tree,
+// using the interpreted comparators created for the linked structure.
+func newBoolExpression(tree *parser.BoolExpression, comparators map[*l8api.L8Comparator]*Comparator) *boolExpression {
+	node := &boolExpression{operation: tree.Operation}
+	if tree.Operation == parser.BoolCompare {
+		node.comparator = comparators[tree.Comparator]
+		return node
 	}
-	if this.condition != nil {
-		cond, e = this.condition.Match(root, matchCase)
-		if e != nil {
-			return false, e
-		}
+	node.operands = make([]*boolExpression, 0, len(tree.Operands))
+	for _, operand := range tree.Operands {
+		node.operands = append(node.operands, newBoolExpression(operand, comparators))
 	}
-	if this.child != nil {
-		child, e = this.child.Match(root, matchCase)
-		if e != nil {
-			return false, e
+	return node
+}
+
+// match evaluates the boolean tree against the given object. AND stops at the first
+// operand that does not match and OR stops at the first operand that matches.
+func (this *boolExpression) match(root interface{}, matchCase bool) (bool, error) {
+	switch this.operation {
+	case parser.BoolCompare:
+		return this.comparator.Match(root, matchCase)
+	case parser.BoolAnd:
+		for _, operand := range this.operands {
+			m, e := operand.match(root, matchCase)
+			if e != nil || !m {
+				return false, e
+			}
 		}
-	}
-	if this.next != nil {
-		next, e = this.next.Match(root, matchCase)
+		return true, nil
+	case parser.BoolOr:
+		for _, operand := range this.operands {
+			m, e := operand.match(root, matchCase)
+			if e != nil {
+				return false, e
+			}
+			if m {
+				return true, nil
+			}
+		}
+		return false, nil
+	case parser.BoolNot:
+		m, e := this.operands[0].match(root, matchCase)
 		if e != nil {
 			return false, e
 		}
+		return !m, nil
+	case parser.BoolGroup:
+		return this.operands[0].match(root, matchCase)
 	}
-	if this.operation == "" {
-		return child && next && cond, nil
-	}
-	if this.operation == parser.And {
-		return child && next && cond, nil
+	return false, errors.New("Unsupported operation in match:" + strconv.Itoa(int(this.operation)))
+}
+
+// Match evaluates this expression, together with the expressions linked after it,
+// against the given object. AND binds tighter than OR and NOT binds tighter than both.
+func (this *Expression) Match(root interface{}, matchCase bool) (bool, error) {
+	return this.tree.match(root, matchCase)
+}
+
+// setStrict sets whether the comparators of this expression, and of the expressions
+// linked after it, compare strings exactly with = and !=.
+func (this *Expression) setStrict(strict bool) {
+	this.tree.setStrict(strict)
+}
+
+// setStrict sets whether the comparators of the tree compare strings exactly with = and !=.
+func (this *boolExpression) setStrict(strict bool) {
+	if this.comparator != nil {
+		this.comparator.strict = strict
 	}
-	if this.operation == parser.Or {
-		return child || next || cond, nil
+	for _, operand := range this.operands {
+		operand.setStrict(strict)
 	}
-
-	return false, errors.New("Unsupported operation in match:" + string(this.operation))
 }
 
 // Condition returns the condition at this expression node.
@@ -0,0 +1,360 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Functions.go implements the built-in scalar functions of the parser package, which
+// transform the values of single objects in SELECT, WHERE, GROUP-BY and SORT-BY.
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+)
+
+// scalarFunction computes the value of a scalar function call from the values of its
+// arguments. Functions other than coalesce, concat and len return nil for a nil argument.
+type scalarFunction func(args []interface{}) (interface{}, error)
+
+// scalarFunctions maps the scalar function names to their implementations.
+var scalarFunctions = make(map[string]scalarFunction)
+
+// init registers the implementations of the scalar functions.
+func init() {
+	scalarFunctions[parser.LowerFunction] = stringFunction(strings.ToLower)
+	scalarFunctions[parser.UpperFunction] = stringFunction(strings.ToUpper)
+	scalarFunctions[parser.TrimFunction] = stringFunction(func(s string) string {
+		return strings.TrimFunc(s, unicode.IsSpace)
+	})
+	scalarFunctions[parser.LenFunction] = lenFunction
+	scalarFunctions[parser.SubstrFunction] = substrFunction
+	scalarFunctions[parser.ConcatFunction] = concatFunction
+	scalarFunctions[parser.ContainsFunction] = matchFunction(strings.Contains)
+	scalarFunctions[parser.StartsWithFunction] = matchFunction(strings.HasPrefix)
+	scalarFunctions[parser.EndsWithFunction] = matchFunction(strings.HasSuffix)
+	scalarFunctions[parser.AbsFunction] = absFunction
+	scalarFunctions[parser.RoundFunction] = roundFunction
+	scalarFunctions[parser.FloorFunction] = floatFunction(math.Floor)
+	scalarFunctions[parser.CeilFunction] = floatFunction(math.Ceil)
+	scalarFunctions[parser.CoalesceFunction] = coalesceFunction
+	scalarFunctions[parser.CastFunction] = castFunction
+}
+
+// stringOf returns the string of a string value, dereferencing a pointer.
+func stringOf(value interface{}) (string, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return "", errors.New("Expected a string, got " + v.Kind().String())
+	}
+	return v.String(), nil
+}
+
+// stringFunction returns a function of one string argument.
+func stringFunction(fn func(string) string) scalarFunction {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		s, err := stringOf(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(s), nil
+	}
+}
+
+// matchFunction returns a function that tests a string argument against another.
+// Matching is case-sensitive; use lower or upper for case-insensitive matching.
+func matchFunction(fn func(string, string) bool) scalarFunction {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		s, err := stringOf(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sub, err := stringOf(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, sub), nil
+	}
+}
+
+// lenFunction returns the number of characters of a string, or of values of a slice,
+// array or map, as an int64. Nil has length 0.
+func lenFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return int64(0), nil
+	}
+	v := reflect.ValueOf(args[0])
+	switch v.Kind() {
+	case reflect.String:
+		return int64(utf8.RuneCountInString(v.String())), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return int64(v.Len()), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return int64(0), nil
+		}
+		return lenFunction([]interface{}{v.Elem().Interface()})
+	}
+	return nil, errors.New("Expected a string or a collection, got " + v.Kind().String())
+}
+
+// substrFunction returns the characters of a string from a 1-based start, up to the
+// optional length. A start before the first character counts from the first one.
+func substrFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringOf(args[0])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	start, err := intArgument(args[1], "start")
+	if err != nil {
+		return nil, err
+	}
+	end := int64(len(runes))
+	if len(args) > 2 {
+		length, err := intArgument(args[2], "length")
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, errors.New("Negative substr length " + strconv.FormatInt(length, 10))
+		}
+		if start+length-1 < end {
+			end = start + length - 1
+		}
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start > end {
+		return "", nil
+	}
+	return string(runes[start-1 : end]), nil
+}
+
+// intArgument returns an integer argument as an int64.
+func intArgument(value interface{}, name string) (int64, error) {
+	n, err := arithmeticOperand(value)
+	if err != nil {
+		return 0, err
+	}
+	switch n.class {
+	case signedNumber:
+		return n.i, nil
+	case unsignedNumber:
+		if n.u <= math.MaxInt64 {
+			return int64(n.u), nil
+		}
+	}
+	return 0, errors.New("Expected an integer " + name + ", got " + fmt.Sprint(value))
+}
+
+// concatFunction returns the concatenated text of its arguments. Nil arguments are empty.
+func concatFunction(args []interface{}) (interface{}, error) {
+	buff := strings.Builder{}
+	for _, arg := range args {
+		if arg != nil {
+			buff.WriteString(fmt.Sprint(arg))
+		}
+	}
+	return buff.String(), nil
+}
+
+// absFunction returns the absolute value of a number, keeping its promoted type.
+func absFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	n, err := arithmeticOperand(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch n.class {
+	case signedNumber:
+		if n.i >= 0 {
+			return n.i, nil
+		}
+		return negate(n.i)
+	case unsignedNumber:
+		return n.u, nil
+	}
+	return math.Abs(n.f), nil
+}
+
+// roundFunction rounds a number half away from zero to the optional number of decimal digits.
+// Integers are returned as they are.
+func roundFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	n, err := arithmeticOperand(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch n.class {
+	case signedNumber:
+		return n.i, nil
+	case unsignedNumber:
+		return n.u, nil
+	}
+	if len(args) == 1 {
+		return math.Round(n.f), nil
+	}
+	digits, err := intArgument(args[1], "number of digits")
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, float64(digits))
+	return math.Round(n.f*scale) / scale, nil
+}
+
+// floatFunction returns a function of one number that applies fn to floats and returns
+// integers, promoted to int64 or uint64, as they are.
+func floatFunction(fn func(float64) float64) scalarFunction {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		n, err := arithmeticOperand(args[0])
+		if err != nil {
+			return nil, err
+		}
+		switch n.class {
+		case signedNumber:
+			return n.i, nil
+		case unsignedNumber:
+			return n.u, nil
+		}
+		return fn(n.f), nil
+	}
+}
+
+// coalesceFunction returns the first argument that is not nil, or nil if all are.
+func coalesceFunction(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if !isNilValue(reflect.ValueOf(arg)) {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+// castFunction converts a value to the type named by the second argument: int (int64),
+// uint (uint64), float (float64), string or bool. Strings are parsed, floats are truncated
+// toward zero, and bools are 1 or 0.
+func castFunction(args []interface{}) (interface{}, error) {
+	value, typ := args[0], args[1].(string)
+	if value == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+		value = v.Interface()
+	}
+	if typ == parser.CastString {
+		return fmt.Sprint(value), nil
+	}
+	if v.Kind() == reflect.String {
+		return castString(strings.TrimSpace(v.String()), typ)
+	}
+	if v.Kind() == reflect.Bool {
+		if typ == parser.CastBool {
+			return v.Bool(), nil
+		}
+		value = int64(0)
+		if v.Bool() {
+			value = int64(1)
+		}
+	}
+	n, err := arithmeticOperand(value)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case parser.CastInt:
+		switch n.class {
+		case signedNumber:
+			return n.i, nil
+		case unsignedNumber:
+			if n.u > math.MaxInt64 {
+				return nil, ErrOverflow
+			}
+			return int64(n.u), nil
+		}
+		if math.IsNaN(n.f) || n.f >= math.MaxInt64 || n.f < math.MinInt64 {
+			return nil, ErrOverflow
+		}
+		return int64(n.f), nil
+	case parser.CastUint:
+		switch n.class {
+		case signedNumber:
+			if n.i < 0 {
+				return nil, errors.New("Cannot cast negative value " + fmt.Sprint(value) + " to uint")
+			}
+			return uint64(n.i), nil
+		case unsignedNumber:
+			return n.u, nil
+		}
+		if math.IsNaN(n.f) || n.f < 0 || n.f >= math.MaxUint64 {
+			return nil, ErrOverflow
+		}
+		return uint64(n.f), nil
+	case parser.CastFloat:
+		return n.float(), nil
+	case parser.CastBool:
+		return n.float() != 0, nil
+	}
+	return nil, errors.New("Unknown cast type " + typ)
+}
+
+// castString parses a string as the type.
+func castString(s, typ string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch typ {
+	case parser.CastInt:
+		value, err = strconv.ParseInt(s, 10, 64)
+	case parser.CastUint:
+		value, err = strconv.ParseUint(s, 10, 64)
+	case parser.CastFloat:
+		value, err = strconv.ParseFloat(s, 64)
+	case parser.CastBool:
+		value, err = strconv.ParseBool(s)
+	default:
+		err = errors.New("Unknown cast type " + typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,70 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// GroupingSets.go computes the groups of the grouping sets of rollup and cube, which add
+// subtotal rows, where some group-by columns are rolled up, to the rows of a group-by.
+package interpreter
+
+// GroupingColumn is the column of the aggregate rows of a query with rollup or cube that
+// tells which group-by columns were rolled up. It is an int64 with a bit set for each rolled
+// up column, the first group-by column being the most significant bit: 0 for the rows of
+// all the columns, and 2^n-1 for the grand total of n columns. Rolled up columns are nil.
+const GroupingColumn = "grouping"
+
+// groupingKeys returns the keys, group-by values and GroupingColumn values of the groups of
+// each grouping set that an object belongs to, given the group-by values of its groups with
+// all the columns. Each group appears once, even if several groups of the object roll up into it.
+func (this *Query) groupingKeys(keyValues []map[string]interface{}) ([]string, []map[string]interface{}, []int64) {
+	keys := make([]string, 0, len(keyValues)*len(this.groupingSets))
+	values := make([]map[string]interface{}, 0, len(keyValues)*len(this.groupingSets))
+	groupings := make([]int64, 0, len(keyValues)*len(this.groupingSets))
+	seen := make(map[string]bool)
+	for _, set := range this.groupingSets {
+		grouping := groupingOf(set)
+		for _, kv := range keyValues {
+			setValues := make(map[string]interface{}, len(kv))
+			for i, gb := range this.groupBy {
+				if set[i] {
+					setValues[gb] = kv[gb]
+				}
+			}
+			key := this.groupId(setValues)
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+				values = append(values, setValues)
+				groupings = append(groupings, grouping)
+			}
+		}
+	}
+	return keys, values, groupings
+}
+
+// groupingOf returns the GroupingColumn value of a grouping set.
+func groupingOf(set []bool) int64 {
+	grouping := int64(0)
+	for _, grouped := range set {
+		grouping <<= 1
+		if !grouped {
+			grouping |= 1
+		}
+	}
+	return grouping
+}
+
+// isRolledUp reports whether the group-by column at the given index is rolled up in the group.
+func (this *Query) isRolledUp(group *aggregateGroup, index int) bool {
+	return group.grouping&(1<<(len(this.groupBy)-1-index)) != 0
+}
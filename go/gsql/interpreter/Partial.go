@@ -0,0 +1,312 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Partial.go computes aggregates in map-reduce mode: each node aggregates its own objects
+// into a partial state, the coordinator merges the partial states of all nodes and
+// finalizes the merged state into the result rows. The partial state is a protobuf
+// message, so it can be sent between nodes as is.
+package interpreter
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Field names of the partial state message.
+const (
+	partialFunctions = "functions" // The list of aggregate functions, in query order
+	partialGroups    = "groups"    // The list of groups
+	partialId        = "id"        // The group key built from the group-by values
+	partialKeys      = "keys"      // The group-by values of a group
+	partialGrouping  = "grouping"  // The rolled up columns of a group of a grouping set
+	partialStates    = "states"    // The accumulator states of a group, in query order
+	partialError     = "error"     // The error of an accumulator that failed on its node
+	valueType        = "type"      // The type of an encoded value
+	valueText        = "value"     // The exact text of an encoded value
+)
+
+// AggregatePartial groups the list by the group-by fields and returns the partial state
+// of the aggregate functions of each group, to be merged with the partial states of other
+// nodes by MergePartials and turned into rows by Finalize. Like Aggregate, it expects
+// the list to be filtered already.
+// An aggregate that fails is handled according to the query's error policy; when skipped,
+// the failure is carried in the partial state and its value in the final row is nil.
+func (this *Query) AggregatePartial(list []interface{}) (*structpb.Struct, error) {
+	if !this.isAggregate {
+		return nil, errors.New("Query has no aggregate functions")
+	}
+	groups, err := this.aggregateGroups(list)
+	if err != nil {
+		return nil, err
+	}
+	return this.encodeGroups(groups)
+}
+
+// MergePartials merges the partial states produced by AggregatePartial on several nodes
+// into a single partial state. Groups are kept in the order they are first seen, in the
+// order of the partial states. Merging is associative, so merged states can be merged again.
+func (this *Query) MergePartials(partials ...*structpb.Struct) (*structpb.Struct, error) {
+	groups, err := this.decodeGroups(partials...)
+	if err != nil {
+		return nil, err
+	}
+	return this.encodeGroups(groups)
+}
+
+// Finalize computes the result rows of the merged partial states, filters them by the
+// HAVING clause, sorts them by the sort-by keys and takes the requested page, the same
+// way Execute does for the objects of a single node.
+func (this *Query) Finalize(partials ...*structpb.Struct) (*Result, error) {
+	groups, err := this.decodeGroups(partials...)
+	if err != nil {
+		return nil, err
+	}
+	rows, types, err := this.groupRows(groups)
+	if err != nil {
+		return nil, err
+	}
+	rows, types, total, err := this.rowsPage(rows, types)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Rows: rows, Types: types, Total: total}, nil
+}
+
+// encodeGroups encodes the groups as a partial state message.
+func (this *Query) encodeGroups(groups []*aggregateGroup) (*structpb.Struct, error) {
+	functions := make([]*structpb.Value, 0, len(this.aggregates))
+	for _, agg := range this.aggregates {
+		functions = append(functions, structpb.NewStringValue(agg.Function))
+	}
+	list := make([]*structpb.Value, 0, len(groups))
+	for _, group := range groups {
+		keys := make(map[string]*structpb.Value)
+		for k, v := range group.keys {
+			value, err := encodeValue(v)
+			if err != nil {
+				return nil, errors.New("Cannot encode group-by value of " + k + ": " + err.Error())
+			}
+			keys[k] = value
+		}
+		states := make([]*structpb.Value, 0, len(group.accumulators))
+		for i, acc := range group.accumulators {
+			state, err := acc.Partial()
+			if err != nil {
+				if _, ok := err.(*EvaluationError); !ok {
+					err = &EvaluationError{Message: "Cannot encode partial state", Err: err}
+				}
+				if e := this.aggregateError(this.aggregates[i], err); e != nil {
+					return nil, e
+				}
+				state = newState(map[string]*structpb.Value{partialError: structpb.NewStringValue(err.Error())})
+			}
+			states = append(states, state)
+		}
+		fields := map[string]*structpb.Value{
+			partialId:     structpb.NewStringValue(group.id),
+			partialKeys:   newState(keys),
+			partialStates: structpb.NewListValue(&structpb.ListValue{Values: states}),
+		}
+		if group.grouping != 0 {
+			fields[partialGrouping] = structpb.NewStringValue(strconv.FormatInt(group.grouping, 10))
+		}
+		list = append(list, newState(fields))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		partialFunctions: structpb.NewListValue(&structpb.ListValue{Values: functions}),
+		partialGroups:    structpb.NewListValue(&structpb.ListValue{Values: list}),
+	}}, nil
+}
+
+// decodeGroups decodes the partial state messages and merges their groups by group key.
+func (this *Query) decodeGroups(partials ...*structpb.Struct) ([]*aggregateGroup, error) {
+	groups := make(map[string]*aggregateGroup)
+	groupOrder := make([]*aggregateGroup, 0)
+	for _, partial := range partials {
+		if partial == nil {
+			continue
+		}
+		functions := partial.Fields[partialFunctions].GetListValue().GetValues()
+		if len(functions) != len(this.aggregates) {
+			return nil, errors.New("Partial state does not match the aggregate functions of the query")
+		}
+		for i, agg := range this.aggregates {
+			if functions[i].GetStringValue() != agg.Function {
+				return nil, errors.New("Partial state does not match the aggregate function " + agg.Function)
+			}
+		}
+		for _, value := range partial.Fields[partialGroups].GetListValue().GetValues() {
+			fields := value.GetStructValue().GetFields()
+			states := fields[partialStates].GetListValue().GetValues()
+			if len(states) != len(this.aggregates) {
+				return nil, errors.New("Partial state group does not match the aggregate functions of the query")
+			}
+			id := fields[partialId].GetStringValue()
+			group, exists := groups[id]
+			if !exists {
+				keys := make(map[string]interface{})
+				for k, v := range fields[partialKeys].GetStructValue().GetFields() {
+					key, err := decodeValue(v)
+					if err != nil {
+						return nil, err
+					}
+					keys[k] = key
+				}
+				grouping := int64(0)
+				if text := fields[partialGrouping].GetStringValue(); text != "" {
+					var err error
+					if grouping, err = strconv.ParseInt(text, 10, 64); err != nil {
+						return nil, errors.New("Invalid grouping of partial state group: " + text)
+					}
+				}
+				group = this.newAggregateGroup(id, keys, grouping)
+				groups[id] = group
+				groupOrder = append(groupOrder, group)
+			}
+			for i, state := range states {
+				group.accumulators[i].Merge(state)
+			}
+		}
+	}
+	return groupOrder, nil
+}
+
+// newState creates a struct value with the given fields.
+func newState(fields map[string]*structpb.Value) *structpb.Value {
+	return structpb.NewStructValue(&structpb.Struct{Fields: fields})
+}
+
+// encodeValue encodes an aggregated value as a protobuf value. Nil, strings and bools are
+// encoded as such, while other numbers and timestamps are encoded as a struct holding
+// their type and exact text, so that they decode to the same type and value.
+// Timestamps decode to time.Time.
+func encodeValue(value interface{}) (*structpb.Value, error) {
+	if isNilValue(reflect.ValueOf(value)) {
+		return structpb.NewNullValue(), nil
+	}
+	if t, ok := timeOf(value); ok {
+		return newTypedValue("time", t.Format(time.RFC3339Nano)), nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.String:
+		return structpb.NewStringValue(v.String()), nil
+	case v.Kind() == reflect.Bool:
+		return structpb.NewBoolValue(v.Bool()), nil
+	case isIntValue(v):
+		return newTypedValue(v.Kind().String(), strconv.FormatInt(v.Int(), 10)), nil
+	case isUintValue(v):
+		return newTypedValue(v.Kind().String(), strconv.FormatUint(v.Uint(), 10)), nil
+	case isFloatValue(v):
+		return newTypedValue(v.Kind().String(), strconv.FormatFloat(v.Float(), 'g', -1, 64)), nil
+	}
+	return nil, errors.New("Cannot encode value of type " + v.Type().String())
+}
+
+// newTypedValue creates the encoded value of a number or timestamp.
+func newTypedValue(typ, text string) *structpb.Value {
+	return newState(map[string]*structpb.Value{
+		valueType: structpb.NewStringValue(typ),
+		valueText: structpb.NewStringValue(text),
+	})
+}
+
+// decodeValue decodes a protobuf value encoded by encodeValue.
+func decodeValue(value *structpb.Value) (interface{}, error) {
+	switch kind := value.GetKind().(type) {
+	case nil, *structpb.Value_NullValue:
+		return nil, nil
+	case *structpb.Value_StringValue:
+		return kind.StringValue, nil
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue, nil
+	case *structpb.Value_NumberValue:
+		return kind.NumberValue, nil
+	}
+	fields := value.GetStructValue().GetFields()
+	typ := fields[valueType].GetStringValue()
+	text := fields[valueText].GetStringValue()
+	var result interface{}
+	var err error
+	switch typ {
+	case "time":
+		result, err = time.Parse(time.RFC3339Nano, text)
+	case "int", "int8", "int16", "int32", "int64":
+		var i int64
+		i, err = strconv.ParseInt(text, 10, 64)
+		result = reflect.ValueOf(i).Convert(kindTypes[typ]).Interface()
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		var u uint64
+		u, err = strconv.ParseUint(text, 10, 64)
+		result = reflect.ValueOf(u).Convert(kindTypes[typ]).Interface()
+	case "float32", "float64":
+		var f float64
+		f, err = strconv.ParseFloat(text, 64)
+		result = reflect.ValueOf(f).Convert(kindTypes[typ]).Interface()
+	default:
+		return nil, errors.New("Cannot decode value of type '" + typ + "'")
+	}
+	if err != nil {
+		return nil, errors.New("Cannot decode value '" + text + "' of type " + typ + ": " + err.Error())
+	}
+	return result, nil
+}
+
+// kindTypes maps the names of the numeric kinds to their types.
+var kindTypes = map[string]reflect.Type{
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+}
+
+// decodeValues decodes a list of values encoded by encodeValue.
+func decodeValues(value *structpb.Value) ([]interface{}, error) {
+	values := value.GetListValue().GetValues()
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		decoded, err := decodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// encodeValues encodes a list of values with encodeValue.
+func encodeValues(values []interface{}) (*structpb.Value, error) {
+	list := make([]*structpb.Value, 0, len(values))
+	for _, v := range values {
+		encoded, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, encoded)
+	}
+	return structpb.NewListValue(&structpb.ListValue{Values: list}), nil
+}
@@ -0,0 +1,325 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Project.go projects objects into flat rows of their selected columns. Rows keep the
+// SELECT order and name each column by its alias, and repeated paths are returned as
+// arrays, joined into a single string or expanded into one row per element.
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// DefaultJoinSeparator is the separator of joined repeated values.
+const DefaultJoinSeparator = ", "
+
+// RepeatedMode defines how a projection returns the values of a repeated path.
+type RepeatedMode int
+
+// Modes of projecting repeated paths.
+const (
+	RepeatedArray  RepeatedMode = iota // The values are returned as a single []interface{} value
+	RepeatedJoin                       // The values are formatted and joined into a single string
+	RepeatedExpand                     // Each value is returned in its own row
+)
+
+// column is a selected property column of the query.
+type column struct {
+	name       string        // The name of the column in rows: its alias, or its path
+	path       string        // The property path or expression of the column, as written in the query
+	property   ifs.IProperty // The accessor of the column's property
+	arithmetic *Arithmetic   // The expression of a computed column, nil for a property column
+}
+
+// Row is a flat projection of an object, holding the values of the selected
+// columns in SELECT order.
+type Row struct {
+	Columns []string      // The column names: the alias of a column, or its path
+	Values  []interface{} // The values, in the order of Columns
+}
+
+// Get returns the value of the named column, and false if the row has no such column.
+func (this Row) Get(name string) (interface{}, bool) {
+	for i, col := range this.Columns {
+		if col == name {
+			return this.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Map returns the row as a column name -> value map.
+func (this Row) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(this.Columns))
+	for i, col := range this.Columns {
+		m[col] = this.Values[i]
+	}
+	return m
+}
+
+// MarshalJSON encodes the row as a JSON object whose keys keep the column order.
+func (this Row) MarshalJSON() ([]byte, error) {
+	buff := bytes.Buffer{}
+	buff.WriteString("{")
+	for i, col := range this.Columns {
+		if i > 0 {
+			buff.WriteString(",")
+		}
+		key, err := json.Marshal(col)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(this.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		buff.Write(key)
+		buff.WriteString(":")
+		buff.Write(value)
+	}
+	buff.WriteString("}")
+	return buff.Bytes(), nil
+}
+
+// SetRepeatedMode sets how Project and ProjectRows return the values of repeated paths.
+// The default is RepeatedArray.
+func (this *Query) SetRepeatedMode(mode RepeatedMode) {
+	this.repeatedMode = mode
+}
+
+// RepeatedMode returns how Project and ProjectRows return the values of repeated paths.
+func (this *Query) RepeatedMode() RepeatedMode {
+	return this.repeatedMode
+}
+
+// SetJoinSeparator sets the separator of joined repeated values.
+// The default is DefaultJoinSeparator.
+func (this *Query) SetJoinSeparator(separator string) {
+	this.joinSeparator = separator
+}
+
+// Project returns the flat row of the selected columns of the object. The WHERE clause
+// is not evaluated; use Match to test the object first. In RepeatedExpand mode, an
+// object that expands into more than one row is an error; use ProjectRows instead.
+// Returns an error for aggregate and window queries, and for queries without columns.
+func (this *Query) Project(obj interface{}) (Row, error) {
+	rows, err := this.ProjectRows(obj)
+	if err != nil {
+		return Row{}, err
+	}
+	if len(rows) > 1 {
+		return Row{}, errors.New("Object expands into " + fmt.Sprint(len(rows)) + " rows, use ProjectRows")
+	}
+	return rows[0], nil
+}
+
+// ProjectRows returns the flat rows of the selected columns of the object. Only the
+// RepeatedExpand mode returns more than one row: repeated columns under the same repeated
+// field are expanded together, element by element, and all other repeated columns are
+// expanded as a cartesian product. An empty repeated column expands into a single nil value.
+func (this *Query) ProjectRows(obj interface{}) ([]Row, error) {
+	if this.isAggregate {
+		return nil, errors.New("Aggregate queries cannot be projected")
+	}
+	if len(this.windows) > 0 {
+		return nil, errors.New("Queries with window functions cannot be projected")
+	}
+	if len(this.columns) == 0 {
+		return nil, errors.New("Query has no selected columns to project")
+	}
+	names := make([]string, len(this.columns))
+	values := make([]interface{}, len(this.columns))
+	for i, col := range this.columns {
+		v, err := this.selectedValue(col, obj)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = col.name
+		values[i] = v
+	}
+	switch this.repeatedMode {
+	case RepeatedJoin:
+		for i, v := range values {
+			if elems, ok := repeatedValues(v); ok {
+				values[i] = this.joinValues(elems)
+			}
+		}
+	case RepeatedExpand:
+		return this.expandRows(obj, names, values), nil
+	default:
+		for i, v := range values {
+			if elems, ok := repeatedValues(v); ok {
+				values[i] = elems
+			}
+		}
+	}
+	return []Row{{Columns: names, Values: values}}, nil
+}
+
+// repeatedValues returns the elements of a slice or array value, and false for any
+// other value. Byte slices are single values.
+func repeatedValues(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return nil, true
+	}
+	elems := make([]interface{}, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i).Interface()
+	}
+	return elems, true
+}
+
+// joinValues formats the elements and joins them with the join separator.
+// Nil elements are formatted as empty strings.
+func (this *Query) joinValues(elems []interface{}) string {
+	parts := make([]string, len(elems))
+	for i, elem := range elems {
+		if elem != nil {
+			parts[i] = fmt.Sprint(elem)
+		}
+	}
+	return strings.Join(parts, this.joinSeparator)
+}
+
+// expansion is a set of repeated columns expanded together, element by element.
+type expansion struct {
+	columns  []int           // Indexes of the expanded columns
+	elements [][]interface{} // The elements of each expanded column
+	size     int             // The number of elements of the longest expanded column
+}
+
+// expandRows expands the repeated values of the object into rows. The columns under the
+// same repeated field, such as addresses.country and addresses.city, are zipped, a shorter
+// column being padded with nil, and the cartesian product of the other repeated columns
+// is taken. Which columns are zipped depends only on the type of the object, never on the
+// number of values.
+func (this *Query) expandRows(obj interface{}, names []string, values []interface{}) []Row {
+	typ := reflect.TypeOf(obj)
+	expansions := make([]*expansion, 0)
+	byAncestor := make(map[string]*expansion)
+	for i, v := range values {
+		elems, ok := repeatedValues(v)
+		if !ok {
+			continue
+		}
+		ancestor := ""
+		if this.columns[i].arithmetic == nil {
+			ancestor = repeatedAncestor(typ, this.columns[i].path)
+		}
+		exp := byAncestor[ancestor]
+		if exp == nil || ancestor == "" {
+			exp = &expansion{}
+			expansions = append(expansions, exp)
+			if ancestor != "" {
+				byAncestor[ancestor] = exp
+			}
+		}
+		exp.columns = append(exp.columns, i)
+		exp.elements = append(exp.elements, elems)
+		if len(elems) > exp.size {
+			exp.size = len(elems)
+		}
+	}
+	rows := []Row{{Columns: names, Values: values}}
+	for _, exp := range expansions {
+		expanded := make([]Row, 0, len(rows)*(exp.size+1))
+		for _, row := range rows {
+			for e := 0; e < exp.size || (e == 0 && exp.size == 0); e++ {
+				rowValues := append([]interface{}{}, row.Values...)
+				for c, col := range exp.columns {
+					rowValues[col] = nil
+					if e < len(exp.elements[c]) {
+						rowValues[col] = exp.elements[c][e]
+					}
+				}
+				expanded = append(expanded, Row{Columns: names, Values: rowValues})
+			}
+		}
+		rows = expanded
+	}
+	return rows
+}
+
+// repeatedAncestor returns the path of the nearest repeated field (a slice or a map) above
+// the property path in the type, such as "addresses" for "addresses.country", or "" if no
+// field above the property is repeated. The path may start with the name of the type.
+func repeatedAncestor(typ reflect.Type, path string) string {
+	fields := strings.Split(path, ".")
+	if len(fields) > 1 && typ != nil && strings.EqualFold(indirectType(typ).Name(), fields[0]) {
+		fields = fields[1:]
+	}
+	ancestor := ""
+	for i, field := range fields[:len(fields)-1] {
+		typ = fieldType(typ, field)
+		if typ == nil {
+			return ancestor
+		}
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+			ancestor = strings.Join(fields[:i+1], ".")
+			typ = typ.Elem()
+		}
+	}
+	return ancestor
+}
+
+// fieldType returns the type of the field of a struct type, or of a pointer to it,
+// matched case-insensitively, with pointers removed. Returns nil if there is no such field.
+func fieldType(typ reflect.Type, name string) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	typ = indirectType(typ)
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if strings.EqualFold(typ.Field(i).Name, name) {
+			return indirectType(typ.Field(i).Type)
+		}
+	}
+	return nil
+}
+
+// indirectType returns the type pointed to by pointer types, or the type itself.
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// hasArithmeticColumns reports whether any of the selected columns is computed.
+func (this *Query) hasArithmeticColumns() bool {
+	for _, col := range this.columns {
+		if col.arithmetic != nil {
+			return true
+		}
+	}
+	return false
+}
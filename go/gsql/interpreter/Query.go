@@ -46,28 +46,49 @@ type Query struct {
 	propertiesMap  map[string]ifs.IProperty // Map of property names to property accessors
 	properties     []ifs.IProperty          // Ordered list of selected properties
 	where          *Expression              // The WHERE clause expression for filtering
-	sortBy         string                   // Property name to sort results by
-	sortByProperty *properties.Property     // Resolved sort property accessor
+	sortBy         string                   // The sort-by clause text
+	sortByProperty *properties.Property     // Resolved accessor of the first sort key
+	sortKeys       []*sortKey               // Resolved sort keys, in order of precedence
 	descending     bool                     // Sort in descending order if true
 	limit          int32                    // Maximum number of results
 	page           int32                    // Page number for pagination
 	matchCase      bool                     // Case-sensitive matching if true
 	resources      ifs.IResources           // Resources for logging and introspection
+	registry       *Registry                // User-defined extensions, nil if there are none
 	query          *l8api.L8Query           // The original parsed query
 	groupBy        []string                 // Group-by field names
-	groupByProps   []*properties.Property   // Resolved group-by properties
+	groupByProps   []*properties.Property   // Resolved group-by properties, nil for computed columns
+	groupByArith   []*Arithmetic            // Expressions of computed group-by columns, nil for properties
+	groupByExprs   []*parser.GroupExpression // Parsed group-by items, with their grouping functions
+	groupingSets   [][]bool                 // Grouping sets of rollup and cube, nil for a plain group-by
+	windows        []*window                // Resolved window functions of the SELECT clause
+	distinct       bool                     // True for a distinct projection of the selected columns
+	columns        []*column                // Selected property columns, in SELECT order
+	groupAliases   map[string]string        // Group-by column -> alias of its SELECT column
+	repeatedMode   RepeatedMode             // How Project returns the values of repeated paths
+	joinSeparator  string                   // The separator of joined repeated values
 	aggregates     []*l8api.L8AggregateFunction // Parsed aggregate functions
 	aggregateProps map[string]*properties.Property // Field -> property for aggregated fields
-	having         *Expression              // HAVING clause expression
+	having         *Expression              // HAVING clause expression over aggregate rows
 	isAggregate    bool                     // True if query has aggregate functions
+	errorPolicy    ErrorPolicy              // How Filter handles objects that fail evaluation
+	strict         bool                     // Compare strings exactly with = and != if true
 }
 
 // NewFromQuery creates a new interpreted Query from a parsed L8Query protobuf message.
 // It resolves all property references, creates the expression tree, and validates
 // that all referenced types and properties exist. Returns an error if validation fails.
 func NewFromQuery(query *l8api.L8Query, resources ifs.IResources) (*Query, error) {
+	return NewFromQueryWithRegistry(query, resources, nil)
+}
+
+// NewFromQueryWithRegistry creates a new interpreted Query like NewFromQuery, resolving the
+// user-defined comparators, functions and aggregates of the registry. The registry may be nil.
+func NewFromQueryWithRegistry(query *l8api.L8Query, resources ifs.IResources, registry *Registry) (*Query, error) {
 	iQuery := &Query{}
 	iQuery.propertiesMap = make(map[string]ifs.IProperty)
+	iQuery.groupAliases = make(map[string]string)
+	iQuery.joinSeparator = DefaultJoinSeparator
 	iQuery.properties = make([]ifs.IProperty, 0)
 	iQuery.descending = query.Descending
 	iQuery.matchCase = query.MatchCase
@@ -75,6 +96,7 @@ func NewFromQuery(query *l8api.L8Query, resources ifs.IResources) (*Query, error
 	iQuery.limit = query.Limit
 	iQuery.sortBy = query.SortBy
 	iQuery.resources = resources
+	iQuery.registry = registry
 	iQuery.query = query
 
 	err := iQuery.initTables(query)
@@ -92,26 +114,24 @@ func NewFromQuery(query *l8api.L8Query, resources ifs.IResources) (*Query, error
 		return nil, errors.New("root table is nil")
 	}
 
-	expr, err := CreateExpression(query.Criteria, rootTable, resources)
+	expr, err := createExpression(query.Criteria, newExpressionScope(rootTable, resources, iQuery.registry))
 	if err != nil {
 		return nil, err
 	}
 	iQuery.where = expr
 
-	if iQuery.sortBy != "" {
-		sortByProperty, er := properties.PropertyOf(rootTable.TypeName+"."+iQuery.sortBy, resources)
-		if er != nil {
-			return nil, errors.New(er.Error())
-		}
-		iQuery.sortByProperty = sortByProperty
-	}
-
-	// Initialize aggregate fields
+	// Initialize aggregate fields; the only aggregate of a distinct projection is the count of duplicates
 	if len(query.Aggregates) > 0 {
-		iQuery.isAggregate = true
+		iQuery.isAggregate = !iQuery.distinct
 		iQuery.aggregates = query.Aggregates
 		iQuery.aggregateProps = make(map[string]*properties.Property)
 		for _, agg := range query.Aggregates {
+			if iQuery.distinct && (agg.Function != "count" || agg.Field != "*") {
+				return nil, errors.New("Distinct projections support only count(*), not " + parser.AggregateCall(agg))
+			}
+			if _, er := newAccumulator(agg.Function, iQuery.registry); er != nil {
+				return nil, er
+			}
 			if agg.Field != "*" {
 				prop, er := properties.PropertyOf(rootTable.TypeName+"."+agg.Field, resources)
 				if er != nil {
@@ -122,39 +142,76 @@ func NewFromQuery(query *l8api.L8Query, resources ifs.IResources) (*Query, error
 		}
 	}
 
-	// Initialize group-by fields
+	// Initialize group-by fields, expanding rollup and cube into their columns and grouping sets
 	if len(query.GroupBy) > 0 {
-		iQuery.groupBy = query.GroupBy
-		iQuery.groupByProps = make([]*properties.Property, 0, len(query.GroupBy))
-		for _, gb := range query.GroupBy {
-			prop, er := properties.PropertyOf(rootTable.TypeName+"."+gb, resources)
+		exprs, sets, er := parser.GroupingSets(query.GroupBy)
+		if er != nil {
+			return nil, er
+		}
+		if len(sets) > 1 {
+			iQuery.groupingSets = sets
+		}
+		iQuery.groupBy = make([]string, 0, len(exprs))
+		iQuery.groupByProps = make([]*properties.Property, 0, len(exprs))
+		iQuery.groupByExprs = make([]*parser.GroupExpression, 0, len(exprs))
+		iQuery.groupByArith = make([]*Arithmetic, 0, len(exprs))
+		for _, expr := range exprs {
+			var prop *properties.Property
+			var arithmetic *Arithmetic
+			if expr.Expression != nil {
+				arithmetic, er = newArithmetic(expr.Expression, newExpressionScope(rootTable, resources, iQuery.registry))
+			} else {
+				prop, er = properties.PropertyOf(rootTable.TypeName+"."+expr.Field, resources)
+			}
 			if er != nil {
 				return nil, errors.New(er.Error())
 			}
+			iQuery.groupBy = append(iQuery.groupBy, expr.String())
 			iQuery.groupByProps = append(iQuery.groupByProps, prop)
+			iQuery.groupByArith = append(iQuery.groupByArith, arithmetic)
+			iQuery.groupByExprs = append(iQuery.groupByExprs, expr)
 		}
 	}
 
-	// Initialize HAVING clause
+	// Initialize HAVING clause, resolved against the columns of the aggregate rows
 	if query.Having != nil {
-		havingExpr, er := CreateExpression(query.Having, rootTable, resources)
+		havingExpr, er := createExpression(query.Having, newRowScope(iQuery.rowColumns(), resources, iQuery.registry))
 		if er != nil {
 			return nil, er
 		}
 		iQuery.having = havingExpr
 	}
 
+	// Initialize sort-by keys, resolved against the aggregate rows for aggregate queries
+	if iQuery.sortBy != "" {
+		err = iQuery.initSortKeys(rootTable, resources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return iQuery, nil
 }
 
 // NewQuery parses an L8QL query string and creates a new interpreted Query.
 // This is a convenience function that combines parsing and interpretation.
+// Parse failures are returned as a *parser.SyntaxError.
 func NewQuery(gsql string, resources ifs.IResources) (*Query, error) {
-	pQuery, err := parser.NewQuery(gsql, resources.Logger())
+	return NewQueryWithRegistry(gsql, resources, nil)
+}
+
+// NewQueryWithRegistry parses an L8QL query string and creates a new interpreted Query
+// that may use the user-defined extensions of the registry. The registry may be nil.
+func NewQueryWithRegistry(gsql string, resources ifs.IResources, registry *Registry) (*Query, error) {
+	var ext parser.Extensions
+	if registry != nil {
+		ext = registry
+	}
+	pQuery, err := parser.NewQueryWithExtensions(gsql, resources.Logger(), ext)
 	if err != nil {
 		return nil, err
 	}
-	return NewFromQuery(pQuery.Query(), resources)
+	return NewFromQueryWithRegistry(pQuery.Query(), resources, registry)
 }
 
 // Query returns the underlying L8Query protobuf message.
@@ -227,11 +284,49 @@ func (this *Query) Limit() int32 {
 	return this.limit
 }
 
-// SortBy returns the property name used for sorting results.
+// SortBy returns the sort-by clause, e.g. "region asc, cpu desc".
+// For a single key without a direction, this is the property name.
 func (this *Query) SortBy() string {
 	return this.sortBy
 }
 
+// initSortKeys parses the sort-by clause and resolves each key to a property of the
+// root type or, for aggregate queries, to a column of the aggregate rows. A computed key,
+// such as lower(name) or sum(bytes) / count(*), is evaluated over the object or row.
+func (this *Query) initSortKeys(rootTable *l8reflect.L8Node, resources ifs.IResources) error {
+	keys, err := parser.ParseSortBy(this.sortBy, this.descending)
+	if err != nil {
+		return err
+	}
+	columns := this.rowColumns()
+	this.sortKeys = make([]*sortKey, 0, len(keys))
+	for _, key := range keys {
+		if this.isAggregate {
+			column := columns[columnReference(key.Property)]
+			if expr, ok, _ := parser.ParseArithmetic(key.Property); ok && column == "" {
+				arithmetic, er := newArithmetic(expr, newRowScope(columns, resources, this.registry))
+				if er != nil {
+					return er
+				}
+				this.sortKeys = append(this.sortKeys, &sortKey{key: key, arithmetic: arithmetic})
+				continue
+			}
+			if column == "" {
+				return errors.New("No Column was found for sort-by key: " + key.Property)
+			}
+			this.sortKeys = append(this.sortKeys, &sortKey{key: key, column: column})
+			continue
+		}
+		sortKey, er := this.newSortKey(key, resources)
+		if er != nil {
+			return errors.New(er.Error())
+		}
+		this.sortKeys = append(this.sortKeys, sortKey)
+	}
+	this.sortByProperty = this.sortKeys[0].property
+	return nil
+}
+
 // initTables resolves the root type from the query's RootType field.
 func (this *Query) initTables(query *l8api.L8Query) error {
 	node, ok := this.resources.Introspector().Node(query.RootType)
@@ -244,18 +339,73 @@ func (this *Query) initTables(query *l8api.L8Query) error {
 
 // initColumns resolves the SELECT columns to property accessors.
 // If the query selects "*", no specific properties are initialized.
+// A time bucketing column, such as "bucket(timestamp, 5m)", must also be a group-by column,
+// on its own or in a rollup or cube, whose values are the buckets of the aggregate rows.
+// Window functions are resolved to windows, computed by Execute.
+// The leading distinct modifier of a distinct projection is not a column.
+// A column's alias names it in result rows, including the rows of a group-by column.
+// Arithmetic columns are computed from the properties they refer to, and are returned
+// in result rows only, as objects have no field to hold them.
 func (this *Query) initColumns(query *l8api.L8Query, resources ifs.IResources) error {
 	if query.Properties != nil && len(query.Properties) == 1 && query.Properties[0] == "*" {
 		return nil
 	} else {
-		for _, col := range query.Properties {
-			propPath := propertyPath(col, this.rootType.TypeName)
+		columns := query.Properties
+		if parser.IsDistinct(query) {
+			this.distinct = true
+			columns = parser.DistinctColumns(query)
+		}
+		for _, col := range columns {
+			if fn, ok, err := parser.ParseWindowFunction(col); ok {
+				if err != nil {
+					return err
+				}
+				w, err := this.newWindow(fn, resources)
+				if err != nil {
+					return err
+				}
+				this.windows = append(this.windows, w)
+				continue
+			}
+			text, alias, err := parser.SplitAlias(col)
+			if err != nil {
+				return err
+			}
+			if alias != "" && isGroupByColumn(query.GroupBy, text) {
+				this.groupAliases[text] = alias
+			}
+			if expr, err := parser.ParseGroupExpression(text); err == nil && expr.Function != "" {
+				if !isGroupByColumn(query.GroupBy, text) {
+					return this.resources.Logger().Error("column ", text, " must be a group-by column")
+				}
+				continue
+			}
+			name := text
+			if alias != "" {
+				name = alias
+			}
+			if expr, ok, _ := parser.ParseArithmetic(text); ok {
+				if len(query.Aggregates) > 0 && !this.distinct {
+					if !isGroupByColumn(query.GroupBy, text) {
+						return this.resources.Logger().Error("column ", text, " must be a group-by column")
+					}
+					continue
+				}
+				arithmetic, err := newArithmetic(expr, newExpressionScope(this.rootType, resources, this.registry))
+				if err != nil {
+					return this.resources.Logger().Error("cannot resolve column ", text, ":", err.Error())
+				}
+				this.columns = append(this.columns, &column{name: name, path: text, arithmetic: arithmetic})
+				continue
+			}
+			propPath := propertyPath(text, this.rootType.TypeName)
 			prop, err := properties.PropertyOf(propPath, resources)
 			if err != nil {
 				return this.resources.Logger().Error("cannot find property for col ", propPath, ":", err.Error())
 			}
-			this.propertiesMap[col] = prop
+			this.propertiesMap[text] = prop
 			this.properties = append(this.properties, prop)
+			this.columns = append(this.columns, &column{name: name, path: text, property: prop})
 		}
 	}
 	return nil
@@ -276,12 +426,30 @@ func propertyPath(colName, rootTable string) string {
 	return rootTablePrefix.String()
 }
 
+// isGroupByColumn reports whether the column is one of the columns of the group-by items.
+func isGroupByColumn(groupBy []string, column string) bool {
+	exprs, _, err := parser.GroupingSets(groupBy)
+	if err != nil {
+		return false
+	}
+	for _, expr := range exprs {
+		if expr.String() == column {
+			return true
+		}
+	}
+	return false
+}
+
 // match evaluates whether the given object matches the query's WHERE clause.
 // Returns true if there is no WHERE clause or if the object matches.
+// Evaluation failures are returned as an *EvaluationError.
 func (this *Query) match(root interface{}) (bool, error) {
 	if root == nil {
 		return false, nil
 	}
+	if value := reflect.ValueOf(root); value.Kind() == reflect.Ptr && value.IsNil() {
+		return false, nil
+	}
 	if this.rootType == nil {
 		return false, nil
 	}
@@ -294,10 +462,32 @@ func (this *Query) match(root interface{}) (bool, error) {
 // Filter applies the query's WHERE clause to a list of objects and returns
 // only the matching objects. If onlySelectedColumns is true and specific
 // columns were selected, returns cloned objects with only those columns populated.
+// With the AbortOnError policy, an evaluation failure is logged and an empty list is returned.
 func (this *Query) Filter(list []interface{}, onlySelectedColumns bool) []interface{} {
+	result, err := this.FilterWithError(list, onlySelectedColumns)
+	if err != nil {
+		this.resources.Logger().Error(err)
+		return make([]interface{}, 0)
+	}
+	return result
+}
+
+// FilterWithError is like Filter, but returns the evaluation failure that aborted it.
+// With the SkipOnError policy, failing objects are logged and treated as non-matching,
+// so the error is always nil. With the AbortOnError policy, the first failure is returned
+// as an *EvaluationError.
+func (this *Query) FilterWithError(list []interface{}, onlySelectedColumns bool) ([]interface{}, error) {
 	result := make([]interface{}, 0)
 	for _, i := range list {
-		if this.Match(i) {
+		m, err := this.match(i)
+		if err != nil {
+			if this.errorPolicy == AbortOnError {
+				return nil, err
+			}
+			this.resources.Logger().Error(err)
+			continue
+		}
+		if m {
 			if !onlySelectedColumns || len(this.properties) == 0 {
 				result = append(result, i)
 			} else {
@@ -305,11 +495,42 @@ func (this *Query) Filter(list []interface{}, onlySelectedColumns bool) []interf
 			}
 		}
 	}
-	return result
+	return result, nil
+}
+
+// SetErrorPolicy sets how Filter handles objects that fail evaluation.
+// The default is SkipOnError.
+func (this *Query) SetErrorPolicy(policy ErrorPolicy) {
+	this.errorPolicy = policy
+}
+
+// ErrorPolicy returns how Filter handles objects that fail evaluation.
+func (this *Query) ErrorPolicy() ErrorPolicy {
+	return this.errorPolicy
+}
+
+// SetStrictEquality sets whether = and != compare strings exactly in the WHERE and HAVING
+// clauses. By default a '*' in the right value of = or != is a wildcard that matches any
+// value containing one of the parts around it; in strict mode it is an ordinary character,
+// and patterns are matched with like, ilike and glob instead.
+func (this *Query) SetStrictEquality(strict bool) {
+	this.strict = strict
+	if this.where != nil {
+		this.where.setStrict(strict)
+	}
+	if this.having != nil {
+		this.having.setStrict(strict)
+	}
+}
+
+// StrictEquality returns whether = and != compare strings exactly.
+func (this *Query) StrictEquality() bool {
+	return this.strict
 }
 
 // Match evaluates whether the given object matches the query's WHERE clause.
 // This is a convenience method that logs errors and returns the boolean result.
+// An object that fails evaluation does not match.
 func (this *Query) Match(any interface{}) bool {
 	m, e := this.match(any)
 	if e != nil {
@@ -318,13 +539,14 @@ func (this *Query) Match(any interface{}) bool {
 	return m
 }
 
-// SortByValue extracts the sort-by property value from the given object.
+// SortByValue extracts the value of the first sort key from the given object,
+// or from the given row for aggregate queries.
 // Returns nil if no sort-by property is configured.
 func (this *Query) SortByValue(v interface{}) interface{} {
-	if this.sortBy == "" {
+	if len(this.sortKeys) == 0 {
 		return nil
 	}
-	resp, e := this.sortByProperty.Get(v)
+	resp, e := this.sortKeys[0].value(v)
 	if e != nil {
 		this.resources.Logger().Error(e)
 	}
@@ -334,6 +556,9 @@ func (this *Query) SortByValue(v interface{}) interface{} {
 // cloneOnlyWithColumns creates a new instance of the object type and copies
 // only the selected column values from the source object.
 func (this *Query) cloneOnlyWithColumns(any interface{}) interface{} {
+	if reflect.ValueOf(any).Kind() != reflect.Ptr {
+		return any
+	}
 	typ := reflect.ValueOf(any).Elem().Type()
 	clone := reflect.New(typ).Interface()
 	for _, column := range this.properties {
@@ -391,86 +616,339 @@ func (this *Query) IsAggregate() bool {
 	return this.isAggregate
 }
 
+// IsDistinct returns true if this query is a distinct projection of its selected columns.
+func (this *Query) IsDistinct() bool {
+	return this.distinct
+}
+
 // Aggregate groups the filtered list by group-by fields and computes
 // aggregate functions for each group. Returns an array of result maps.
 // Each map contains the group-by field values and computed aggregate values.
+// Only the rows matching the HAVING clause are returned, ordered by the sort-by keys
+// and limited to the requested page; with the AbortOnError policy, an evaluation
+// failure is logged and no rows are returned.
 func (this *Query) Aggregate(list []interface{}) []map[string]interface{} {
-	// Group objects by group-by key
-	groups := make(map[string][]interface{})
-	groupOrder := make([]string, 0)
-	groupKeys := make(map[string]map[string]interface{})
-
-	for _, item := range list {
-		key, keyValues := this.buildGroupKey(item)
-		if _, exists := groups[key]; !exists {
-			groups[key] = make([]interface{}, 0)
-			groupOrder = append(groupOrder, key)
-			groupKeys[key] = keyValues
+	rows, _, _, err := this.aggregatePage(list)
+	if err != nil {
+		this.resources.Logger().Error(err)
+		return make([]map[string]interface{}, 0)
+	}
+	return rows
+}
+
+// rowColumns maps the references a HAVING clause may use to the columns of the
+// aggregate rows: group-by columns, aggregate aliases and aggregate function calls
+// such as "count(*)", all matched case-insensitively.
+func (this *Query) rowColumns() map[string]string {
+	columns := make(map[string]string)
+	for _, gb := range this.groupBy {
+		columns[columnReference(gb)] = this.groupColumnName(gb)
+		if alias, ok := this.groupAliases[gb]; ok {
+			columns[columnReference(alias)] = alias
 		}
-		groups[key] = append(groups[key], item)
 	}
+	if this.groupingSets != nil {
+		columns[columnReference(GroupingColumn)] = GroupingColumn
+	}
+	for _, agg := range this.aggregates {
+		columns[columnReference(agg.Alias)] = agg.Alias
+		columns[columnReference(parser.AggregateCall(agg))] = agg.Alias
+	}
+	return columns
+}
 
-	// Compute aggregates for each group
-	results := make([]map[string]interface{}, 0, len(groupOrder))
-	for _, key := range groupOrder {
-		groupItems := groups[key]
-		result := make(map[string]interface{})
+// aggregateGroup holds the group-by values of a group and the accumulators of its
+// aggregate functions, in the order of the query's aggregates.
+type aggregateGroup struct {
+	id           string                 // The group key built from the group-by values
+	keys         map[string]interface{} // Group-by field name -> value, without the rolled up columns
+	grouping     int64                  // The rolled up columns of a grouping set, see GroupingColumn
+	accumulators []*Accumulator         // The accumulators of the aggregate functions
+}
 
-		// Copy group-by values
-		for k, v := range groupKeys[key] {
-			result[k] = v
-		}
+// newAggregateGroup creates a group with an empty accumulator for each aggregate function.
+func (this *Query) newAggregateGroup(id string, keys map[string]interface{}, grouping int64) *aggregateGroup {
+	group := &aggregateGroup{id: id, keys: keys, grouping: grouping, accumulators: make([]*Accumulator, 0, len(this.aggregates))}
+	for _, agg := range this.aggregates {
+		acc, _ := newAccumulator(agg.Function, this.registry)
+		group.accumulators = append(group.accumulators, acc)
+	}
+	return group
+}
 
-		// Compute each aggregate function
-		for _, agg := range this.aggregates {
-			acc := NewAccumulator(agg.Function)
-			for _, item := range groupItems {
+// aggregateGroups groups the list by the group-by fields and accumulates the aggregate
+// functions of each group. The groups are returned in the order they are first seen.
+// With rollup or cube, an object is accumulated once in its group of each grouping set.
+// Objects whose group-by values cannot be grouped, such as a non-timestamp value of a
+// time bucket, are handled according to the query's error policy.
+func (this *Query) aggregateGroups(list []interface{}) ([]*aggregateGroup, error) {
+	groups := make(map[string]*aggregateGroup)
+	groupOrder := make([]*aggregateGroup, 0)
+
+	for _, item := range list {
+		keys, keyValues, err := this.groupKeys(item)
+		if err != nil {
+			if this.errorPolicy == AbortOnError {
+				return nil, err
+			}
+			this.resources.Logger().Error(err)
+			continue
+		}
+		groupings := make([]int64, len(keys))
+		if this.groupingSets != nil {
+			keys, keyValues, groupings = this.groupingKeys(keyValues)
+		}
+		for k, key := range keys {
+			group, exists := groups[key]
+			if !exists {
+				group = this.newAggregateGroup(key, keyValues[k], groupings[k])
+				groups[key] = group
+				groupOrder = append(groupOrder, group)
+			}
+			for i, agg := range this.aggregates {
 				if agg.Field == "*" {
-					acc.Add(nil)
+					group.accumulators[i].Add(nil)
 				} else {
 					prop := this.aggregateProps[agg.Field]
 					if prop != nil {
 						val, _ := prop.Get(item)
-						acc.Add(val)
+						group.accumulators[i].Add(val)
 					}
 				}
 			}
+		}
+	}
+	return groupOrder, nil
+}
+
+// aggregateRows groups the list by the group-by fields and computes the aggregate
+// functions of each group, ordered by their group-by values. It also returns the
+// type of each column, taken from the first group with a known type.
+// An aggregate that fails, such as an integer sum that overflows, is handled according
+// to the query's error policy; when skipped, its value in the row is nil.
+func (this *Query) aggregateRows(list []interface{}) ([]map[string]interface{}, map[string]reflect.Type, error) {
+	groups, err := this.aggregateGroups(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	return this.groupRows(groups)
+}
+
+// groupRows computes the result row of each group, along with the column types, after
+// filling the gaps between time buckets. The rows are in the order of the groups; they
+// are ordered by sortRows.
+func (this *Query) groupRows(groups []*aggregateGroup) ([]map[string]interface{}, map[string]reflect.Type, error) {
+	groups, err := this.fillGaps(groups)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([]map[string]interface{}, 0, len(groups))
+	types := make(map[string]reflect.Type)
+	for _, group := range groups {
+		result := make(map[string]interface{})
+
+		// Copy group-by values under their column names, with nil for the rolled up columns
+		for k, v := range group.keys {
+			name := this.groupColumnName(k)
+			result[name] = v
+			if types[name] == nil && v != nil {
+				types[name] = reflect.TypeOf(v)
+			}
+		}
+		if this.groupingSets != nil {
+			for _, gb := range this.groupBy {
+				if _, ok := group.keys[gb]; !ok {
+					result[this.groupColumnName(gb)] = nil
+				}
+			}
+			result[GroupingColumn] = group.grouping
+			types[GroupingColumn] = int64Type
+		}
+
+		// Compute each aggregate function
+		for i, agg := range this.aggregates {
+			acc := group.accumulators[i]
+			if err := this.aggregateError(agg, acc.Err()); err != nil {
+				return nil, nil, err
+			}
 			result[agg.Alias] = acc.Result()
+			if types[agg.Alias] == nil {
+				types[agg.Alias] = acc.ResultType()
+			}
 		}
 
 		results = append(results, result)
 	}
 
-	return results
+	return results, types, nil
 }
 
-// buildGroupKey creates a string key from the group-by field values of an object.
-// Also returns a map of field name -> value for constructing the result.
-func (this *Query) buildGroupKey(item interface{}) (string, map[string]interface{}) {
-	keyValues := make(map[string]interface{})
+// compareGroupRows orders two aggregate rows by their group-by values, in the order of
+// the group-by columns, with nil values first. A rolled up column sorts after all its
+// values, so subtotals follow the rows they sum up.
+func (this *Query) compareGroupRows(a, b map[string]interface{}) int {
+	for col, gb := range this.groupBy {
+		if this.groupingSets != nil {
+			bit := int64(1) << (len(this.groupBy) - 1 - col)
+			rolledA, rolledB := a[GroupingColumn].(int64)&bit != 0, b[GroupingColumn].(int64)&bit != 0
+			if rolledA != rolledB {
+				if rolledA {
+					return 1
+				}
+				return -1
+			}
+		}
+		name := this.groupColumnName(gb)
+		if c := compareValues(a[name], b[name]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// groupColumnName returns the name of a group-by column in the aggregate rows:
+// the alias of its SELECT column, or the column itself.
+func (this *Query) groupColumnName(gb string) string {
+	if alias, ok := this.groupAliases[gb]; ok {
+		return alias
+	}
+	return gb
+}
+
+// aggregateError handles the failure of an aggregate function according to the query's
+// error policy, returning the error to abort with or nil if the failure was logged.
+func (this *Query) aggregateError(agg *l8api.L8AggregateFunction, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ee, ok := err.(*EvaluationError); ok && ee.Property == "" {
+		ee.Property = agg.Field
+		ee.Operator = parser.AggregateCall(agg)
+	}
+	if this.errorPolicy == AbortOnError {
+		return err
+	}
+	this.resources.Logger().Error(err)
+	return nil
+}
+
+// groupKeys returns the keys of the groups an object belongs to, along with the group-by
+// field name -> value map of each group. A key holds the type and value of each group-by
+// value, so values of different types never share a group.
+// A group-by property with multiple values, such as a repeated field or a path through
+// one, is unnested: the object belongs to the group of each distinct element, and with
+// several multi-valued properties to the group of each combination of their elements.
+// An empty collection puts the object in the group of a nil value.
+// The values of time bucketing functions are the buckets of the property values.
+func (this *Query) groupKeys(item interface{}) ([]string, []map[string]interface{}, error) {
 	if len(this.groupByProps) == 0 {
 		// No group-by — all items in one group
-		return "__all__", keyValues
+		return []string{"__all__"}, []map[string]interface{}{{}}, nil
 	}
 
-	buff := bytes.Buffer{}
+	keys := []string{""}
+	keyValues := []map[string]interface{}{{}}
 	for i, prop := range this.groupByProps {
-		val, _ := prop.Get(item)
+		var val interface{}
+		if arithmetic := this.groupByArith[i]; arithmetic != nil {
+			v, err := arithmetic.Evaluate(item)
+			if err != nil {
+				return nil, nil, &EvaluationError{Property: this.groupBy[i], Operator: parser.GroupBy,
+					LeftKind: reflect.ValueOf(item).Kind(), Message: "Cannot evaluate group-by column", Err: err}
+			}
+			val = v
+		} else {
+			val, _ = prop.Get(item)
+		}
+		elements, elementKeys, err := this.groupValues(i, val)
+		if err != nil {
+			return nil, nil, err
+		}
+		nextKeys := make([]string, 0, len(keys)*len(elements))
+		nextValues := make([]map[string]interface{}, 0, len(keys)*len(elements))
+		for k, key := range keys {
+			if i > 0 {
+				key += "|"
+			}
+			for e, element := range elements {
+				values := make(map[string]interface{}, len(keyValues[k])+1)
+				for name, value := range keyValues[k] {
+					values[name] = value
+				}
+				values[this.groupBy[i]] = element
+				nextKeys = append(nextKeys, key+elementKeys[e])
+				nextValues = append(nextValues, values)
+			}
+		}
+		keys = nextKeys
+		keyValues = nextValues
+	}
+	return keys, keyValues, nil
+}
+
+// groupId returns the group key of the given group-by values, as built by groupKeys.
+// A rolled up column, absent from the values, has the key "*", which no value has.
+func (this *Query) groupId(values map[string]interface{}) string {
+	key := ""
+	for i, gb := range this.groupBy {
 		if i > 0 {
-			buff.WriteString("|")
+			key += "|"
 		}
-		key := this.groupBy[i]
-		keyValues[key] = val
-		if val != nil {
-			buff.WriteString(strings.Replace(fmt.Sprintf("%v", val), "|", "\\|", -1))
+		if _, ok := values[gb]; ok || this.groupingSets == nil {
+			key += groupValueKey(values[gb])
 		} else {
-			buff.WriteString("<nil>")
+			key += "*"
 		}
 	}
-	return buff.String(), keyValues
+	return key
+}
+
+// groupValues returns the distinct values of the group-by column at the given index for
+// a property value, along with the key of each: the distinct elements of a multi-valued
+// property, or the value itself, or their time buckets for a bucketing function.
+// An empty collection has the single value nil.
+func (this *Query) groupValues(index int, value interface{}) ([]interface{}, []string, error) {
+	elements := []interface{}{value}
+	kind := reflect.ValueOf(value).Kind()
+	if kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
+		elements = valuesOf(value, nil)
+		if len(elements) == 0 {
+			elements = []interface{}{nil}
+		}
+	}
+	expr := this.groupByExprs[index]
+	values := make([]interface{}, 0, len(elements))
+	keys := make([]string, 0, len(elements))
+	seen := make(map[string]bool)
+	for _, element := range elements {
+		if expr.Function != "" {
+			bucket, err := bucketOf(expr, element)
+			if err != nil {
+				return nil, nil, &EvaluationError{Property: this.groupBy[index], Operator: expr.Function,
+					LeftKind: reflect.ValueOf(element).Kind(), Message: "Cannot group by time bucket", Err: err}
+			}
+			element = bucket
+		}
+		key := groupValueKey(element)
+		if !seen[key] {
+			seen[key] = true
+			values = append(values, element)
+			keys = append(keys, key)
+		}
+	}
+	return values, keys, nil
+}
+
+// groupValueKey returns the key of a group-by value, made of its type and value.
+func groupValueKey(value interface{}) string {
+	if value == nil {
+		return "<nil>"
+	}
+	return strings.Replace(fmt.Sprintf("%T:%v", value, value), "|", "\\|", -1)
 }
 
-// SortByProperty returns the resolved sort-by property, or nil.
+// SortByProperty returns the resolved property of the first sort key, or nil
+// for aggregate queries.
 func (this *Query) SortByProperty() *properties.Property {
 	return this.sortByProperty
 }
@@ -0,0 +1,455 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Registry.go provides the registration of user-defined comparators, scalar functions and
+// aggregate functions. Extensions are registered in a Registry that is passed to the queries
+// that use them, so tenants with different registries can have different extensions. A query
+// resolves its extensions and checks their signatures when it is created by
+// NewFromQueryWithRegistry, so later registrations do not change existing queries.
+package interpreter
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ArgType is the type of an operand or argument of a user-defined extension. Values are
+// converted to the type before they are passed to the extension, and nil is passed as nil.
+type ArgType int
+
+// Types of operands and arguments.
+const (
+	AnyArg    ArgType = iota // Any value, passed as it is
+	StringArg                // A string
+	IntArg                   // An integer or a string of an integer, passed as an int64
+	FloatArg                 // A number or a string of a number, passed as a float64
+	BoolArg                  // A bool or the string true or false, passed as a bool
+)
+
+// String returns the name of the type.
+func (this ArgType) String() string {
+	switch this {
+	case StringArg:
+		return "string"
+	case IntArg:
+		return "int"
+	case FloatArg:
+		return "float"
+	case BoolArg:
+		return "bool"
+	}
+	return "any"
+}
+
+// UserComparator is a user-defined comparison operator, written as a word between its
+// operands, e.g. "address within '10.0.0.0/8'". Like the built-in comparators, string
+// operands are lowercased unless the query is case-sensitive. Unlike them, repeated
+// operands are passed as they are, as slices or maps, which only AnyArg accepts.
+type UserComparator struct {
+	Left    ArgType                                     // The type of the left operand
+	Right   ArgType                                     // The type of the right operand
+	Compare func(left, right interface{}) (bool, error) // Reports whether the operands match
+}
+
+// UserFunction is a user-defined scalar function, called like the built-in functions in
+// SELECT, WHERE, GROUP-BY and SORT-BY. A call of a function with a bool result may be a
+// WHERE predicate on its own.
+type UserFunction struct {
+	Args     []ArgType                                     // The types of the arguments
+	Variadic bool                                          // Whether more arguments of the type of the last one may follow
+	Result   ArgType                                       // The type of the result
+	Call     func(args []interface{}) (interface{}, error) // Computes the result from the arguments
+}
+
+// UserAggregate is a user-defined aggregate function, called with a field or * followed
+// by constant arguments, e.g. "top_share(bytes, 10)". Its default alias is built like
+// the aliases of the built-in aggregates, e.g. "topShare10Bytes".
+type UserAggregate struct {
+	Field ArgType                                      // The type of the aggregated values, ignored for *, where nil is added once per object
+	Args  []ArgType                                    // The types of the constant arguments
+	New   func(args []interface{}) (Aggregator, error) // Creates the aggregator of a group
+}
+
+// Aggregator computes a user-defined aggregate function over the values of a group.
+type Aggregator interface {
+	Add(value interface{}) error // Incorporates a value, failing if it cannot be aggregated
+	Result() interface{}         // The result, or nil if there is none
+}
+
+// PartialAggregator is an Aggregator whose state can be merged across nodes, as required
+// by aggregate queries in map-reduce mode.
+type PartialAggregator interface {
+	Aggregator
+	Partial() (*structpb.Value, error) // The state, to be merged into an aggregator of the same call
+	Merge(state *structpb.Value) error // Incorporates the state of an aggregator of the same call
+}
+
+// Registry holds user-defined extensions. It is owned by the caller, who passes it to the
+// queries that may use its extensions, e.g. one registry per tenant, and it is released
+// with them. Registration is safe for concurrent use.
+type Registry struct {
+	mtx         sync.RWMutex
+	comparators map[string]*UserComparator
+	functions   map[string]*UserFunction
+	aggregates  map[string]*UserAggregate
+}
+
+// NewRegistry creates a registry with no extensions.
+func NewRegistry() *Registry {
+	return &Registry{comparators: make(map[string]*UserComparator), functions: make(map[string]*UserFunction),
+		aggregates: make(map[string]*UserAggregate)}
+}
+
+// RegisterComparator registers a user-defined comparator.
+// Returns an error if the name is not a word, is reserved or is already registered.
+func (this *Registry) RegisterComparator(name string, comparator *UserComparator) error {
+	if comparator == nil || comparator.Compare == nil {
+		return errors.New("Comparator " + name + " has no Compare function")
+	}
+	name, err := extensionName(name)
+	if err != nil {
+		return err
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if err = this.checkUnused(name); err != nil {
+		return err
+	}
+	this.comparators[name] = comparator
+	return nil
+}
+
+// RegisterFunction registers a user-defined scalar function.
+// Returns an error if the name is not a word, is reserved or is already registered.
+func (this *Registry) RegisterFunction(name string, function *UserFunction) error {
+	if function == nil || function.Call == nil {
+		return errors.New("Function " + name + " has no Call function")
+	}
+	if function.Variadic && len(function.Args) == 0 {
+		return errors.New("Variadic function " + name + " has no arguments")
+	}
+	name, err := extensionName(name)
+	if err != nil {
+		return err
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if err = this.checkUnused(name); err != nil {
+		return err
+	}
+	this.functions[name] = function
+	return nil
+}
+
+// RegisterAggregate registers a user-defined aggregate function.
+// Returns an error if the name is not a word, is reserved or is already registered.
+func (this *Registry) RegisterAggregate(name string, aggregate *UserAggregate) error {
+	if aggregate == nil || aggregate.New == nil {
+		return errors.New("Aggregate function " + name + " has no New function")
+	}
+	name, err := extensionName(name)
+	if err != nil {
+		return err
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if err = this.checkUnused(name); err != nil {
+		return err
+	}
+	this.aggregates[name] = aggregate
+	return nil
+}
+
+// extensionName returns the lowercase name of an extension, or an error if the name is
+// not a word or is reserved.
+func extensionName(name string) (string, error) {
+	name = strings.ToLower(name)
+	if name == "" || !isName(name) {
+		return "", errors.New("Invalid extension name '" + name + "', expected a word")
+	}
+	if parser.IsReserved(name) {
+		return "", errors.New("Extension name " + name + " is reserved")
+	}
+	return name, nil
+}
+
+// isName reports whether the name is a word of letters, digits and underscores,
+// not starting with a digit.
+func isName(name string) bool {
+	for i, c := range name {
+		letter := c >= 'a' && c <= 'z' || c == '_'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUnused returns an error if an extension of the name is registered.
+func (this *Registry) checkUnused(name string) error {
+	_, comparator := this.comparators[name]
+	_, function := this.functions[name]
+	_, aggregate := this.aggregates[name]
+	if comparator || function || aggregate {
+		return errors.New("Extension " + name + " is already registered")
+	}
+	return nil
+}
+
+// IsAggregate reports whether the name is a user-defined aggregate function, so the parser
+// can detect its calls. A nil registry has no extensions.
+func (this *Registry) IsAggregate(name string) bool {
+	return this.aggregate(name) != nil
+}
+
+// comparator returns the user-defined comparator of the name, or nil.
+func (this *Registry) comparator(name string) *UserComparator {
+	if this == nil {
+		return nil
+	}
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	return this.comparators[name]
+}
+
+// function returns the user-defined scalar function of the name, or nil.
+func (this *Registry) function(name string) *UserFunction {
+	if this == nil {
+		return nil
+	}
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	return this.functions[name]
+}
+
+// aggregate returns the user-defined aggregate function of the name, or nil.
+func (this *Registry) aggregate(name string) *UserAggregate {
+	if this == nil {
+		return nil
+	}
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	return this.aggregates[name]
+}
+
+// argumentOf converts a value to the type of an operand or argument.
+func argumentOf(value interface{}, typ ArgType) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+		value = v.Interface()
+	}
+	if value == nil || typ == AnyArg {
+		return value, nil
+	}
+	switch typ {
+	case StringArg:
+		if v.Kind() == reflect.String {
+			return v.String(), nil
+		}
+	case BoolArg:
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+		if v.Kind() == reflect.String {
+			return castString(strings.TrimSpace(v.String()), parser.CastBool)
+		}
+	case IntArg:
+		if v.Kind() == reflect.String {
+			return castString(strings.TrimSpace(v.String()), parser.CastInt)
+		}
+		if n, err := arithmeticOperand(value); err == nil && n.class != floatNumber {
+			return castFunction([]interface{}{value, parser.CastInt})
+		}
+	case FloatArg:
+		if v.Kind() == reflect.String {
+			return castString(strings.TrimSpace(v.String()), parser.CastFloat)
+		}
+		if n, err := arithmeticOperand(value); err == nil {
+			return n.float(), nil
+		}
+	}
+	return nil, errors.New("Expected " + typ.String() + ", got " + v.Kind().String())
+}
+
+// unquote strips the single quotes of a quoted literal operand.
+func unquote(literal string) string {
+	if len(literal) >= 2 && literal[0] == '\'' && literal[len(literal)-1] == '\'' {
+		return literal[1 : len(literal)-1]
+	}
+	return literal
+}
+
+// userComparable is the Comparable of a user-defined comparator.
+type userComparable struct {
+	name         string          // The name of the comparator
+	comparator   *UserComparator // The comparator
+	negated      bool            // Whether the comparison is negated by not
+	leftLiteral  bool            // Whether the left operand is a literal, which may be quoted
+	rightLiteral bool            // Whether the right operand is a literal, which may be quoted
+}
+
+// Compare converts the operands to the types of the comparator and compares them,
+// reporting the opposite for a negated comparison. A nil operand does not match, so it
+// matches a negated comparison.
+func (this *userComparable) Compare(left, right interface{}) (bool, error) {
+	match, err := this.compare(left, right)
+	if err != nil {
+		return false, err
+	}
+	return match != this.negated, nil
+}
+
+// compare converts the operands to the types of the comparator and compares them.
+func (this *userComparable) compare(left, right interface{}) (bool, error) {
+	if s, ok := left.(string); ok && this.leftLiteral {
+		left = unquote(s)
+	}
+	if s, ok := right.(string); ok && this.rightLiteral {
+		right = unquote(s)
+	}
+	left, err := argumentOf(left, this.comparator.Left)
+	if err != nil {
+		return false, errors.New("Invalid left operand of " + this.name + ": " + err.Error())
+	}
+	right, err = argumentOf(right, this.comparator.Right)
+	if err != nil {
+		return false, errors.New("Invalid right operand of " + this.name + ": " + err.Error())
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+	return this.comparator.Compare(left, right)
+}
+
+// argType returns the type of the argument at the index, or false if the function
+// does not accept that many arguments.
+func (this *UserFunction) argType(index int) (ArgType, bool) {
+	if index < len(this.Args) {
+		return this.Args[index], true
+	}
+	if this.Variadic {
+		return this.Args[len(this.Args)-1], true
+	}
+	return AnyArg, false
+}
+
+// checkArgs returns an error if the function does not accept the number of arguments.
+func (this *UserFunction) checkArgs(name string, n int) error {
+	if n >= len(this.Args) && (this.Variadic || n == len(this.Args)) {
+		return nil
+	}
+	expected := strconv.Itoa(len(this.Args))
+	if this.Variadic {
+		expected += " or more"
+	}
+	return errors.New("Function " + name + " expects " + expected + " argument(s), got " + strconv.Itoa(n))
+}
+
+// scalar returns the scalarFunction that converts the arguments to the types of the
+// function, calls it and converts its result to the type of the result.
+func (this *UserFunction) scalar(name string) scalarFunction {
+	return func(args []interface{}) (interface{}, error) {
+		converted := make([]interface{}, len(args))
+		for i, arg := range args {
+			typ, _ := this.argType(i)
+			value, err := argumentOf(arg, typ)
+			if err != nil {
+				return nil, errors.New("Invalid argument " + strconv.Itoa(i+1) + " of " + name + ": " + err.Error())
+			}
+			converted[i] = value
+		}
+		result, err := this.Call(converted)
+		if err != nil {
+			return nil, err
+		}
+		result, err = argumentOf(result, this.Result)
+		if err != nil {
+			return nil, errors.New("Invalid result of " + name + ": " + err.Error())
+		}
+		return result, nil
+	}
+}
+
+// newAggregator creates the aggregator of a call of the aggregate function with the
+// constant arguments of the call.
+func (this *UserAggregate) newAggregator(name string, args []string) (aggregator, error) {
+	if len(args) != len(this.Args) {
+		return nil, errors.New("Aggregate function " + name + " expects " + strconv.Itoa(len(this.Args)+1) +
+			" argument(s), got " + strconv.Itoa(len(args)+1))
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		value, err := argumentOf(unquote(arg), this.Args[i])
+		if err != nil {
+			return nil, errors.New("Invalid argument " + strconv.Itoa(i+2) + " of " + name + ": " + err.Error())
+		}
+		converted[i] = value
+	}
+	agg, err := this.New(converted)
+	if err != nil {
+		return nil, err
+	}
+	return &userAggregator{name: name, field: this.Field, agg: agg}, nil
+}
+
+// userAggregator is the aggregator of a user-defined aggregate function.
+type userAggregator struct {
+	name  string     // The name of the function
+	field ArgType    // The type of the aggregated values
+	agg   Aggregator // The aggregator of the function
+}
+
+func (a *userAggregator) add(value interface{}) error {
+	converted, err := argumentOf(value, a.field)
+	if err != nil {
+		return aggregateError("Cannot aggregate value with "+a.name+": "+err.Error(), value, reflect.Invalid, nil)
+	}
+	return a.agg.Add(converted)
+}
+
+func (a *userAggregator) result() interface{} {
+	return a.agg.Result()
+}
+
+func (a *userAggregator) resultType() reflect.Type {
+	if result := a.agg.Result(); result != nil {
+		return reflect.TypeOf(result)
+	}
+	return nil
+}
+
+func (a *userAggregator) partial() (*structpb.Value, error) {
+	if partial, ok := a.agg.(PartialAggregator); ok {
+		return partial.Partial()
+	}
+	return nil, errors.New("Aggregate function " + a.name + " does not support map-reduce")
+}
+
+func (a *userAggregator) merge(state *structpb.Value) error {
+	if partial, ok := a.agg.(PartialAggregator); ok {
+		return partial.Merge(state)
+	}
+	return errors.New("Aggregate function " + a.name + " does not support map-reduce")
+}
@@ -0,0 +1,244 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Result.go executes a query against a list of objects in a single call:
+// filtering, sorting, paging and projection, or aggregation for aggregate queries.
+package interpreter
+
+import (
+	"reflect"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// Result is the outcome of executing a query against a list of objects.
+type Result struct {
+	Objects []interface{} // The objects of the requested page (non-aggregate queries)
+	// Rows are the aggregate rows that passed HAVING, the unique rows of a distinct
+	// projection, or the columns of each object of window and arithmetic queries.
+	Rows  []map[string]interface{}
+	Types map[string]reflect.Type // The type of each column of the rows, nil where no row has a value (queries with rows)
+	Total int                     // The number of matching objects, or of aggregate or unique rows, before paging
+}
+
+// Execute applies the query to the list. For a non-aggregate query, the objects matching
+// the WHERE clause are sorted by the sort-by keys (keeping the original order of equal
+// objects), the requested page is taken and, if specific columns were selected, each object
+// is cloned with only those columns. For an aggregate query, the matching objects are
+// aggregated, the rows are filtered by the HAVING clause, sorted by the sort-by keys and
+// the requested page of rows is taken.
+// For a distinct projection, the matching objects are sorted and the requested page of
+// their unique rows is taken, in the order of the first object of each row.
+// When the SELECT clause has window functions, they are computed over all the sorted
+// matching objects before paging, and the result also holds a row for each object of the
+// page, with the values of its selected columns and window functions. Queries with
+// arithmetic columns also return a row for each object of the page.
+// Pages are zero-based and a limit of 0 returns all objects or rows.
+// Evaluation failures are handled according to the query's error policy.
+func (this *Query) Execute(list []interface{}) (*Result, error) {
+	matched, err := this.FilterWithError(list, false)
+	if err != nil {
+		return nil, err
+	}
+	if this.isAggregate {
+		rows, types, total, err := this.aggregatePage(matched)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Rows: rows, Types: types, Total: total}, nil
+	}
+	if this.distinct {
+		sorted, err := this.sortObjects(matched, 0)
+		if err != nil {
+			return nil, err
+		}
+		rows, types, err := this.distinctRows(sorted)
+		if err != nil {
+			return nil, err
+		}
+		start, end := this.pageRange(len(rows))
+		return &Result{Rows: rows[start:end], Types: types, Total: len(rows)}, nil
+	}
+	result := &Result{Total: len(matched)}
+	top := this.top()
+	if len(this.windows) > 0 {
+		top = 0
+	}
+	sorted, err := this.sortObjects(matched, top)
+	if err != nil {
+		return nil, err
+	}
+	start, end := this.pageRange(len(sorted))
+	if len(this.windows) > 0 {
+		rows, err := this.windowRows(sorted)
+		if err != nil {
+			return nil, err
+		}
+		result.Rows, result.Types, err = this.pageRows(sorted[start:end], rows[start:end])
+		if err != nil {
+			return nil, err
+		}
+	} else if this.hasArithmeticColumns() {
+		rows := make([]map[string]interface{}, end-start)
+		for i := range rows {
+			rows[i] = make(map[string]interface{}, len(this.columns))
+		}
+		result.Rows, result.Types, err = this.pageRows(sorted[start:end], rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	page := make([]interface{}, 0, end-start)
+	for _, item := range sorted[start:end] {
+		if len(this.properties) > 0 {
+			item = this.cloneOnlyWithColumns(item)
+		}
+		page = append(page, item)
+	}
+	result.Objects = page
+	return result, nil
+}
+
+// aggregatePage aggregates the list, filters the rows by the HAVING clause and returns
+// the requested page of sorted rows, along with the column types and the number of rows
+// before paging. Only the rows up to the end of the page are ordered, not all of them.
+func (this *Query) aggregatePage(list []interface{}) ([]map[string]interface{}, map[string]reflect.Type, int, error) {
+	rows, types, err := this.aggregateRows(list)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return this.rowsPage(rows, types)
+}
+
+// rowsPage filters the aggregate rows by the HAVING clause and returns the requested
+// page of sorted rows, along with the column types and the number of rows before paging.
+func (this *Query) rowsPage(rows []map[string]interface{}, types map[string]reflect.Type) ([]map[string]interface{}, map[string]reflect.Type, int, error) {
+	rows, err := this.havingRows(rows)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	sorted, err := this.sortRows(rows, this.top())
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	start, end := this.pageRange(len(sorted))
+	return sorted[start:end], types, len(rows), nil
+}
+
+// top returns the number of items up to the end of the requested page,
+// or 0 if all items are requested.
+func (this *Query) top() int {
+	if this.limit <= 0 || this.page < 0 {
+		return 0
+	}
+	return (int(this.page) + 1) * int(this.limit)
+}
+
+// pageRange returns the bounds of the requested page within a list of the given size.
+func (this *Query) pageRange(size int) (int, int) {
+	if this.limit <= 0 {
+		return 0, size
+	}
+	start := int(this.page) * int(this.limit)
+	if this.page < 0 || start >= size {
+		return size, size
+	}
+	end := start + int(this.limit)
+	if end > size {
+		end = size
+	}
+	return start, end
+}
+
+// selectedValue returns the value of a selected column of the item, computing the value
+// of an arithmetic column. Failures are handled according to the query's error policy.
+func (this *Query) selectedValue(col *column, item interface{}) (interface{}, error) {
+	if col.arithmetic == nil {
+		return this.columnValue(col.path, col.property, item)
+	}
+	v, err := col.arithmetic.Evaluate(item)
+	if err == nil {
+		return v, nil
+	}
+	evalErr := &EvaluationError{Property: col.path, Operator: parser.Select,
+		LeftKind: reflect.ValueOf(item).Kind(), Message: "Cannot evaluate column", Err: err}
+	if this.errorPolicy == AbortOnError {
+		return nil, evalErr
+	}
+	this.resources.Logger().Error(evalErr)
+	return nil, nil
+}
+
+// columnValue returns the value of a selected column of an object. A failure is handled
+// according to the query's error policy; when skipped, the value is nil.
+func (this *Query) columnValue(col string, prop ifs.IProperty, item interface{}) (interface{}, error) {
+	v, err := prop.Get(item)
+	if err == nil {
+		return v, nil
+	}
+	evalErr := &EvaluationError{Property: col, Operator: parser.Select,
+		LeftKind: reflect.ValueOf(item).Kind(), Message: "Cannot get column value", Err: err}
+	if this.errorPolicy == AbortOnError {
+		return nil, evalErr
+	}
+	this.resources.Logger().Error(evalErr)
+	return nil, nil
+}
+
+// pageRows adds the values of the selected columns of the objects of a page to their
+// rows, which hold the values of the window functions, and returns the rows with the
+// type of each column.
+func (this *Query) pageRows(page []interface{}, rows []map[string]interface{}) ([]map[string]interface{}, map[string]reflect.Type, error) {
+	types := make(map[string]reflect.Type)
+	for i, item := range page {
+		for _, col := range this.columns {
+			v, err := this.selectedValue(col, item)
+			if err != nil {
+				return nil, nil, err
+			}
+			rows[i][col.name] = v
+		}
+		for col, v := range rows[i] {
+			if types[col] == nil && !isNilValue(reflect.ValueOf(v)) {
+				types[col] = reflect.TypeOf(v)
+			}
+		}
+	}
+	return rows, types, nil
+}
+
+// havingRows returns the aggregate rows that match the HAVING clause.
+// Rows that fail evaluation are handled according to the query's error policy.
+func (this *Query) havingRows(rows []map[string]interface{}) ([]map[string]interface{}, error) {
+	if this.having == nil {
+		return rows, nil
+	}
+	result := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		m, err := this.having.Match(row, this.matchCase)
+		if err != nil {
+			if this.errorPolicy == AbortOnError {
+				return nil, err
+			}
+			this.resources.Logger().Error(err)
+			continue
+		}
+		if m {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,321 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Sketches.go implements the approximate aggregates, whose memory does not grow with the
+// number of values: approx_count_distinct, using a HyperLogLog sketch, and approx_percentile,
+// using a merging t-digest. Both sketches are mergeable, so they work in map-reduce mode.
+package interpreter
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Defaults and limits of the sketch sizes.
+const (
+	DefaultDistinctError   = 0.01 // Default relative standard error of approx_count_distinct
+	DefaultPercentileError = 0.01 // Default rank error of approx_percentile
+	minPrecision           = 4    // Smallest HyperLogLog precision, 16 registers
+	maxPrecision           = 18   // Largest HyperLogLog precision, 256K registers
+)
+
+// parseErrorBound parses the optional error bound argument of an approximate aggregate.
+func parseErrorBound(args []string, defaultError float64) (float64, error) {
+	if len(args) == 0 {
+		return defaultError, nil
+	}
+	e, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || e <= 0 || e >= 1 {
+		return 0, errors.New("Error bound must be a number between 0 and 1: " + args[0])
+	}
+	return e, nil
+}
+
+// hllAggregator estimates the number of distinct non-nil values with a HyperLogLog sketch.
+// The relative standard error is about 1.04/sqrt(2^precision).
+type hllAggregator struct {
+	precision uint8   // The number of hash bits selecting a register
+	registers []uint8 // The maximum rank seen by each register
+}
+
+// newHllAggregator creates the aggregator of approx_count_distinct(field[, error]), with the
+// smallest precision whose standard error is within the requested relative error.
+func newHllAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) > 1 {
+		return nil, errors.New("Aggregate function approx_count_distinct expects an optional error bound")
+	}
+	e, err := parseErrorBound(args, DefaultDistinctError)
+	if err != nil {
+		return nil, err
+	}
+	precision := int(math.Ceil(math.Log2((1.04 / e) * (1.04 / e))))
+	if precision < minPrecision {
+		precision = minPrecision
+	} else if precision > maxPrecision {
+		precision = maxPrecision
+	}
+	return &hllAggregator{precision: uint8(precision), registers: make([]uint8, 1<<precision)}, nil
+}
+
+func (a *hllAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	hash := hashValue(value)
+	index := hash >> (64 - a.precision)
+	rank := uint8(bits.LeadingZeros64(hash<<a.precision|1<<(a.precision-1))) + 1
+	if rank > a.registers[index] {
+		a.registers[index] = rank
+	}
+	return nil
+}
+
+// hashValue returns a 64 bit hash of the value and its type, so that values are distinct
+// under the same rules as count(distinct).
+func hashValue(value interface{}) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%T:%v", value, value)
+	// Finalize with the MurmurHash3 mixer, as HyperLogLog needs well distributed bits
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// result returns the estimate, using linear counting while many registers are empty.
+func (a *hllAggregator) result() interface{} {
+	m := float64(len(a.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range a.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	switch len(a.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	}
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+func (a *hllAggregator) resultType() reflect.Type {
+	return int64Type
+}
+
+func (a *hllAggregator) partial() (*structpb.Value, error) {
+	return newState(map[string]*structpb.Value{
+		"precision": structpb.NewNumberValue(float64(a.precision)),
+		"registers": structpb.NewStringValue(base64.StdEncoding.EncodeToString(a.registers)),
+	}), nil
+}
+
+// merge takes the maximum rank of each register. Both sketches must have the same precision.
+func (a *hllAggregator) merge(state *structpb.Value) error {
+	fields := state.GetStructValue().GetFields()
+	if uint8(fields["precision"].GetNumberValue()) != a.precision {
+		return errors.New("Cannot merge approx_count_distinct sketches of different precisions")
+	}
+	registers, err := base64.StdEncoding.DecodeString(fields["registers"].GetStringValue())
+	if err != nil || len(registers) != len(a.registers) {
+		return errors.New("Invalid approx_count_distinct sketch")
+	}
+	for i, r := range registers {
+		if r > a.registers[i] {
+			a.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// centroid is a cluster of values of a t-digest, represented by their mean and count.
+type centroid struct {
+	mean   float64 // The mean of the values
+	weight float64 // The number of values
+}
+
+// tdigestAggregator estimates a percentile of the numeric values with a merging t-digest.
+// Centroids are small near the extremes and larger in the middle, so that the rank error
+// is about 1/compression and smaller for extreme percentiles. While there are fewer
+// values than the compression, every value is its own centroid and the result is exact.
+type tdigestAggregator struct {
+	percentile  float64    // The percentile to compute, between 0 and 100
+	compression float64    // The compression, the inverse of the rank error
+	centroids   []centroid // The compressed centroids, sorted by mean
+	buffer      []centroid // The centroids added since the last compression
+	total       float64    // The total weight of the centroids and the buffer
+	min         float64    // The smallest value
+	max         float64    // The largest value
+}
+
+// newTdigestAggregator creates the aggregator of approx_percentile(field, p[, error]).
+func newTdigestAggregator(fn string, args []string) (aggregator, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("Aggregate function approx_percentile expects a percentile and an optional error bound")
+	}
+	p, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || p < 0 || p > 100 {
+		return nil, errors.New("Percentile must be a number between 0 and 100: " + args[0])
+	}
+	e, err := parseErrorBound(args[1:], DefaultPercentileError)
+	if err != nil {
+		return nil, err
+	}
+	return &tdigestAggregator{percentile: p, compression: math.Ceil(1 / e), min: math.Inf(1), max: math.Inf(-1)}, nil
+}
+
+func (a *tdigestAggregator) add(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	num, ok := toFloat64(value)
+	if !ok {
+		return aggregateError("Cannot compute a percentile of a non-numeric value", value, reflect.Float64, nil)
+	}
+	a.addCentroid(centroid{mean: num, weight: 1})
+	return nil
+}
+
+// addCentroid adds a centroid to the buffer, compressing when the buffer is full.
+func (a *tdigestAggregator) addCentroid(c centroid) {
+	a.buffer = append(a.buffer, c)
+	a.total += c.weight
+	a.min = math.Min(a.min, c.mean)
+	a.max = math.Max(a.max, c.mean)
+	if len(a.buffer) >= int(5*a.compression) {
+		a.compress()
+	}
+}
+
+// compress merges the buffer into the centroids, combining adjacent centroids as long as
+// the combined centroid spans at most one unit of the scale function.
+func (a *tdigestAggregator) compress() {
+	if len(a.buffer) == 0 {
+		return
+	}
+	all := append(a.centroids, a.buffer...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	merged := make([]centroid, 0, len(all))
+	current := all[0]
+	before := 0.0
+	for _, c := range all[1:] {
+		if a.scale((before+current.weight+c.weight)/a.total)-a.scale(before/a.total) <= 1 {
+			current.mean += (c.mean - current.mean) * c.weight / (current.weight + c.weight)
+			current.weight += c.weight
+			continue
+		}
+		merged = append(merged, current)
+		before += current.weight
+		current = c
+	}
+	a.centroids = append(merged, current)
+	a.buffer = nil
+}
+
+// scale is the t-digest scale function k1, mapping a quantile to the centroid index space.
+func (a *tdigestAggregator) scale(q float64) float64 {
+	return a.compression / (2 * math.Pi) * math.Asin(2*math.Min(1, q)-1)
+}
+
+// result interpolates between the centers of the centroids around the rank of the percentile,
+// using the same rank as the exact percentile.
+func (a *tdigestAggregator) result() interface{} {
+	a.compress()
+	if len(a.centroids) == 0 {
+		return nil
+	}
+	rank := a.percentile/100*(a.total-1) + 0.5
+	center := a.centroids[0].weight / 2
+	if rank <= center {
+		return a.min + (a.centroids[0].mean-a.min)*interpolation(rank-0.5, center-0.5)
+	}
+	for i := 1; i < len(a.centroids); i++ {
+		next := center + (a.centroids[i-1].weight+a.centroids[i].weight)/2
+		if rank <= next {
+			return a.centroids[i-1].mean + (a.centroids[i].mean-a.centroids[i-1].mean)*(rank-center)/(next-center)
+		}
+		center = next
+	}
+	last := a.centroids[len(a.centroids)-1].mean
+	return last + (a.max-last)*interpolation(rank-center, a.total-0.5-center)
+}
+
+// interpolation returns the fraction of the distance covered, or 0 for an empty distance.
+func interpolation(covered, distance float64) float64 {
+	if distance <= 0 {
+		return 0
+	}
+	return covered / distance
+}
+
+func (a *tdigestAggregator) resultType() reflect.Type {
+	return float64Type
+}
+
+func (a *tdigestAggregator) partial() (*structpb.Value, error) {
+	a.compress()
+	list := make([]*structpb.Value, 0, 2*len(a.centroids))
+	for _, c := range a.centroids {
+		list = append(list, structpb.NewNumberValue(c.mean), structpb.NewNumberValue(c.weight))
+	}
+	return newState(map[string]*structpb.Value{
+		"compression": structpb.NewNumberValue(a.compression),
+		"min":         structpb.NewNumberValue(a.min),
+		"max":         structpb.NewNumberValue(a.max),
+		"centroids":   structpb.NewListValue(&structpb.ListValue{Values: list}),
+	}), nil
+}
+
+// merge adds the centroids of another digest. Both digests must have the same compression.
+func (a *tdigestAggregator) merge(state *structpb.Value) error {
+	fields := state.GetStructValue().GetFields()
+	if fields["compression"].GetNumberValue() != a.compression {
+		return errors.New("Cannot merge approx_percentile sketches of different error bounds")
+	}
+	list := fields["centroids"].GetListValue().GetValues()
+	if len(list)%2 != 0 {
+		return errors.New("Invalid approx_percentile sketch")
+	}
+	for i := 0; i < len(list); i += 2 {
+		a.addCentroid(centroid{mean: list[i].GetNumberValue(), weight: list[i+1].GetNumberValue()})
+	}
+	if len(list) > 0 {
+		a.min = math.Min(a.min, fields["min"].GetNumberValue())
+		a.max = math.Max(a.max, fields["max"].GetNumberValue())
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Sort.go orders objects and aggregate rows by the keys of the query's sort-by clause.
+package interpreter
+
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"github.com/saichler/l8reflect/go/reflect/properties"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// sortKey is a sort-by key resolved against the root type, or against the columns
+// of the aggregate rows for aggregate queries.
+type sortKey struct {
+	key        *parser.SortKey      // The parsed key with its direction and nulls order
+	property   *properties.Property // The accessor of the key's property, for objects
+	column     string               // The key's column, for aggregate rows
+	arithmetic *Arithmetic          // The expression of a computed key
+}
+
+// value returns the sort value of an object or an aggregate row.
+func (this *sortKey) value(item interface{}) (interface{}, error) {
+	if this.arithmetic != nil {
+		return this.arithmetic.Evaluate(item)
+	}
+	if this.property != nil {
+		return this.property.Get(item)
+	}
+	row, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Column " + this.column + " requires a result row")
+	}
+	return row[this.column], nil
+}
+
+// newSortKey resolves a sort key of objects to a property of the root type, or to the
+// expression of a computed key such as lower(name).
+func (this *Query) newSortKey(key *parser.SortKey, resources ifs.IResources) (*sortKey, error) {
+	if expr, ok, _ := parser.ParseArithmetic(key.Property); ok {
+		arithmetic, err := newArithmetic(expr, newExpressionScope(this.rootType, resources, this.registry))
+		if err != nil {
+			return nil, err
+		}
+		return &sortKey{key: key, arithmetic: arithmetic}, nil
+	}
+	prop, err := properties.PropertyOf(propertyPath(key.Property, this.rootType.TypeName), resources)
+	if err != nil {
+		return nil, err
+	}
+	return &sortKey{key: key, property: prop}, nil
+}
+
+// SortKeys returns the parsed sort-by keys, in order of precedence.
+func (this *Query) SortKeys() []*parser.SortKey {
+	keys := make([]*parser.SortKey, 0, len(this.sortKeys))
+	for _, key := range this.sortKeys {
+		keys = append(keys, key.key)
+	}
+	return keys
+}
+
+// sortObjects returns the first top objects in sort order, or all of them if top is
+// not positive. Objects that are equal in all keys keep their original order.
+func (this *Query) sortObjects(list []interface{}, top int) ([]interface{}, error) {
+	order, err := this.sortOrder(len(list), func(i int) interface{} { return list[i] }, top, nil)
+	if err != nil || order == nil {
+		return list, err
+	}
+	sorted := make([]interface{}, len(order))
+	for i, index := range order {
+		sorted[i] = list[index]
+	}
+	return sorted, nil
+}
+
+// sortRows returns the first top aggregate rows in sort order, or all of them if top
+// is not positive. Rows that are equal in all keys, and all rows when there is no
+// sort-by, are ordered by their group-by values, so the order does not depend on the
+// order of the objects.
+func (this *Query) sortRows(rows []map[string]interface{}, top int) ([]map[string]interface{}, error) {
+	tie := func(i, j int) int { return this.compareGroupRows(rows[i], rows[j]) }
+	order, err := this.sortOrder(len(rows), func(i int) interface{} { return rows[i] }, top, tie)
+	if err != nil || order == nil {
+		return rows, err
+	}
+	sorted := make([]map[string]interface{}, len(order))
+	for i, index := range order {
+		sorted[i] = rows[index]
+	}
+	return sorted, nil
+}
+
+// sortOrder returns the indexes of the first top items in sort order, or of all items
+// if top is not positive, or nil if there is nothing to sort. Items that are equal in
+// all keys are ordered by tie, if not nil, and then by their index. When only the first
+// items are requested, they are selected with a bounded heap instead of sorting all items.
+// Returns an *EvaluationError if a sort value cannot be read and the query's error
+// policy is AbortOnError; otherwise the value sorts as nil.
+func (this *Query) sortOrder(size int, item func(int) interface{}, top int, tie func(i, j int) int) ([]int, error) {
+	if len(this.sortKeys) == 0 && tie == nil {
+		if top > 0 && top < size {
+			order := make([]int, top)
+			for i := range order {
+				order[i] = i
+			}
+			return order, nil
+		}
+		return nil, nil
+	}
+	values := make([][]interface{}, size)
+	for i := 0; i < size; i++ {
+		values[i] = make([]interface{}, len(this.sortKeys))
+		for k, key := range this.sortKeys {
+			v, err := key.value(item(i))
+			if err != nil {
+				evalErr := &EvaluationError{Property: key.key.Property, Operator: parser.SortBy,
+					LeftKind: reflect.ValueOf(item(i)).Kind(), Message: "Cannot get sort value", Err: err}
+				if this.errorPolicy == AbortOnError {
+					return nil, evalErr
+				}
+				this.resources.Logger().Error(evalErr)
+				v = nil
+			}
+			values[i][k] = v
+		}
+	}
+	// Remaining ties are broken by the original index, which keeps the sort stable.
+	less := func(i, j int) bool {
+		c := this.compareKeys(values[i], values[j])
+		if c == 0 && tie != nil {
+			c = tie(i, j)
+		}
+		return c < 0 || c == 0 && i < j
+	}
+	if top <= 0 || top >= size {
+		order := make([]int, size)
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return less(order[i], order[j]) })
+		return order, nil
+	}
+	h := &topHeap{less: less}
+	for i := 0; i < size; i++ {
+		if h.Len() < top {
+			heap.Push(h, i)
+		} else if less(i, h.indexes[0]) {
+			h.indexes[0] = i
+			heap.Fix(h, 0)
+		}
+	}
+	order := h.indexes
+	sort.Slice(order, func(i, j int) bool { return less(order[i], order[j]) })
+	return order, nil
+}
+
+// topHeap is a heap of item indexes whose root is the last item in sort order,
+// so it can hold the first items seen so far and evict the last one.
+type topHeap struct {
+	indexes []int
+	less    func(i, j int) bool
+}
+
+func (this *topHeap) Len() int           { return len(this.indexes) }
+func (this *topHeap) Less(i, j int) bool { return this.less(this.indexes[j], this.indexes[i]) }
+func (this *topHeap) Swap(i, j int) {
+	this.indexes[i], this.indexes[j] = this.indexes[j], this.indexes[i]
+}
+func (this *topHeap) Push(x interface{}) { this.indexes = append(this.indexes, x.(int)) }
+func (this *topHeap) Pop() interface{} {
+	last := this.indexes[len(this.indexes)-1]
+	this.indexes = this.indexes[:len(this.indexes)-1]
+	return last
+}
+
+// compareKeys orders two lists of sort values by the sort keys, applying the
+// direction and nulls order of each key.
+func (this *Query) compareKeys(a, b []interface{}) int {
+	return compareSortValues(this.sortKeys, a, b)
+}
+
+// compareSortValues orders two lists of sort values by the given keys, applying the
+// direction and nulls order of each key.
+func compareSortValues(keys []*sortKey, a, b []interface{}) int {
+	for k, key := range keys {
+		aNil := isNilValue(reflect.ValueOf(a[k]))
+		bNil := isNilValue(reflect.ValueOf(b[k]))
+		if aNil || bNil {
+			if aNil == bNil {
+				continue
+			}
+			if aNil == key.key.NullsFirst {
+				return -1
+			}
+			return 1
+		}
+		c := compareValues(a[k], b[k])
+		if c != 0 {
+			if key.key.Descending {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders two values, returning -1, 0 or 1. Nil sorts before any other
+// value, numbers and enums are compared numerically, strings lexically, timestamps
+// chronologically and false sorts before true. Values of other or different kinds are
+// ordered by their string representation.
+func compareValues(a, b interface{}) int {
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	aNil := isNilValue(va)
+	bNil := isNilValue(vb)
+	if aNil || bNil {
+		return compareBool(!aNil, !bNil)
+	}
+	aTime, aok := timeOf(a)
+	bTime, bok := timeOf(b)
+	if aok && bok {
+		return aTime.Compare(bTime)
+	}
+	if va.Kind() == reflect.Ptr {
+		va = va.Elem()
+	}
+	if vb.Kind() == reflect.Ptr {
+		vb = vb.Elem()
+	}
+	switch {
+	case isIntValue(va) && isIntValue(vb):
+		return cmp.Compare(va.Int(), vb.Int())
+	case isUintValue(va) && isUintValue(vb):
+		return cmp.Compare(va.Uint(), vb.Uint())
+	case isNumberValue(va) && isNumberValue(vb):
+		return cmp.Compare(floatOf(va), floatOf(vb))
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String())
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return compareBool(va.Bool(), vb.Bool())
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// compareBool compares two booleans, with false before true.
+func compareBool(a, b bool) int {
+	if a == b {
+		return 0
+	}
+	if !a {
+		return -1
+	}
+	return 1
+}
+
+// isNilValue reports whether the value is nil or a nil pointer, map, slice or interface.
+func isNilValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isIntValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isFloatValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func isNumberValue(v reflect.Value) bool {
+	return isIntValue(v) || isUintValue(v) || isFloatValue(v)
+}
+
+// floatOf returns the value of a number as a float64.
+func floatOf(v reflect.Value) float64 {
+	switch {
+	case isIntValue(v):
+		return float64(v.Int())
+	case isUintValue(v):
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// timeOf returns the time of a time.Time value or of a timestamp message
+// such as timestamppb.Timestamp.
+func timeOf(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		return *t, true
+	case interface{ AsTime() time.Time }:
+		return t.AsTime(), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,318 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Window.go computes the window functions of the SELECT clause over the sorted objects
+// that match a query: row numbers and ranks, the values of preceding and following objects,
+// and running or moving aggregates, each within the partitions of the window.
+package interpreter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	"github.com/saichler/l8reflect/go/reflect/properties"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// window is a window function resolved against the root type.
+type window struct {
+	fn        *parser.WindowFunction // The parsed window function
+	column    string                 // The canonical text of the function
+	field     *properties.Property   // The accessor of the function's field, nil if it has none or is "*"
+	partition []*properties.Property // The accessors of the partition properties
+	sortKeys  []*sortKey             // The resolved sort keys of the window
+}
+
+// newWindow resolves the properties of a window function against the root type.
+func (this *Query) newWindow(fn *parser.WindowFunction, resources ifs.IResources) (*window, error) {
+	w := &window{fn: fn, column: fn.String()}
+	resolve := func(name string) (*properties.Property, error) {
+		prop, err := properties.PropertyOf(propertyPath(name, this.rootType.TypeName), resources)
+		if err != nil {
+			return nil, this.resources.Logger().Error("cannot find property for window column ", w.column, ":", err.Error())
+		}
+		return prop, nil
+	}
+	var err error
+	if fn.Field != "" && fn.Field != "*" {
+		if w.field, err = resolve(fn.Field); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range fn.PartitionBy {
+		prop, err := resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		w.partition = append(w.partition, prop)
+	}
+	for _, key := range fn.SortBy {
+		key, err := this.newSortKey(key, resources)
+		if err != nil {
+			return nil, err
+		}
+		w.sortKeys = append(w.sortKeys, key)
+	}
+	return w, nil
+}
+
+// Windows returns the window functions of the SELECT clause, in order.
+func (this *Query) Windows() []*parser.WindowFunction {
+	windows := make([]*parser.WindowFunction, 0, len(this.windows))
+	for _, w := range this.windows {
+		windows = append(windows, w.fn)
+	}
+	return windows
+}
+
+// windowRows computes the window functions over the sorted objects and returns, for each
+// object, a row holding the value of each window function under its alias.
+// Values that cannot be read, and aggregates that fail, are handled according to the
+// query's error policy; when skipped, they are nil.
+func (this *Query) windowRows(list []interface{}) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, len(list))
+	for i := range rows {
+		rows[i] = make(map[string]interface{}, len(this.windows))
+	}
+	for _, w := range this.windows {
+		partitions, err := this.windowPartitions(w, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, partition := range partitions {
+			order, sortValues, err := this.windowOrder(w, list, partition)
+			if err != nil {
+				return nil, err
+			}
+			values, err := this.windowValues(w, list, order, sortValues)
+			if err != nil {
+				return nil, err
+			}
+			for k, index := range order {
+				rows[index][w.fn.Alias] = values[k]
+			}
+		}
+	}
+	return rows, nil
+}
+
+// windowValue reads a property of an object for a window function. A failure is handled
+// according to the query's error policy, see windowValueError.
+func (this *Query) windowValue(w *window, prop *properties.Property, item interface{}) (interface{}, error) {
+	v, err := prop.Get(item)
+	if err == nil {
+		return v, nil
+	}
+	pid, _ := prop.PropertyId()
+	return nil, this.windowValueError(w, pid, item, err)
+}
+
+// windowValueError handles the failure to read a value of an object for a window function:
+// the error is returned with AbortOnError, and otherwise logged and the value is nil.
+func (this *Query) windowValueError(w *window, property string, item interface{}, err error) error {
+	evalErr := &EvaluationError{Property: property, Operator: w.column,
+		LeftKind: reflect.ValueOf(item).Kind(), Message: "Cannot get window value", Err: err}
+	if this.errorPolicy == AbortOnError {
+		return evalErr
+	}
+	this.resources.Logger().Error(evalErr)
+	return nil
+}
+
+// windowPartitions returns the indexes of the objects of each partition of the window, in
+// the order of the list. Partitions are returned in the order they are first seen.
+func (this *Query) windowPartitions(w *window, list []interface{}) ([][]int, error) {
+	if len(w.partition) == 0 {
+		all := make([]int, len(list))
+		for i := range all {
+			all[i] = i
+		}
+		return [][]int{all}, nil
+	}
+	partitions := make(map[string]int)
+	result := make([][]int, 0)
+	for i, item := range list {
+		keys := make([]string, 0, len(w.partition))
+		for _, prop := range w.partition {
+			v, err := this.windowValue(w, prop, item)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, groupValueKey(v))
+		}
+		key := strings.Join(keys, "|")
+		index, ok := partitions[key]
+		if !ok {
+			index = len(result)
+			partitions[key] = index
+			result = append(result, nil)
+		}
+		result[index] = append(result[index], i)
+	}
+	return result, nil
+}
+
+// windowOrder sorts the indexes of a partition by the sort keys of the window, keeping the
+// order of the list for equal objects, and returns them with the sort values of each.
+func (this *Query) windowOrder(w *window, list []interface{}, partition []int) ([]int, [][]interface{}, error) {
+	values := make(map[int][]interface{}, len(partition))
+	for _, index := range partition {
+		values[index] = make([]interface{}, len(w.sortKeys))
+		for k, key := range w.sortKeys {
+			v, err := key.value(list[index])
+			if err != nil {
+				if err = this.windowValueError(w, key.key.Property, list[index], err); err != nil {
+					return nil, nil, err
+				}
+				v = nil
+			}
+			values[index][k] = v
+		}
+	}
+	order := append([]int{}, partition...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return compareSortValues(w.sortKeys, values[order[i]], values[order[j]]) < 0
+	})
+	sortValues := make([][]interface{}, len(order))
+	for k, index := range order {
+		sortValues[k] = values[index]
+	}
+	return order, sortValues, nil
+}
+
+// windowValues computes the window function for each object of a sorted partition.
+func (this *Query) windowValues(w *window, list []interface{}, order []int, sortValues [][]interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(order))
+	switch w.fn.Function {
+	case "row_number":
+		for k := range order {
+			values[k] = int64(k + 1)
+		}
+	case "rank", "dense_rank":
+		rank := int64(0)
+		for k := range order {
+			if k == 0 || compareSortValues(w.sortKeys, sortValues[k-1], sortValues[k]) != 0 {
+				if w.fn.Function == "rank" {
+					rank = int64(k + 1)
+				} else {
+					rank++
+				}
+			}
+			values[k] = rank
+		}
+	case "lag", "lead":
+		offset := -w.fn.Offset
+		if w.fn.Function == "lead" {
+			offset = w.fn.Offset
+		}
+		for k := range order {
+			if k+offset < 0 || k+offset >= len(order) {
+				continue
+			}
+			v, err := this.windowValue(w, w.field, list[order[k+offset]])
+			if err != nil {
+				return nil, err
+			}
+			values[k] = v
+		}
+	default:
+		return this.windowAggregates(w, list, order, values)
+	}
+	return values, nil
+}
+
+// windowAggregates computes an aggregate window function for each object of a sorted
+// partition, over the frame of the object: the object and the given number of preceding
+// objects for a moving frame, the objects up to the object when the window is sorted,
+// and the whole partition otherwise.
+func (this *Query) windowAggregates(w *window, list []interface{}, order []int, values []interface{}) ([]interface{}, error) {
+	fieldValues := make([]interface{}, len(order))
+	if w.field != nil {
+		for k, index := range order {
+			v, err := this.windowValue(w, w.field, list[index])
+			if err != nil {
+				return nil, err
+			}
+			fieldValues[k] = v
+		}
+	}
+	reported := false
+	result := func(acc *Accumulator) (interface{}, error) {
+		if acc.Err() != nil && !reported {
+			reported = true
+			if err := this.windowError(w, acc); err != nil {
+				return nil, err
+			}
+		}
+		return acc.Result(), nil
+	}
+	if w.fn.Preceding >= 0 {
+		for k := range order {
+			acc := NewAccumulator(w.fn.Function)
+			start := k - w.fn.Preceding
+			if start < 0 {
+				start = 0
+			}
+			for _, v := range fieldValues[start : k+1] {
+				acc.Add(v)
+			}
+			v, err := result(acc)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = v
+		}
+		return values, nil
+	}
+	acc := NewAccumulator(w.fn.Function)
+	for k, v := range fieldValues {
+		acc.Add(v)
+		if len(w.sortKeys) > 0 {
+			r, err := result(acc)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = r
+		}
+	}
+	if len(w.sortKeys) == 0 {
+		total, err := result(acc)
+		if err != nil {
+			return nil, err
+		}
+		for k := range values {
+			values[k] = total
+		}
+	}
+	return values, nil
+}
+
+// windowError handles the failure of the accumulator of an aggregate window function
+// according to the query's error policy, returning the error to abort with or nil if
+// the failure was logged.
+func (this *Query) windowError(w *window, acc *Accumulator) error {
+	err := acc.Err()
+	if ee, ok := err.(*EvaluationError); ok && ee.Property == "" {
+		ee.Property = w.fn.Field
+		ee.Operator = w.column
+	}
+	if this.errorPolicy == AbortOnError {
+		return err
+	}
+	this.resources.Logger().Error(err)
+	return nil
+}
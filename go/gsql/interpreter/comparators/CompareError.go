@@ -0,0 +1,83 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package comparators
+
+import (
+	"reflect"
+)
+
+// CompareError describes operands that a comparator cannot compare,
+// such as a kind with no compare function or operands of incompatible kinds.
+type CompareError struct {
+	Comparator string       // The name of the comparator (e.g. "Equal")
+	LeftKind   reflect.Kind // The kind of the left operand (reflect.Invalid for nil)
+	RightKind  reflect.Kind // The kind of the right operand (reflect.Invalid for nil)
+	Message    string       // Description of the problem
+}
+
+// newCompareError creates a CompareError for the given operands.
+func newCompareError(name string, left, right interface{}, message string) *CompareError {
+	return &CompareError{Comparator: name, LeftKind: reflect.ValueOf(left).Kind(),
+		RightKind: reflect.ValueOf(right).Kind(), Message: message}
+}
+
+// Error returns the message with the comparator name and the operand kinds.
+func (this *CompareError) Error() string {
+	return this.Comparator + ": " + this.Message + " (left " + this.LeftKind.String() + ", right " + this.RightKind.String() + ")"
+}
+
+// isComparable reports whether a value can be handed to the compare function
+// registered for the given kind. Nil values and strings are accepted by every compare
+// function, numeric values by the numeric compare functions, and slices when all of
+// their elements are accepted.
+func isComparable(v interface{}, kind reflect.Kind) bool {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Invalid, reflect.String:
+		return true
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if !isComparable(value.Index(i).Interface(), kind) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr:
+		return kind == reflect.Ptr
+	case reflect.Bool:
+		return kind == reflect.Bool
+	}
+	return isNumericKind(value.Kind()) && isNumericKind(kind)
+}
+
+// isFloatKind reports whether the kind is a floating point kind.
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// isNumericKind reports whether the kind is a signed integer, unsigned integer or floating point kind.
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -15,10 +15,10 @@ limitations under the License.
 
 // Package comparators provides implementations of comparison operators for the L8QL query interpreter.
 // Each comparator implements the Comparable interface and provides type-aware comparison logic
-// for strings, integers (signed and unsigned), and pointers.
+// for strings, integers (signed and unsigned), floating point numbers, and pointers.
 //
 // Supported comparators:
-//   - Equal (=): Checks if values are equal, with wildcard support for strings
+//   - Equal (=): Checks if values are equal, with wildcard support for strings unless strict
 //   - NotEqual (!=): Checks if values are not equal
 //   - GreaterThan (>): Checks if left value is greater than right
 //   - GreaterThanOrEqual (>=): Checks if left value is greater than or equal to right
@@ -26,16 +26,19 @@ limitations under the License.
 //   - LessThanOrEqual (<=): Checks if left value is less than or equal to right
 //   - IN: Checks if left value is in a list of values
 //   - NotIN: Checks if left value is not in a list of values
+//   - Like (like, ilike, glob): Checks if left string matches an anchored pattern
 package comparators
 
 import (
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
 )
 
 // Equal implements the equality (=) comparison operator.
-// It supports string comparison with wildcard patterns (*), integer, and unsigned integer types.
+// It supports string comparison with wildcard patterns (*), integer, unsigned integer, and floating point types.
+// Floating point values are equal when they differ by no more than FloatEpsilon.
 type Equal struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -55,55 +58,98 @@ func NewEqual() *Equal {
 	c.compares[reflect.Uint16] = eqUintMatcher
 	c.compares[reflect.Uint32] = eqUintMatcher
 	c.compares[reflect.Uint64] = eqUintMatcher
+	c.compares[reflect.Float32] = eqFloatMatcher
+	c.compares[reflect.Float64] = eqFloatMatcher
 	c.compares[reflect.Ptr] = eqPtrMatcher
 	c.compares[reflect.Bool] = eqBoolMatcher
 	return c
 }
 
 // Compare evaluates equality between left and right values.
-func (equal *Equal) Compare(left, right interface{}) bool {
+func (equal *Equal) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, equal.compares, "Equal")
 }
 
 // Compare is a helper function that dispatches to the appropriate type-specific
 // comparison function based on the kind of the operands.
-func Compare(left, right interface{}, compares map[reflect.Kind]func(interface{}, interface{}) bool, name string) bool {
+// Returns a *CompareError if there is no comparison function for the kind of the
+// operands, or if an operand cannot be compared by that function.
+func Compare(left, right interface{}, compares map[reflect.Kind]func(interface{}, interface{}) bool, name string) (bool, error) {
 	kind := getKind(left, right)
 	compareFunc := compares[kind]
 	if compareFunc == nil {
-		panic("Cannot find compare func for:" + name + " Kind:" + kind.String())
+		return false, newCompareError(name, left, right, "Cannot find compare func for kind "+kind.String())
 	}
-	return compareFunc(left, right)
+	if !isComparable(left, kind) || !isComparable(right, kind) {
+		return false, newCompareError(name, left, right, "Cannot compare operands as "+kind.String())
+	}
+	return compareFunc(left, right), nil
 }
 
 // removeSingleQuote strips surrounding single quotes from a string value.
 func removeSingleQuote(value string) string {
-	if strings.Contains(value, "'") {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
 		return value[1 : len(value)-1]
 	}
 	return value
 }
 
+// getString returns the string of a string value. A nil value is the empty string.
+func getString(v interface{}) (string, bool) {
+	if v == nil {
+		return "", true
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// NewStrictEqual creates an Equal comparator that compares strings exactly, so a '*'
+// in a value is an ordinary character. Patterns are matched by Like instead.
+func NewStrictEqual() *Equal {
+	c := NewEqual()
+	c.compares[reflect.String] = strictEqStringMatcher
+	return c
+}
+
 // eqStringMatcher compares two string values for equality.
 // Supports wildcard patterns (*), nil comparisons, and slice matching.
 func eqStringMatcher(left, right interface{}) bool {
+	return eqStrings(left, right, true)
+}
+
+// strictEqStringMatcher compares two string values for exact equality.
+// Supports nil comparisons and slice matching.
+func strictEqStringMatcher(left, right interface{}) bool {
+	return eqStrings(left, right, false)
+}
+
+// eqStrings compares two string values for equality, with wildcard patterns if wildcard is true.
+func eqStrings(left, right interface{}, wildcard bool) bool {
 	vLeft := reflect.ValueOf(left)
 	if vLeft.Kind() == reflect.Slice {
 		for i := 0; i < vLeft.Len(); i++ {
-			if eqStringMatcher(vLeft.Index(i).Interface(), right) {
+			if eqStrings(vLeft.Index(i).Interface(), right, wildcard) {
 				return true
 			}
 		}
 		return false
 	}
-	aside := removeSingleQuote(left.(string))
-	zside := removeSingleQuote(right.(string))
+	aside, aok := getString(left)
+	zside, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(aside)
+	zside = removeSingleQuote(zside)
 	if aside == "nil" && zside == "" {
 		return true
 	}
 	if zside == "nil" && aside == "" {
 		return true
 	}
+	if !wildcard {
+		return aside == zside
+	}
 	if aside == "*" || zside == "*" {
 		return true
 	}
@@ -121,15 +167,21 @@ func eqStringMatcher(left, right interface{}) bool {
 
 // eqPtrMatcher compares pointer values, handling nil comparisons.
 func eqPtrMatcher(left, right interface{}) bool {
-	if left == nil && right.(string) == "nil" {
+	if isNil(left) && right == "nil" {
 		return true
 	}
-	if right == nil && left.(string) == "nil" {
+	if isNil(right) && left == "nil" {
 		return true
 	}
 	return false
 }
 
+// isNil reports whether the value is nil or a nil pointer.
+func isNil(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	return !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil())
+}
+
 // eqIntMatcher compares signed integer values for equality.
 func eqIntMatcher(left, right interface{}) bool {
 	aside, aok := getInt64(left)
@@ -152,6 +204,28 @@ func eqIntMatcher(left, right interface{}) bool {
 	return aside == zside
 }
 
+// eqFloatMatcher compares floating point values for equality within FloatEpsilon.
+func eqFloatMatcher(left, right interface{}) bool {
+	aside, aok := getFloat64(left)
+	zside, zok := getFloat64(right)
+
+	rightValue, ok := right.(string)
+	if ok && rightValue == "nil" && aok && aside == 0 {
+		return true
+	}
+
+	leftValue, ok := left.(string)
+	if ok && leftValue == "nil" && zok && zside == 0 {
+		return true
+	}
+
+	if !aok || !zok {
+		return false
+	}
+
+	return floatsEqual(aside, zside, floatBits(left, right))
+}
+
 // eqUintMatcher compares unsigned integer values for equality.
 func eqUintMatcher(left, right interface{}) bool {
 	aside, ok := getUint64(left)
@@ -167,6 +241,8 @@ func eqUintMatcher(left, right interface{}) bool {
 
 // getKind determines the appropriate reflect.Kind to use for comparison.
 // It handles slices by examining the element type and prioritizes non-string kinds.
+// When an integer is compared with a floating point value, or with a literal number that
+// has a fraction, the floating point kind is used.
 func getKind(aside, zside interface{}) reflect.Kind {
 	aSideKind := reflect.String
 	zSideKind := reflect.String
@@ -200,6 +276,12 @@ func getKind(aside, zside interface{}) reflect.Kind {
 		}
 	}
 
+	if isFloatKind(zSideKind) && isNumericKind(aSideKind) && !isFloatKind(aSideKind) {
+		return zSideKind
+	}
+	if isIntegerKind(aSideKind) && isFractional(zside) || isIntegerKind(zSideKind) && isFractional(aside) {
+		return reflect.Float64
+	}
 	if aSideKind != reflect.String {
 		return aSideKind
 	} else if zSideKind != reflect.String {
@@ -208,34 +290,157 @@ func getKind(aside, zside interface{}) reflect.Kind {
 	return aSideKind
 }
 
+// isIntegerKind reports whether the kind is a signed or unsigned integer kind.
+func isIntegerKind(kind reflect.Kind) bool {
+	return isNumericKind(kind) && !isFloatKind(kind)
+}
+
+// isFractional reports whether the value is a string holding a number with a fraction,
+// such as the literal 0.5, which an integer can only be compared with as a float.
+func isFractional(v interface{}) bool {
+	str, ok := v.(string)
+	if !ok {
+		return false
+	}
+	f, e := strconv.ParseFloat(removeSingleQuote(str), 64)
+	return e == nil && !math.IsInf(f, 0) && f != math.Trunc(f)
+}
+
 // getInt64 converts an interface value to int64.
-// Handles both numeric types and string representations of integers.
+// Handles integer types and string representations of integers,
+// including whole numbers in scientific notation (e.g. 1e3).
 func getInt64(v interface{}) (int64, bool) {
 	value := reflect.ValueOf(v)
-	if value.Kind() != reflect.String {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return value.Int(), true
-	} else {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value.Uint()), true
+	case reflect.String:
 		i, e := strconv.Atoi(value.String())
 		if e != nil {
-			return 0, false
+			f, ok := getWholeFloat(value.String())
+			if !ok || f < math.MinInt64 || f >= math.MaxInt64 {
+				return 0, false
+			}
+			return int64(f), true
 		}
 		return int64(i), true
 	}
+	return 0, false
 }
 
 // getUint64 converts an interface value to uint64.
-// Handles both numeric types and string representations of integers.
+// Handles integer types and string representations of integers,
+// including whole numbers in scientific notation (e.g. 1e3).
 func getUint64(v interface{}) (uint64, bool) {
 	value := reflect.ValueOf(v)
-	if value.Kind() != reflect.String {
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		return value.Uint(), true
-	} else {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() < 0 {
+			return 0, false
+		}
+		return uint64(value.Int()), true
+	case reflect.String:
 		i, e := strconv.Atoi(value.String())
 		if e != nil {
-			return 0, false
+			f, ok := getWholeFloat(value.String())
+			if !ok || f < 0 || f >= math.MaxUint64 {
+				return 0, false
+			}
+			return uint64(f), true
 		}
 		return uint64(i), true
 	}
+	return 0, false
+}
+
+// getWholeFloat parses a string as a floating point number that has no fraction.
+func getWholeFloat(str string) (float64, bool) {
+	f, e := strconv.ParseFloat(str, 64)
+	if e != nil || f != math.Trunc(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// FloatEpsilon is the relative tolerance used when comparing floating point values.
+// Two values are equal if they differ by no more than FloatEpsilon times the larger
+// magnitude, or by no more than FloatEpsilon when both are close to zero.
+var FloatEpsilon = 1e-9
+
+// getFloat64 converts an interface value to float64.
+// Handles numeric types and string representations of numbers, including
+// scientific notation (e.g. 1.5e-3, 2E+6).
+func getFloat64(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.String:
+		f, e := strconv.ParseFloat(removeSingleQuote(value.String()), 64)
+		if e != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// floatBits returns 32 if either value is a float32, so that comparisons are made
+// at the precision of the property rather than at the precision of the literal.
+func floatBits(left, right interface{}) int {
+	if reflect.ValueOf(left).Kind() == reflect.Float32 || reflect.ValueOf(right).Kind() == reflect.Float32 {
+		return 32
+	}
+	return 64
+}
+
+// floatsEqual reports whether two floating point values are equal within FloatEpsilon.
+// NaN is not equal to any value.
+func floatsEqual(aside, zside float64, bits int) bool {
+	if bits == 32 {
+		aside = float64(float32(aside))
+		zside = float64(float32(zside))
+	}
+	if aside == zside {
+		return true
+	}
+	if math.IsNaN(aside) || math.IsNaN(zside) || math.IsInf(aside, 0) || math.IsInf(zside, 0) {
+		return false
+	}
+	diff := math.Abs(aside - zside)
+	return diff <= FloatEpsilon || diff <= FloatEpsilon*math.Max(math.Abs(aside), math.Abs(zside))
+}
+
+// compareFloat compares two floating point values, returning -1, 0 or 1 when left
+// is less than, equal to (within FloatEpsilon) or greater than right.
+// Returns false if either value is not a number.
+func compareFloat(left, right interface{}) (int, bool) {
+	aside, ok := getFloat64(left)
+	if !ok || math.IsNaN(aside) {
+		return 0, false
+	}
+	zside, ok := getFloat64(right)
+	if !ok || math.IsNaN(zside) {
+		return 0, false
+	}
+	if floatsEqual(aside, zside, floatBits(left, right)) {
+		return 0, true
+	}
+	if aside < zside {
+		return -1, true
+	}
+	return 1, true
 }
 
 // GetWildCardSubstrings splits a string by wildcard (*) characters
@@ -270,13 +475,15 @@ func eqBoolMatcher(left, right interface{}) bool {
 
 func getBool(v interface{}) (bool, bool) {
 	value := reflect.ValueOf(v)
-	if value.Kind() != reflect.String {
+	switch value.Kind() {
+	case reflect.Bool:
 		return value.Bool(), true
-	} else {
+	case reflect.String:
 		i, e := strconv.ParseBool(value.String())
 		if e != nil {
 			return false, false
 		}
 		return i, true
 	}
+	return false, false
 }
@@ -20,7 +20,7 @@ import (
 )
 
 // GreaterThan implements the greater-than (>) comparison operator.
-// It supports string, signed integer, and unsigned integer types.
+// It supports string, signed integer, unsigned integer, and floating point types.
 type GreaterThan struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -40,18 +40,25 @@ func NewGreaterThan() *GreaterThan {
 	c.compares[reflect.Uint16] = gtUintMatcher
 	c.compares[reflect.Uint32] = gtUintMatcher
 	c.compares[reflect.Uint64] = gtUintMatcher
+	c.compares[reflect.Float32] = gtFloatMatcher
+	c.compares[reflect.Float64] = gtFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is greater than right.
-func (gt *GreaterThan) Compare(left, right interface{}) bool {
+func (gt *GreaterThan) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, gt.compares, "Greater Than")
 }
 
 // gtStringMatcher compares two string values lexicographically.
 func gtStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zside := removeSingleQuote(strings.ToLower(right.(string)))
+	aside, aok := getString(left)
+	zside, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zside = removeSingleQuote(strings.ToLower(zside))
 	return aside > zside
 }
 
@@ -80,3 +87,13 @@ func gtUintMatcher(left, right interface{}) bool {
 	}
 	return aside > zside
 }
+
+// gtFloatMatcher compares floating point values. Values within FloatEpsilon
+// of each other are considered equal.
+func gtFloatMatcher(left, right interface{}) bool {
+	result, ok := compareFloat(left, right)
+	if !ok {
+		return false
+	}
+	return result > 0
+}
@@ -20,7 +20,7 @@ import (
 )
 
 // GreaterThanOrEqual implements the greater-than-or-equal (>=) comparison operator.
-// It supports string, signed integer, and unsigned integer types.
+// It supports string, signed integer, unsigned integer, and floating point types.
 type GreaterThanOrEqual struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -40,18 +40,25 @@ func NewGreaterThanOrEqual() *GreaterThanOrEqual {
 	c.compares[reflect.Uint16] = gteqUintMatcher
 	c.compares[reflect.Uint32] = gteqUintMatcher
 	c.compares[reflect.Uint64] = gteqUintMatcher
+	c.compares[reflect.Float32] = gteqFloatMatcher
+	c.compares[reflect.Float64] = gteqFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is greater than or equal to right.
-func (gteq *GreaterThanOrEqual) Compare(left, right interface{}) bool {
+func (gteq *GreaterThanOrEqual) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, gteq.compares, "Greater Than Or Equal")
 }
 
 // gteqStringMatcher compares two string values lexicographically.
 func gteqStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zside := removeSingleQuote(strings.ToLower(right.(string)))
+	aside, aok := getString(left)
+	zside, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zside = removeSingleQuote(strings.ToLower(zside))
 	return aside >= zside
 }
 
@@ -80,3 +87,13 @@ func gteqUintMatcher(left, right interface{}) bool {
 	}
 	return aside >= zside
 }
+
+// gteqFloatMatcher compares floating point values. Values within FloatEpsilon
+// of each other are considered equal.
+func gteqFloatMatcher(left, right interface{}) bool {
+	result, ok := compareFloat(left, right)
+	if !ok {
+		return false
+	}
+	return result >= 0
+}
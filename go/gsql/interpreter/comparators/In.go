@@ -42,18 +42,25 @@ func NewIN() *IN {
 	c.compares[reflect.Uint16] = inUintMatcher
 	c.compares[reflect.Uint32] = inUintMatcher
 	c.compares[reflect.Uint64] = inUintMatcher
+	c.compares[reflect.Float32] = inFloatMatcher
+	c.compares[reflect.Float64] = inFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is in the list specified by right.
-func (in *IN) Compare(left, right interface{}) bool {
+func (in *IN) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, in.compares, "In")
 }
 
 // inStringMatcher checks if a string value is in a list of strings.
 func inStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zsideList := strings.ToLower(right.(string))
+	aside, aok := getString(left)
+	zsideList, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zsideList = strings.ToLower(zsideList)
 	values := getInStringList(zsideList)
 	for _, v := range values {
 		if aside == v {
@@ -70,19 +77,14 @@ func inIntMatcher(left, right interface{}) bool {
 		return false
 	}
 
-	zsideList := strings.ToLower(right.(string))
-
-	values := getInStringList(zsideList)
-	for _, v := range values {
-		intV, e := strconv.Atoi(v)
-		if e != nil {
-			return false
-		}
-		if aside == int64(intV) {
-			return true
-		}
+	zsideList, ok := getString(right)
+	if !ok {
+		return false
 	}
-	return false
+	zsideList = strings.ToLower(zsideList)
+
+	found, _ := intInList(aside, getInStringList(zsideList))
+	return found
 }
 
 // inUintMatcher checks if an unsigned integer value is in a list of integers.
@@ -92,27 +94,63 @@ func inUintMatcher(left, right interface{}) bool {
 		return false
 	}
 
-	zsideList := strings.ToLower(right.(string))
+	zsideList, ok := getString(right)
+	if !ok {
+		return false
+	}
+	zsideList = strings.ToLower(zsideList)
 
-	values := getInStringList(zsideList)
+	found, _ := uintInList(aside, getInStringList(zsideList))
+	return found
+}
+
+// intInList reports whether the signed integer is one of the values of the list. Values
+// with a fraction are compared as floating point values. The search stops with ok false
+// at the first value that is not a number.
+func intInList(aside int64, values []string) (found, ok bool) {
 	for _, v := range values {
-		intV, e := strconv.Atoi(v)
-		if e != nil {
-			return false
+		v = strings.TrimSpace(v)
+		if intV, e := strconv.ParseInt(v, 10, 64); e == nil {
+			if aside == intV {
+				return true, true
+			}
+		} else if f, isFloat := getFloat64(v); !isFloat {
+			return false, false
+		} else if floatsEqual(float64(aside), f, 64) {
+			return true, true
 		}
-		if aside == uint64(intV) {
-			return true
+	}
+	return false, true
+}
+
+// uintInList reports whether the unsigned integer is one of the values of the list. Values
+// with a fraction are compared as floating point values. The search stops with ok false
+// at the first value that is not a number.
+func uintInList(aside uint64, values []string) (found, ok bool) {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if uintV, e := strconv.ParseUint(v, 10, 64); e == nil {
+			if aside == uintV {
+				return true, true
+			}
+		} else if f, isFloat := getFloat64(v); !isFloat {
+			return false, false
+		} else if floatsEqual(float64(aside), f, 64) {
+			return true, true
 		}
 	}
-	return false
+	return false, true
 }
 
 // getInStringList extracts the list of values from a bracket-enclosed string.
-// E.g., "[a,b,c]" returns ["a", "b", "c"]
+// E.g., "[a,b,c]" returns ["a", "b", "c"]. A string without brackets is a list of one value.
 func getInStringList(str string) []string {
+	lst := str
 	index := strings.Index(str, "[")
-	index2 := strings.Index(str, "]")
-	lst := str[index+1 : index2]
+	index2 := strings.LastIndex(str, "]")
+	if index != -1 && index2 > index {
+		lst = str[index+1 : index2]
+	}
 	values := strings.Split(lst, ",")
 	result := make([]string, 0)
 	for _, v := range values {
@@ -120,3 +158,31 @@ func getInStringList(str string) []string {
 	}
 	return result
 }
+
+// inFloatMatcher checks if a floating point value is in a list of numbers.
+// Values within FloatEpsilon of each other are considered equal.
+func inFloatMatcher(left, right interface{}) bool {
+	aside, ok := getFloat64(left)
+	if !ok {
+		return false
+	}
+
+	zsideList, ok := getString(right)
+	if !ok {
+		return false
+	}
+	zsideList = strings.ToLower(zsideList)
+
+	values := getInStringList(zsideList)
+	bits := floatBits(left, right)
+	for _, v := range values {
+		floatV, e := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if e != nil {
+			return false
+		}
+		if floatsEqual(aside, floatV, bits) {
+			return true
+		}
+	}
+	return false
+}
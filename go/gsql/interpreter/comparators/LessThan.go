@@ -20,7 +20,7 @@ import (
 )
 
 // LessThan implements the less-than (<) comparison operator.
-// It supports string, signed integer, and unsigned integer types.
+// It supports string, signed integer, unsigned integer, and floating point types.
 type LessThan struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -40,18 +40,25 @@ func NewLessThan() *LessThan {
 	c.compares[reflect.Uint16] = ltUintMatcher
 	c.compares[reflect.Uint32] = ltUintMatcher
 	c.compares[reflect.Uint64] = ltUintMatcher
+	c.compares[reflect.Float32] = ltFloatMatcher
+	c.compares[reflect.Float64] = ltFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is less than right.
-func (lt *LessThan) Compare(left, right interface{}) bool {
+func (lt *LessThan) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, lt.compares, "Less Than")
 }
 
 // ltStringMatcher compares two string values lexicographically.
 func ltStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zside := removeSingleQuote(strings.ToLower(right.(string)))
+	aside, aok := getString(left)
+	zside, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zside = removeSingleQuote(strings.ToLower(zside))
 	return aside < zside
 }
 
@@ -80,3 +87,13 @@ func ltUintMatcher(left, right interface{}) bool {
 	}
 	return aside < zside
 }
+
+// ltFloatMatcher compares floating point values. Values within FloatEpsilon
+// of each other are considered equal.
+func ltFloatMatcher(left, right interface{}) bool {
+	result, ok := compareFloat(left, right)
+	if !ok {
+		return false
+	}
+	return result < 0
+}
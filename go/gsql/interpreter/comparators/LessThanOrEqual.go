@@ -20,7 +20,7 @@ import (
 )
 
 // LessThanOrEqual implements the less-than-or-equal (<=) comparison operator.
-// It supports string, signed integer, and unsigned integer types.
+// It supports string, signed integer, unsigned integer, and floating point types.
 type LessThanOrEqual struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -40,18 +40,25 @@ func NewLessThanOrEqual() *LessThanOrEqual {
 	c.compares[reflect.Uint16] = lteqUintMatcher
 	c.compares[reflect.Uint32] = lteqUintMatcher
 	c.compares[reflect.Uint64] = lteqUintMatcher
+	c.compares[reflect.Float32] = lteqFloatMatcher
+	c.compares[reflect.Float64] = lteqFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is less than or equal to right.
-func (lteq *LessThanOrEqual) Compare(left, right interface{}) bool {
+func (lteq *LessThanOrEqual) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, lteq.compares, "Less Than Or Equal")
 }
 
 // lteqStringMatcher compares two string values lexicographically.
 func lteqStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zside := removeSingleQuote(strings.ToLower(right.(string)))
+	aside, aok := getString(left)
+	zside, zok := getString(right)
+	if !aok || !zok {
+		return false
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zside = removeSingleQuote(strings.ToLower(zside))
 	return aside <= zside
 }
 
@@ -80,3 +87,13 @@ func lteqUintMatcher(left, right interface{}) bool {
 	}
 	return aside <= zside
 }
+
+// lteqFloatMatcher compares floating point values. Values within FloatEpsilon
+// of each other are considered equal.
+func lteqFloatMatcher(left, right interface{}) bool {
+	result, ok := compareFloat(left, right)
+	if !ok {
+		return false
+	}
+	return result <= 0
+}
@@ -0,0 +1,173 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package comparators
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// DefaultEscape is the escape character of like and ilike patterns without an escape clause.
+const DefaultEscape = "\\"
+
+// Like implements the like, ilike and glob pattern operators. A pattern matches the
+// whole string: like and ilike patterns use '%' for any sequence of characters and '_'
+// for a single character, and glob patterns use '*' and '?'. The right value is compiled
+// on every comparison; compile a constant pattern once with Compile instead.
+type Like struct {
+	name       string // The name of the operator (e.g. "Like")
+	glob       bool   // Glob syntax (* and ?) rather than like syntax (% and _)
+	ignoreCase bool   // Case-insensitive matching
+	escape     string // The escape character of like and ilike patterns, empty for none
+}
+
+// NewLike creates a case-sensitive like comparator.
+func NewLike() *Like {
+	return &Like{name: "Like", escape: DefaultEscape}
+}
+
+// NewILike creates a case-insensitive like comparator.
+func NewILike() *Like {
+	return &Like{name: "ILike", ignoreCase: true, escape: DefaultEscape}
+}
+
+// NewGlob creates a case-sensitive glob comparator.
+func NewGlob() *Like {
+	return &Like{name: "Glob", glob: true, escape: DefaultEscape}
+}
+
+// WithEscape returns a copy of the comparator that compiles like and ilike patterns with
+// the escape character, or without one if it is empty.
+func (this *Like) WithEscape(escape string) *Like {
+	like := *this
+	like.escape = escape
+	return &like
+}
+
+// Escape returns the escape character of like and ilike patterns, empty for none.
+func (this *Like) Escape() string {
+	return this.escape
+}
+
+// Compare reports whether the left string matches the right string as a pattern,
+// with the escape character of the comparator.
+// A nil value or pattern does not match.
+// Returns a *CompareError if the right value is not a valid pattern or either value
+// is not a string.
+func (this *Like) Compare(left, right interface{}) (bool, error) {
+	if right == nil {
+		return false, nil
+	}
+	text, ok := right.(string)
+	if !ok {
+		return false, newCompareError(this.name, left, right, "Pattern must be a string")
+	}
+	pattern, err := this.Compile(removeSingleQuote(text), this.escape, false)
+	if err != nil {
+		return false, newCompareError(this.name, left, right, err.Error())
+	}
+	return pattern.Compare(left, right)
+}
+
+// Compile compiles a pattern of this operator. Like and ilike patterns use the escape
+// character, which is a single character or empty for none, to match a literal '%', '_'
+// or escape character; glob patterns always use a backslash. An ilike pattern is always
+// case-insensitive, and other patterns if ignoreCase is true.
+// Returns an error for an invalid escape character or escape sequence.
+func (this *Like) Compile(pattern, escape string, ignoreCase bool) (*Pattern, error) {
+	wildcards := "%_"
+	if this.glob {
+		wildcards, escape = "*?", DefaultEscape
+	} else if len([]rune(escape)) > 1 {
+		return nil, &PatternError{Pattern: pattern, Message: "Escape must be a single character, not '" + escape + "'"}
+	}
+	buff := strings.Builder{}
+	if ignoreCase || this.ignoreCase {
+		buff.WriteString("(?i)")
+	}
+	buff.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := string(runes[i])
+		switch {
+		case escape != "" && c == escape:
+			if i+1 == len(runes) {
+				return nil, &PatternError{Pattern: pattern, Message: "Pattern ends with the escape character"}
+			}
+			i++
+			next := string(runes[i])
+			if !this.glob && next != escape && !strings.Contains(wildcards, next) {
+				return nil, &PatternError{Pattern: pattern, Message: "Invalid escape sequence " + c + next}
+			}
+			buff.WriteString(regexp.QuoteMeta(next))
+		case c == wildcards[0:1]:
+			buff.WriteString(".*")
+		case c == wildcards[1:2]:
+			buff.WriteString(".")
+		default:
+			buff.WriteString(regexp.QuoteMeta(c))
+		}
+	}
+	buff.WriteString("$")
+	re, err := regexp.Compile(buff.String())
+	if err != nil {
+		return nil, &PatternError{Pattern: pattern, Message: err.Error()}
+	}
+	return &Pattern{name: this.name, text: pattern, re: re}, nil
+}
+
+// Pattern is a compiled like, ilike or glob pattern. It is a comparator that matches
+// the left value against the pattern and ignores the right value.
+type Pattern struct {
+	name string         // The name of the operator that compiled the pattern
+	text string         // The pattern as written in the query
+	re   *regexp.Regexp // The anchored regular expression of the pattern
+}
+
+// String returns the pattern as written in the query.
+func (this *Pattern) String() string {
+	return this.text
+}
+
+// Match reports whether the whole string matches the pattern.
+func (this *Pattern) Match(value string) bool {
+	return this.re.MatchString(value)
+}
+
+// Compare reports whether the left string matches the pattern. A nil value does not match.
+// Returns a *CompareError if the left value is not a string.
+func (this *Pattern) Compare(left, right interface{}) (bool, error) {
+	if left == nil {
+		return false, nil
+	}
+	value := reflect.ValueOf(left)
+	if value.Kind() != reflect.String {
+		return false, newCompareError(this.name, left, right, "Cannot match a pattern against "+value.Kind().String())
+	}
+	return this.re.MatchString(value.String()), nil
+}
+
+// PatternError describes a like, ilike or glob pattern that cannot be compiled.
+type PatternError struct {
+	Pattern string // The pattern as written in the query
+	Message string // Description of the problem
+}
+
+// Error returns the message with the pattern.
+func (this *PatternError) Error() string {
+	return this.Message + " in pattern '" + this.Pattern + "'"
+}
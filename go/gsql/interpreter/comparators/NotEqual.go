@@ -16,11 +16,10 @@ package comparators
 
 import (
 	"reflect"
-	"strings"
 )
 
 // NotEqual implements the not-equal (!=) comparison operator.
-// It supports string, signed integer, and unsigned integer types.
+// It supports string, signed integer, unsigned integer, floating point, and boolean types.
 type NotEqual struct {
 	compares map[reflect.Kind]func(interface{}, interface{}) bool
 }
@@ -40,21 +39,58 @@ func NewNotEqual() *NotEqual {
 	c.compares[reflect.Uint16] = noteqUintMatcher
 	c.compares[reflect.Uint32] = noteqUintMatcher
 	c.compares[reflect.Uint64] = noteqUintMatcher
+	c.compares[reflect.Float32] = noteqFloatMatcher
+	c.compares[reflect.Float64] = noteqFloatMatcher
+	c.compares[reflect.Bool] = noteqBoolMatcher
 	return c
 }
 
 // Compare evaluates inequality between left and right values.
-func (notequal *NotEqual) Compare(left, right interface{}) bool {
+func (notequal *NotEqual) Compare(left, right interface{}) (bool, error) {
 	return Compare(left, right, notequal.compares, "Not Equal")
 }
 
-// noteqStringMatcher compares two string values for inequality.
+// noteqStringMatcher compares two string values for inequality. It is the opposite of
+// eqStringMatcher, wildcards included, so "not a=b" can be rewritten as "a!=b".
 func noteqStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zside := removeSingleQuote(strings.ToLower(right.(string)))
+	if _, ok := getString(left); !ok {
+		return false
+	}
+	if _, ok := getString(right); !ok {
+		return false
+	}
+	return !eqStringMatcher(left, right)
+}
+
+// noteqBoolMatcher compares two boolean values for inequality.
+func noteqBoolMatcher(left, right interface{}) bool {
+	aside, aok := getBool(left)
+	zside, zok := getBool(right)
+	if !aok || !zok {
+		return false
+	}
 	return aside != zside
 }
 
+// NewStrictNotEqual creates a NotEqual comparator that compares strings exactly, the
+// opposite of the comparator created by NewStrictEqual.
+func NewStrictNotEqual() *NotEqual {
+	c := NewNotEqual()
+	c.compares[reflect.String] = strictNoteqStringMatcher
+	return c
+}
+
+// strictNoteqStringMatcher compares two string values for exact inequality.
+func strictNoteqStringMatcher(left, right interface{}) bool {
+	if _, ok := getString(left); !ok {
+		return false
+	}
+	if _, ok := getString(right); !ok {
+		return false
+	}
+	return !eqStrings(left, right, false)
+}
+
 // noteqIntMatcher compares signed integer values for inequality.
 func noteqIntMatcher(left, right interface{}) bool {
 	aside, ok := getInt64(left)
@@ -80,3 +116,13 @@ func noteqUintMatcher(left, right interface{}) bool {
 	}
 	return aside != zside
 }
+
+// noteqFloatMatcher compares floating point values for inequality.
+// Values within FloatEpsilon of each other are considered equal.
+func noteqFloatMatcher(left, right interface{}) bool {
+	result, ok := compareFloat(left, right)
+	if !ok {
+		return false
+	}
+	return result != 0
+}
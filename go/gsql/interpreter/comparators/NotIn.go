@@ -42,18 +42,25 @@ func NewNotIN() *NotIN {
 	c.compares[reflect.Uint16] = notinUintMatcher
 	c.compares[reflect.Uint32] = notinUintMatcher
 	c.compares[reflect.Uint64] = notinUintMatcher
+	c.compares[reflect.Float32] = notinFloatMatcher
+	c.compares[reflect.Float64] = notinFloatMatcher
 	return c
 }
 
 // Compare evaluates whether left is NOT in the list specified by right.
-func (in *NotIN) Compare(left, right interface{}) bool {
-	return Compare(left, right, in.compares, "In")
+func (in *NotIN) Compare(left, right interface{}) (bool, error) {
+	return Compare(left, right, in.compares, "Not In")
 }
 
 // notinStringMatcher checks if a string value is NOT in a list of strings.
 func notinStringMatcher(left, right interface{}) bool {
-	aside := removeSingleQuote(strings.ToLower(left.(string)))
-	zsideList := strings.ToLower(right.(string))
+	aside, aok := getString(left)
+	zsideList, zok := getString(right)
+	if !aok || !zok {
+		return true
+	}
+	aside = removeSingleQuote(strings.ToLower(aside))
+	zsideList = strings.ToLower(zsideList)
 	values := getInStringList(zsideList)
 	for _, v := range values {
 		if aside == v {
@@ -70,19 +77,14 @@ func notinIntMatcher(left, right interface{}) bool {
 		return true
 	}
 
-	zsideList := strings.ToLower(right.(string))
-
-	values := getInStringList(zsideList)
-	for _, v := range values {
-		intV, e := strconv.Atoi(v)
-		if e != nil {
-			return true
-		}
-		if aside == int64(intV) {
-			return false
-		}
+	zsideList, ok := getString(right)
+	if !ok {
+		return true
 	}
-	return true
+	zsideList = strings.ToLower(zsideList)
+
+	found, _ := intInList(aside, getInStringList(zsideList))
+	return !found
 }
 
 // notinUintMatcher checks if an unsigned integer value is NOT in a list of integers.
@@ -92,15 +94,38 @@ func notinUintMatcher(left, right interface{}) bool {
 		return true
 	}
 
-	zsideList := strings.ToLower(right.(string))
+	zsideList, ok := getString(right)
+	if !ok {
+		return true
+	}
+	zsideList = strings.ToLower(zsideList)
+
+	found, _ := uintInList(aside, getInStringList(zsideList))
+	return !found
+}
+
+// notinFloatMatcher checks if a floating point value is NOT in a list of numbers.
+// Values within FloatEpsilon of each other are considered equal.
+func notinFloatMatcher(left, right interface{}) bool {
+	aside, ok := getFloat64(left)
+	if !ok {
+		return true
+	}
+
+	zsideList, ok := getString(right)
+	if !ok {
+		return true
+	}
+	zsideList = strings.ToLower(zsideList)
 
 	values := getInStringList(zsideList)
+	bits := floatBits(left, right)
 	for _, v := range values {
-		intV, e := strconv.Atoi(v)
+		floatV, e := strconv.ParseFloat(strings.TrimSpace(v), 64)
 		if e != nil {
 			return true
 		}
-		if aside == uint64(intV) {
+		if floatsEqual(aside, floatV, bits) {
 			return false
 		}
 	}
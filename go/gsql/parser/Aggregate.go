@@ -14,63 +14,273 @@ limitations under the License.
 */
 
 // Aggregate.go provides parsing support for aggregate functions in L8QL SELECT clauses.
-// Supported functions: count(*), count(field), sum(field), avg(field), min(field), max(field).
+// Supported functions: count(*), count(field), count(distinct field), sum(field), avg(field),
+// min(field), max(field), median(field), percentile(field, p), stddev(field), variance(field),
+// first(field), last(field), collect(field), approx_count_distinct(field[, error]) and
+// approx_percentile(field, p[, error]).
 package parser
 
 import (
-	"github.com/saichler/l8types/go/types/l8api"
+	"errors"
+	"strconv"
 	"strings"
+
+	"github.com/saichler/l8types/go/types/l8api"
 )
 
-// aggregateFunctions lists the supported aggregate function names.
-var aggregateFunctions = []string{"count", "sum", "avg", "min", "max"}
+// Distinct is the modifier of count(distinct field).
+const Distinct = "distinct"
+
+// aggregateSpec describes the arguments an aggregate function accepts.
+type aggregateSpec struct {
+	star     bool                          // Whether the field may be "*"
+	distinct bool                          // Whether the field may be preceded by the distinct modifier
+	args     int                           // The number of constant arguments following the field
+	optional int                           // The number of optional constant arguments following the required ones
+	variadic bool                          // Whether any number of constant arguments may follow the required ones
+	check    func(i int, arg string) error // Validates the constant argument at index i, if any
+}
+
+// aggregateFunctions maps the supported aggregate function names to their arguments.
+var aggregateFunctions = map[string]*aggregateSpec{
+	"count":      {star: true, distinct: true},
+	"sum":        {},
+	"avg":        {},
+	"min":        {},
+	"max":        {},
+	"median":     {},
+	"percentile": {args: 1, check: checkPercentile},
+	"stddev":     {},
+	"variance":   {},
+	"first":      {},
+	"last":       {},
+	"collect":    {},
+
+	"approx_count_distinct": {optional: 1, check: checkApproxCountDistinct},
+	"approx_percentile":     {args: 1, optional: 1, check: checkApproxPercentile},
+}
+
+// userAggregate describes the arguments of a user-defined aggregate function,
+// which are checked when the query is interpreted.
+var userAggregate = &aggregateSpec{star: true, variadic: true}
 
 // parseAggregateFunction detects if a SELECT column is an aggregate function call.
 // Returns the parsed L8AggregateFunction and true if it is, or nil and false otherwise.
+// A call of a known function with invalid arguments returns true and an error.
+// The calls of the user-defined aggregate functions of the extensions, if any, are detected too.
+// The modifier and constant arguments are kept in the Function, see SplitAggregateFunction.
+// An alias given with as, e.g. "count(*) as total", replaces the default alias.
 // Examples: "count(*)" -> {function:"count", field:"*", alias:"count"}
 //
 //	"sum(salary)" -> {function:"sum", field:"salary", alias:"sumSalary"}
-func parseAggregateFunction(col string) (*l8api.L8AggregateFunction, bool) {
-	col = strings.TrimSpace(col)
-	lower := strings.ToLower(col)
-
-	for _, fn := range aggregateFunctions {
-		prefix := fn + "("
-		if strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, ")") {
-			// Extract field name between parentheses
-			field := strings.TrimSpace(col[len(prefix) : len(col)-1])
-			if field == "" {
-				continue
+//	"count(distinct name)" -> {function:"count:distinct", field:"name", alias:"countDistinctName"}
+//	"percentile(salary, 95)" -> {function:"percentile:95", field:"salary", alias:"percentile95Salary"}
+func parseAggregateFunction(col string, ext Extensions) (*l8api.L8AggregateFunction, bool, error) {
+	s, e := newTokenStream(col)
+	if e != nil {
+		return nil, false, nil
+	}
+	return parseAggregateCall(s, s.tokens[:len(s.tokens)-1], ext)
+}
+
+// parseAggregateCall is parseAggregateFunction over the tokens of a SELECT column.
+// Invalid arguments are reported as a *SyntaxError located at the offending token.
+func parseAggregateCall(s *tokenStream, tokens []*Token, ext Extensions) (*l8api.L8AggregateFunction, bool, error) {
+	tokens, as, e := splitAlias(s, tokens)
+	if e != nil {
+		return nil, false, nil
+	}
+	if len(tokens) < 4 || tokens[0].Type != TokenIdent || tokens[1].Value != "(" || closeOf(tokens, 1) != len(tokens)-1 {
+		return nil, false, nil
+	}
+	fn := strings.ToLower(tokens[0].Text)
+	spec, ok := aggregateFunctions[fn]
+	if !ok && ext != nil && ext.IsAggregate(fn) {
+		spec, ok = userAggregate, true
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	// Split the field and the constant arguments between parentheses
+	closing := tokens[len(tokens)-1]
+	args, e := clauseItems(s, tokens[2:len(tokens)-1])
+	if e != nil {
+		return nil, true, e
+	}
+	field := args[0]
+	args = args[1:]
+	modifiers := make([]string, 0, len(args)+1)
+	if len(field) > 1 && isWord(field[0], Distinct) {
+		if !spec.distinct {
+			return nil, true, s.errorAt(field[0], "Aggregate function "+fn+" does not accept "+Distinct)
+		}
+		modifiers = append(modifiers, Distinct)
+		field = field[1:]
+	}
+	if field[0].Value == "*" && (!spec.star || len(modifiers) > 0) {
+		return nil, true, s.errorAt(field[0], "Aggregate function "+fn+" does not accept *", "field")
+	}
+	if field[0].Type != TokenIdent && field[0].Value != "*" {
+		return nil, true, s.errorAt(field[0], "Invalid field in aggregate function", "field")
+	}
+	if len(field) > 1 {
+		return nil, true, s.errorAt(field[1], "Invalid field in aggregate function", ",", ")")
+	}
+	expected := strconv.Itoa(spec.args + 1)
+	if spec.optional > 0 {
+		expected += " to " + strconv.Itoa(spec.args+spec.optional+1)
+	}
+	if len(args) < spec.args {
+		return nil, true, s.errorAt(closing, "Aggregate function "+fn+" expects "+expected+" argument(s)", ",")
+	}
+	if !spec.variadic && len(args) > spec.args+spec.optional {
+		return nil, true, s.errorAt(args[spec.args+spec.optional][0], "Aggregate function "+fn+" expects "+expected+" argument(s)", ")")
+	}
+	for i, arg := range args {
+		text := clauseText(s, arg)
+		if spec.check != nil {
+			if e := spec.check(i, text); e != nil {
+				return nil, true, s.errorAt(arg[0], e.Error())
 			}
+		}
+		modifiers = append(modifiers, text)
+	}
+
+	alias := buildAlias(fn, field[0].Text, modifiers...)
+	if as != nil {
+		alias = as.Value
+	}
+	if len(modifiers) > 0 {
+		fn = fn + ":" + strings.Join(modifiers, ",")
+	}
+	return &l8api.L8AggregateFunction{
+		Function: fn,
+		Field:    field[0].Text,
+		Alias:    alias,
+	}, true, nil
+}
 
-			alias := buildAlias(fn, field)
-			return &l8api.L8AggregateFunction{
-				Function: fn,
-				Field:    field,
-				Alias:    alias,
-			}, true
+// closeOf returns the index of the bracket that closes the bracket at index open,
+// or -1 if it is not closed.
+func closeOf(tokens []*Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].Type == TokenOpen {
+			depth++
+		} else if tokens[i].Type == TokenClose {
+			depth--
+			if depth == 0 {
+				return i
+			}
 		}
 	}
-	return nil, false
+	return -1
+}
+
+// checkPercentile validates the percentile argument, a number between 0 and 100.
+func checkPercentile(i int, arg string) error {
+	p, e := strconv.ParseFloat(arg, 64)
+	if e != nil || p < 0 || p > 100 {
+		return errors.New("Percentile must be a number between 0 and 100")
+	}
+	return nil
+}
+
+// checkApproxCountDistinct validates the optional relative error of approx_count_distinct.
+func checkApproxCountDistinct(i int, arg string) error {
+	return checkError(arg)
+}
+
+// checkApproxPercentile validates the percentile and the optional rank error of approx_percentile.
+func checkApproxPercentile(i int, arg string) error {
+	if i == 0 {
+		return checkPercentile(i, arg)
+	}
+	return checkError(arg)
+}
+
+// checkError validates the error bound of an approximate aggregate, a number between 0 and 1.
+func checkError(arg string) error {
+	e, err := strconv.ParseFloat(arg, 64)
+	if err != nil || e <= 0 || e >= 1 {
+		return errors.New("Error bound must be a number between 0 and 1")
+	}
+	return nil
+}
+
+// SplitAggregateFunction splits the Function of an L8AggregateFunction into the function
+// name and its modifier and constant arguments, e.g. "percentile:95" -> "percentile", ["95"]
+// and "count:distinct" -> "count", ["distinct"].
+func SplitAggregateFunction(function string) (string, []string) {
+	index := strings.Index(function, ":")
+	if index == -1 {
+		return function, nil
+	}
+	return function[:index], strings.Split(function[index+1:], ",")
+}
+
+// AggregateCall returns the function call text of an aggregate function,
+// e.g. "count(*)", "count(distinct name)" or "percentile(salary, 95)".
+func AggregateCall(agg *l8api.L8AggregateFunction) string {
+	fn, args := SplitAggregateFunction(agg.Function)
+	buff := strings.Builder{}
+	buff.WriteString(fn)
+	buff.WriteString("(")
+	if len(args) > 0 && args[0] == Distinct {
+		buff.WriteString(Distinct)
+		buff.WriteString(" ")
+		args = args[1:]
+	}
+	buff.WriteString(agg.Field)
+	for _, arg := range args {
+		buff.WriteString(", ")
+		buff.WriteString(arg)
+	}
+	buff.WriteString(")")
+	return buff.String()
 }
 
-// buildAlias generates a display alias for an aggregate function.
-// count(*) -> "count", sum(salary) -> "sumSalary", avg(amount) -> "avgAmount"
-func buildAlias(fn, field string) string {
+// buildAlias generates a display alias for an aggregate function. Function names with
+// underscores are camel cased, and the modifier and constant arguments follow the function
+// name, with dots replaced by underscores and consecutive numbers separated by an underscore.
+// count(*) -> "count", sum(salary) -> "sumSalary", avg(amount) -> "avgAmount",
+// count(distinct name) -> "countDistinctName", percentile(salary, 99.9) -> "percentile99_9Salary",
+// approx_count_distinct(name) -> "approxCountDistinctName",
+// approx_percentile(salary, 50, 0.01) -> "approxPercentile50_0_01Salary"
+func buildAlias(fn, field string, args ...string) string {
+	words := strings.Split(fn, "_")
+	fn = words[0]
+	for _, word := range words[1:] {
+		fn += capitalize(word)
+	}
+	for i, arg := range args {
+		if i > 0 && isDigit(arg[0]) && isDigit(fn[len(fn)-1]) {
+			fn += "_"
+		}
+		fn += capitalize(strings.Replace(arg, ".", "_", -1))
+	}
 	if field == "*" {
 		return fn
 	}
-	// Capitalize first letter of field
-	if len(field) > 0 {
-		return fn + strings.ToUpper(field[:1]) + field[1:]
+	return fn + capitalize(field)
+}
+
+// capitalize returns the word with its first letter in upper case.
+func capitalize(word string) string {
+	if len(word) > 0 {
+		return strings.ToUpper(word[:1]) + word[1:]
 	}
-	return fn + field
+	return word
 }
 
 // isAggregateQuery checks if any property in the SELECT clause is an aggregate function.
-func isAggregateQuery(props []string) bool {
+// Aggregate functions over a window are window functions.
+func isAggregateQuery(props []string, ext Extensions) bool {
 	for _, prop := range props {
-		_, ok := parseAggregateFunction(prop)
+		if _, window, _ := ParseWindowFunction(prop); window {
+			continue
+		}
+		_, ok, _ := parseAggregateFunction(prop, ext)
 		if ok {
 			return true
 		}
@@ -0,0 +1,130 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Alias.go parses the aliases of SELECT columns, e.g. "select name as host, count(*) as total",
+// which name the columns of result rows. An alias is an identifier, or a quoted string for
+// names with spaces or punctuation. Aliases are kept in the column text as "<column> as <alias>".
+package parser
+
+import (
+	"strings"
+)
+
+// As is the keyword that introduces the alias of a SELECT column.
+const As = "as"
+
+// SplitAlias splits a SELECT column into its expression and its alias, the alias being empty
+// if the column has none. Returns an error if the column ends with an incomplete alias.
+func SplitAlias(col string) (string, string, error) {
+	col = strings.TrimSpace(col)
+	s, e := newTokenStream(col)
+	if e != nil {
+		return col, "", nil
+	}
+	tokens, alias, e := splitAlias(s, s.tokens[:len(s.tokens)-1])
+	if e != nil {
+		return "", "", e
+	}
+	if alias == nil {
+		return col, "", nil
+	}
+	return clauseText(s, tokens), alias.Value, nil
+}
+
+// splitAlias splits the tokens of a SELECT column into the tokens of its expression and its
+// alias token, which is nil if the column has none. A missing or invalid alias is reported
+// as a *SyntaxError located at the alias.
+func splitAlias(s *tokenStream, tokens []*Token) ([]*Token, *Token, error) {
+	n := len(tokens)
+	if n >= 1 && isWord(tokens[n-1], As) {
+		return nil, nil, s.errorAt(tokens[n-1], "Missing alias after as", "alias")
+	}
+	if n < 3 || !isWord(tokens[n-2], As) || tokens[n-3].Type == TokenComma {
+		return tokens, nil, nil
+	}
+	alias := tokens[n-1]
+	if (alias.Type != TokenIdent && alias.Type != TokenString) || alias.Value == "" ||
+		(alias.Type == TokenIdent && strings.Contains(alias.Value, ".")) {
+		return nil, nil, s.errorAt(alias, "Invalid alias "+alias.Text, "alias")
+	}
+	return tokens[:n-2], alias, nil
+}
+
+// WithAlias returns the column text of an expression with an alias, quoting the alias
+// unless it is an identifier. Returns the expression itself for an empty alias.
+func WithAlias(expr, alias string) string {
+	if alias == "" {
+		return expr
+	}
+	if isIdentifier(alias) {
+		return expr + " " + As + " " + alias
+	}
+	return expr + " " + As + " '" + strings.Replace(alias, "'", "''", -1) + "'"
+}
+
+// isIdentifier reports whether the word is an identifier without dots that is not a keyword.
+func isIdentifier(word string) bool {
+	if word == "" || !isIdentStart(word[0]) || keywords[strings.ToLower(word)] {
+		return false
+	}
+	for i := 1; i < len(word); i++ {
+		if !isWordChar(word[i]) || word[i] == '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// ColumnName returns the name of a SELECT column in result rows: its alias, or the alias
+// of an aggregate or window function, or the column text.
+func ColumnName(col string) string {
+	expr, alias, e := SplitAlias(col)
+	if e != nil {
+		return col
+	}
+	if alias != "" {
+		return alias
+	}
+	if window, ok, e := ParseWindowFunction(expr); ok && e == nil {
+		return window.Alias
+	}
+	if agg, ok, e := parseAggregateFunction(expr, nil); ok && e == nil {
+		return agg.Alias
+	}
+	return expr
+}
+
+// canonicalAliases writes the aliases of the SELECT columns in their canonical form.
+// Returns a *SyntaxError for an incomplete alias, or if an alias is also the name of another column.
+func canonicalAliases(s *tokenStream, items [][]*Token, cols []string) error {
+	names := make(map[string]int)
+	for _, col := range cols {
+		names[ColumnName(col)]++
+	}
+	for i, item := range items {
+		_, alias, e := splitAlias(s, item)
+		if e != nil {
+			return e
+		}
+		if alias != nil {
+			if names[alias.Value] > 1 {
+				return s.errorAt(alias, "Duplicate column name "+alias.Value)
+			}
+			expr, _, _ := SplitAlias(cols[i])
+			cols[i] = WithAlias(expr, alias.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Arithmetic.go parses the arithmetic expressions of computed columns and comparison
+// operands, e.g. "usedBytes / totalBytes" or "-(rxBytes + txBytes) * 8", with the usual
+// precedence: unary minus binds tighter than * / %, which bind tighter than + -.
+// Operands may be properties, numbers, quoted strings, true or false, and calls of
+// the scalar functions, e.g. "len(trim(name)) + 1".
+package parser
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ArithmeticOperation is the operator of an arithmetic expression node.
+type ArithmeticOperation string
+
+// Arithmetic operators. Minus is both the binary subtraction and the unary negation.
+const (
+	Plus   ArithmeticOperation = "+" // Addition
+	Minus  ArithmeticOperation = "-" // Subtraction, or negation of a single operand
+	Times  ArithmeticOperation = "*" // Multiplication
+	Divide ArithmeticOperation = "/" // Division
+	Modulo ArithmeticOperation = "%" // Remainder of the division
+)
+
+// ArithmeticExpression is a node of an arithmetic expression tree: an operand, an operation
+// over one (negation) or two operands, or a call of a scalar function with its arguments.
+type ArithmeticExpression struct {
+	Operation ArithmeticOperation     // The operator, empty for an operand or call node
+	Function  string                  // The scalar function of a call node
+	Operand   string                  // The property path, literal or other function call of an operand node
+	Literal   bool                    // True if the operand is a number, true, false or a quoted string
+	Quoted    bool                    // True if the operand is a quoted string, held unquoted in Operand
+	Operands  []*ArithmeticExpression // The operands of an operation node, or the arguments of a call
+}
+
+// ParseArithmetic parses an arithmetic expression or scalar function call. It returns false,
+// with no error, for a text that holds a single operand or a negated number, which are plain
+// values rather than expressions, and false with the syntax error for a text that is not a
+// valid expression.
+func ParseArithmetic(text string) (*ArithmeticExpression, bool, error) {
+	s, e := newTokenStream(text)
+	if e != nil {
+		return nil, false, e
+	}
+	return parseArithmetic(s)
+}
+
+// parseArithmetic is ParseArithmetic over a stream holding only the tokens of the expression.
+func parseArithmetic(s *tokenStream) (*ArithmeticExpression, bool, error) {
+	expr, e := parseSum(s)
+	if e != nil {
+		return nil, false, e
+	}
+	if s.peek().Type != TokenEOF {
+		return nil, false, s.errorAt(s.peek(), "Unexpected token in arithmetic expression", "+", "-", "*", "/", "%")
+	}
+	if expr.Operation == "" && expr.Function == "" || expr.Operation == Minus && len(expr.Operands) == 1 && expr.Operands[0].Literal {
+		return nil, false, nil
+	}
+	return expr, true, nil
+}
+
+// IsArithmetic reports whether the text is a valid arithmetic expression over more than a
+// single value, or a scalar function call.
+func IsArithmetic(text string) bool {
+	_, ok, _ := ParseArithmetic(text)
+	return ok
+}
+
+// parseSum parses terms joined by + and -.
+func parseSum(s *tokenStream) (*ArithmeticExpression, error) {
+	return parseBinary(s, parseProduct, Plus, Minus)
+}
+
+// parseProduct parses factors joined by *, / and %.
+func parseProduct(s *tokenStream) (*ArithmeticExpression, error) {
+	return parseBinary(s, parseFactor, Times, Divide, Modulo)
+}
+
+// parseBinary parses a left associative sequence of operands, parsed by the operand
+// function, joined by any of the operations.
+func parseBinary(s *tokenStream, operand func(*tokenStream) (*ArithmeticExpression, error),
+	operations ...ArithmeticOperation) (*ArithmeticExpression, error) {
+	left, e := operand(s)
+	if e != nil {
+		return nil, e
+	}
+	for {
+		op := arithmeticOperationOf(s.peek(), operations)
+		if op == "" {
+			return left, nil
+		}
+		s.next()
+		right, e := operand(s)
+		if e != nil {
+			return nil, e
+		}
+		left = &ArithmeticExpression{Operation: op, Operands: []*ArithmeticExpression{left, right}}
+	}
+}
+
+// parseFactor parses a negated factor, a bracketed expression, a scalar function call or a single operand.
+func parseFactor(s *tokenStream) (*ArithmeticExpression, error) {
+	tok := s.peek()
+	switch {
+	case tok.Type == TokenOper && tok.Value == string(Minus):
+		s.next()
+		operand, e := parseFactor(s)
+		if e != nil {
+			return nil, e
+		}
+		return &ArithmeticExpression{Operation: Minus, Operands: []*ArithmeticExpression{operand}}, nil
+	case tok.Type == TokenOpen && tok.Value == "(":
+		open := s.next()
+		expr, e := parseSum(s)
+		if e != nil {
+			return nil, e
+		}
+		if close := s.peek(); close.Type != TokenClose || close.Value != ")" {
+			column := newSyntaxError(s.text, open, "").Column
+			return nil, s.errorAt(close, "Missing close bracket for '(' at column "+strconv.Itoa(column), ")")
+		}
+		s.next()
+		return expr, nil
+	case tok.Type == TokenNumber:
+		s.next()
+		return &ArithmeticExpression{Operand: tok.Text, Literal: true}, nil
+	case tok.Type == TokenString:
+		s.next()
+		return &ArithmeticExpression{Operand: tok.Value, Literal: true, Quoted: true}, nil
+	case isWord(tok, "true") || isWord(tok, "false"):
+		s.next()
+		return &ArithmeticExpression{Operand: strings.ToLower(tok.Text), Literal: true}, nil
+	case tok.Type == TokenIdent:
+		s.next()
+		last := tok
+		if open := s.peek(); open.Type == TokenOpen && open.Value == "(" {
+			if IsScalarFunction(tok.Text) || !isBuiltinCall(strings.ToLower(tok.Text)) {
+				return parseCall(s, tok)
+			}
+			call, e := skipCallArguments(s)
+			if e != nil {
+				return nil, e
+			}
+			last = call
+		}
+		return &ArithmeticExpression{Operand: s.span(tok, last)}, nil
+	}
+	return nil, s.errorAt(tok, "Invalid arithmetic operand", "property", "number", "string", "function", "(")
+}
+
+// arithmeticOperationOf returns the operation of the token if it is one of the operations,
+// or an empty operation otherwise.
+func arithmeticOperationOf(tok *Token, operations []ArithmeticOperation) ArithmeticOperation {
+	if tok.Type != TokenOper {
+		return ""
+	}
+	for _, op := range operations {
+		if tok.Value == string(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// isArithmeticToken reports whether the token is an arithmetic operator.
+func isArithmeticToken(tok *Token) bool {
+	return arithmeticOperationOf(tok, []ArithmeticOperation{Plus, Minus, Times, Divide, Modulo}) != ""
+}
+
+// precedence returns the binding strength of the node's operation; operands bind tightest.
+func (this *ArithmeticExpression) precedence() int {
+	switch {
+	case this.Operation == "":
+		return 4 // Operands and calls
+	case len(this.Operands) == 1:
+		return 3
+	case this.Operation == Times || this.Operation == Divide || this.Operation == Modulo:
+		return 2
+	}
+	return 1
+}
+
+// String returns the canonical text of the expression, with a space around binary operators
+// and brackets only where the precedence requires them.
+func (this *ArithmeticExpression) String() string {
+	buff := bytes.Buffer{}
+	this.toString(&buff)
+	return buff.String()
+}
+
+// toString is a helper that recursively writes the expression to a buffer.
+func (this *ArithmeticExpression) toString(buff *bytes.Buffer) {
+	if this.Function != "" {
+		this.callString(buff)
+		return
+	}
+	if this.Operation == "" {
+		if this.Quoted {
+			buff.WriteString("'")
+			buff.WriteString(strings.ReplaceAll(this.Operand, "'", "''"))
+			buff.WriteString("'")
+		} else {
+			buff.WriteString(this.Operand)
+		}
+		return
+	}
+	if len(this.Operands) == 1 {
+		buff.WriteString(string(this.Operation))
+		this.Operands[0].writeOperand(buff, this.Operands[0].precedence() < 3)
+		return
+	}
+	this.Operands[0].writeOperand(buff, this.Operands[0].precedence() < this.precedence())
+	buff.WriteString(" ")
+	buff.WriteString(string(this.Operation))
+	buff.WriteString(" ")
+	this.Operands[1].writeOperand(buff, this.Operands[1].precedence() <= this.precedence())
+}
+
+// callString writes a scalar function call, e.g. "substr(name, 1, 3)" or "cast(port as string)".
+func (this *ArithmeticExpression) callString(buff *bytes.Buffer) {
+	buff.WriteString(this.Function)
+	buff.WriteString("(")
+	for i, arg := range this.Operands {
+		if i > 0 && this.Function == CastFunction {
+			buff.WriteString(" " + As + " ")
+			buff.WriteString(arg.Operand)
+			continue
+		}
+		if i > 0 {
+			buff.WriteString(", ")
+		}
+		arg.toString(buff)
+	}
+	buff.WriteString(")")
+}
+
+// writeOperand writes the expression as the operand of another, in brackets if required.
+func (this *ArithmeticExpression) writeOperand(buff *bytes.Buffer, brackets bool) {
+	if brackets {
+		buff.WriteString("(")
+	}
+	this.toString(buff)
+	if brackets {
+		buff.WriteString(")")
+	}
+}
+
+// canonicalArithmetic replaces the arithmetic columns of the SELECT clause with their
+// canonical text, keeping their aliases. Calls of user-defined aggregate functions are kept.
+func canonicalArithmetic(s *tokenStream, items [][]*Token, cols []string, ext Extensions) error {
+	for i, item := range items {
+		if _, _, e := splitAlias(s, item); e != nil {
+			return e
+		}
+		text, alias, _ := SplitAlias(cols[i])
+		if _, aggregate, _ := parseAggregateFunction(text, ext); aggregate {
+			continue
+		}
+		expr, ok, _ := ParseArithmetic(text)
+		if ok {
+			cols[i] = WithAlias(expr.String(), alias)
+		}
+	}
+	return nil
+}
+
+// computedColumn returns the index of the first of the SELECT columns that is an arithmetic
+// expression or scalar function call and is not a group-by column, or -1 if there is none.
+func computedColumn(cols []string, groupBy []string, ext Extensions) int {
+	exprs, _, _ := GroupingSets(groupBy)
+	for i, col := range cols {
+		text, _, e := SplitAlias(col)
+		if e != nil || !IsArithmetic(text) {
+			continue
+		}
+		if _, aggregate, _ := parseAggregateFunction(text, ext); aggregate {
+			continue
+		}
+		grouped := false
+		for _, expr := range exprs {
+			grouped = grouped || expr.String() == text
+		}
+		if !grouped {
+			return i
+		}
+	}
+	return -1
+}
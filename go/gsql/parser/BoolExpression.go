@@ -0,0 +1,327 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// BoolExpression.go provides the precedence-correct boolean tree of a WHERE/HAVING clause
+// (NOT binds tighter than AND, which binds tighter than OR) and its conversion to and
+// from the linked L8Expression/L8Condition form carried in l8api.L8Query.
+package parser
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/saichler/l8types/go/types/l8api"
+)
+
+// BoolOperation identifies the kind of a BoolExpression node.
+type BoolOperation int
+
+// Kinds of BoolExpression nodes.
+const (
+	BoolCompare BoolOperation = iota // Leaf node holding a single comparison
+	BoolAnd                          // Conjunction of all operands
+	BoolOr                           // Disjunction of all operands
+	BoolNot                          // Negation of the single operand
+	BoolGroup                        // Parenthesized single operand, kept to preserve the query's brackets
+)
+
+// BoolExpression is a node of the boolean expression tree.
+type BoolExpression struct {
+	Operation  BoolOperation       // The kind of this node
+	Comparator *l8api.L8Comparator // The comparison of a BoolCompare node
+	Operands   []*BoolExpression   // The operands of AND/OR (two or more) and NOT/group (one) nodes
+}
+
+// NewBoolExpression parses a WHERE clause string into a precedence-correct boolean tree.
+// Returns a *SyntaxError if the string contains invalid syntax.
+func NewBoolExpression(ws string) (*BoolExpression, error) {
+	s, e := newTokenStream(ws)
+	if e != nil {
+		return nil, e
+	}
+	expr, e := parseExpression(s)
+	if e != nil {
+		return nil, e
+	}
+	if s.peek().Type != TokenEOF {
+		return nil, s.errorAt(s.peek(), "Unexpected token after expression", "and", "or", "end of clause")
+	}
+	return expr, nil
+}
+
+// String returns the string representation of the tree, with brackets
+// wherever the query had them.
+func (this *BoolExpression) String() string {
+	buff := bytes.Buffer{}
+	this.toString(&buff)
+	return buff.String()
+}
+
+// toString is a helper that recursively writes the tree to a buffer.
+func (this *BoolExpression) toString(buff *bytes.Buffer) {
+	switch this.Operation {
+	case BoolCompare:
+		buff.WriteString(StringComparator(this.Comparator))
+	case BoolAnd, BoolOr:
+		op := And
+		if this.Operation == BoolOr {
+			op = Or
+		}
+		for i, operand := range this.Operands {
+			if i > 0 {
+				buff.WriteString(string(op))
+			}
+			if this.Operation == BoolAnd && operand.Operation == BoolOr {
+				buff.WriteString("(")
+				operand.toString(buff)
+				buff.WriteString(")")
+			} else {
+				operand.toString(buff)
+			}
+		}
+	case BoolNot:
+		buff.WriteString(string(Not))
+		buff.WriteString(" ")
+		if this.Operands[0].Operation == BoolAnd || this.Operands[0].Operation == BoolOr {
+			buff.WriteString("(")
+			this.Operands[0].toString(buff)
+			buff.WriteString(")")
+		} else {
+			this.Operands[0].toString(buff)
+		}
+	case BoolGroup:
+		buff.WriteString("(")
+		this.Operands[0].toString(buff)
+		buff.WriteString(")")
+	}
+}
+
+// newBoolOperation creates an AND/OR node from its operands, returning the single
+// operand itself when there is only one.
+func newBoolOperation(operation BoolOperation, operands []*BoolExpression) *BoolExpression {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &BoolExpression{Operation: operation, Operands: operands}
+}
+
+// BoolExpressionOf converts a linked L8Expression into a precedence-correct boolean tree.
+// The linked form is read as the infix sequence of its comparisons and groups, in order,
+// with AND binding tighter than OR. A missing operator is read as AND.
+// Returns nil for a nil expression.
+func BoolExpressionOf(expr *l8api.L8Expression) (*BoolExpression, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	operands := make([]*BoolExpression, 0)
+	ops := make([]ConditionOperation, 0)
+	for node := expr; node != nil; node = node.Next {
+		if node.Condition != nil {
+			cmpOperands, cmpOps := conditionSequence(node.Condition)
+			operands = append(operands, cmpOperands...)
+			ops = append(ops, cmpOps...)
+		}
+		if node.Child != nil {
+			child, e := BoolExpressionOf(node.Child)
+			if e != nil {
+				return nil, e
+			}
+			if node.Condition != nil {
+				ops = append(ops, And)
+			}
+			operands = append(operands, &BoolExpression{Operation: BoolGroup, Operands: []*BoolExpression{child}})
+		}
+		if node.Next != nil {
+			ops = append(ops, ConditionOperation(node.AndOr))
+		}
+	}
+	return combineSequence(operands, ops)
+}
+
+// conditionSequence returns the comparisons of a condition chain and the operators between them.
+func conditionSequence(condition *l8api.L8Condition) ([]*BoolExpression, []ConditionOperation) {
+	operands := make([]*BoolExpression, 0)
+	ops := make([]ConditionOperation, 0)
+	for c := condition; c != nil; c = c.Next {
+		operands = append(operands, &BoolExpression{Operation: BoolCompare, Comparator: c.Comparator})
+		if c.Next != nil {
+			ops = append(ops, ConditionOperation(c.Oper))
+		}
+	}
+	return operands, ops
+}
+
+// combineSequence builds the tree of an infix sequence of operands, grouping the
+// operands joined by AND before joining the groups by OR.
+func combineSequence(operands []*BoolExpression, ops []ConditionOperation) (*BoolExpression, error) {
+	or := make([]*BoolExpression, 0)
+	and := []*BoolExpression{operands[0]}
+	for i, op := range ops {
+		switch op {
+		case And, "":
+			and = append(and, operands[i+1])
+		case Or:
+			or = append(or, newBoolOperation(BoolAnd, and))
+			and = []*BoolExpression{operands[i+1]}
+		default:
+			return nil, errors.New("Unsupported operation in expression:" + string(op))
+		}
+	}
+	or = append(or, newBoolOperation(BoolAnd, and))
+	return newBoolOperation(BoolOr, or), nil
+}
+
+// L8Expression converts the tree into the linked L8Expression form. Runs of comparisons
+// are linked as condition chains and groups become expression nodes. Chains never span
+// a precedence boundary, so the linked form reads the same whether a condition chain is
+// taken as a unit or as part of the surrounding sequence. l8api.L8Expression has no
+// negation field, so NOT is first rewritten by De Morgan's laws, see negated.
+func (this *BoolExpression) L8Expression() *l8api.L8Expression {
+	l := &linker{}
+	l.link(this.withoutNot())
+	return l.first
+}
+
+// withoutNot returns the tree with each NOT node replaced by the negation of its operand.
+func (this *BoolExpression) withoutNot() *BoolExpression {
+	switch this.Operation {
+	case BoolCompare:
+		return this
+	case BoolNot:
+		return this.Operands[0].negated()
+	}
+	operands := make([]*BoolExpression, 0, len(this.Operands))
+	for _, operand := range this.Operands {
+		operands = append(operands, operand.withoutNot())
+	}
+	return &BoolExpression{Operation: this.Operation, Operands: operands}
+}
+
+// negated returns the negation of the tree, without NOT nodes. The negation is pushed
+// down to the comparisons by De Morgan's laws: AND and OR are swapped, a double negation
+// cancels, groups keep their brackets and each comparison is replaced by its opposite,
+// so "not (a=1 or b>2)" becomes "(a!=1 and b<=2)".
+func (this *BoolExpression) negated() *BoolExpression {
+	switch this.Operation {
+	case BoolCompare:
+		return &BoolExpression{Operation: BoolCompare, Comparator: negateComparator(this.Comparator)}
+	case BoolNot:
+		return this.Operands[0].withoutNot()
+	}
+	operation := this.Operation
+	if operation == BoolAnd {
+		operation = BoolOr
+	} else if operation == BoolOr {
+		operation = BoolAnd
+	}
+	operands := make([]*BoolExpression, 0, len(this.Operands))
+	for _, operand := range this.Operands {
+		operands = append(operands, operand.negated())
+	}
+	return &BoolExpression{Operation: operation, Operands: operands}
+}
+
+// linker builds the linked L8Expression form of a boolean tree.
+type linker struct {
+	first         *l8api.L8Expression // The first node of the linked expression
+	last          *l8api.L8Expression // The last node appended
+	lastCondition *l8api.L8Condition  // The tail of the open condition chain, or nil
+	op            string              // The operator linking the next operand
+}
+
+// link appends the operands of the node to the linked expression.
+func (this *linker) link(node *BoolExpression) {
+	switch node.Operation {
+	case BoolCompare:
+		this.appendComparator(node.Comparator)
+	case BoolAnd:
+		for i, operand := range node.Operands {
+			if i > 0 {
+				this.op = string(And)
+			}
+			if operand.Operation == BoolOr {
+				this.appendGroup(operand)
+			} else {
+				this.link(operand)
+			}
+		}
+	case BoolOr:
+		for i, operand := range node.Operands {
+			if i > 0 {
+				this.op = string(Or)
+			}
+			pure := operand.isChain(BoolOr)
+			if !pure {
+				this.lastCondition = nil
+			}
+			this.link(operand)
+			if !pure {
+				this.lastCondition = nil
+			}
+		}
+	case BoolGroup:
+		this.appendGroup(node.Operands[0])
+	}
+}
+
+// isChain reports whether the node holds only comparisons that can be linked as a
+// single condition chain under a parent of the given operation.
+func (this *BoolExpression) isChain(parent BoolOperation) bool {
+	switch this.Operation {
+	case BoolCompare:
+		return true
+	case BoolAnd, BoolOr:
+		if this.Operation == BoolOr && parent == BoolAnd {
+			return false
+		}
+		for _, operand := range this.Operands {
+			if !operand.isChain(this.Operation) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// appendComparator appends a comparison to the open condition chain,
+// or starts a new chain node if there is none.
+func (this *linker) appendComparator(cmp *l8api.L8Comparator) {
+	condition := &l8api.L8Condition{Comparator: cmp}
+	if this.lastCondition != nil {
+		this.lastCondition.Oper = this.op
+		this.lastCondition.Next = condition
+	} else {
+		this.appendNode(&l8api.L8Expression{Condition: condition})
+	}
+	this.lastCondition = condition
+}
+
+// appendGroup appends a node whose child is the linked form of the operand.
+func (this *linker) appendGroup(operand *BoolExpression) {
+	this.appendNode(&l8api.L8Expression{Child: operand.L8Expression()})
+	this.lastCondition = nil
+}
+
+// appendNode links a new node after the last node.
+func (this *linker) appendNode(expr *l8api.L8Expression) {
+	if this.last == nil {
+		this.first = expr
+	} else {
+		this.last.AndOr = this.op
+		this.last.Next = expr
+	}
+	this.last = expr
+}
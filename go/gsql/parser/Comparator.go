@@ -16,7 +16,7 @@ package parser
 
 import (
 	"bytes"
-	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/saichler/l8types/go/types/l8api"
@@ -35,15 +35,23 @@ const (
 	LTEQ  ComparatorOperation = "<="       // Less than or equal comparison
 	IN    ComparatorOperation = " in "     // Membership test (value in list)
 	NOTIN ComparatorOperation = " not in " // Negative membership test (value not in list)
+	LIKE  ComparatorOperation = " like "   // Anchored pattern match with % and _ wildcards
+	ILIKE ComparatorOperation = " ilike "  // Case-insensitive anchored pattern match with % and _ wildcards
+	GLOB  ComparatorOperation = " glob "   // Anchored pattern match with * and ? wildcards
+
+	NOTLIKE  ComparatorOperation = " not like "  // Negated like pattern match
+	NOTILIKE ComparatorOperation = " not ilike " // Negated ilike pattern match
+	NOTGLOB  ComparatorOperation = " not glob "  // Negated glob pattern match
 )
 
-// comparators holds the ordered list of comparison operators for parsing.
-// The order is important: multi-character operators must come before single-character
-// ones to ensure correct matching (e.g., ">=" before ">").
+// Escape is the keyword of the escape clause of a like or ilike pattern, e.g. "like '10!%' escape '!'".
+const Escape = "escape"
+
+// comparators holds the list of symbolic and keyword comparison operators
+// recognized by the parser.
 var comparators = make([]ComparatorOperation, 0)
 
-// init initializes the comparators slice with all supported operators
-// in the correct order for parsing.
+// init initializes the comparators slice with all supported operators.
 func init() {
 	comparators = append(comparators, GTEQ)
 	comparators = append(comparators, LTEQ)
@@ -53,6 +61,28 @@ func init() {
 	comparators = append(comparators, LT)
 	comparators = append(comparators, NOTIN)
 	comparators = append(comparators, IN)
+	comparators = append(comparators, LIKE)
+	comparators = append(comparators, ILIKE)
+	comparators = append(comparators, GLOB)
+}
+
+// negatedOperations maps each comparison operator to its opposite, which replaces it
+// in a negated comparison.
+var negatedOperations = map[ComparatorOperation]ComparatorOperation{
+	Eq: Neq, Neq: Eq, GT: LTEQ, LTEQ: GT, LT: GTEQ, GTEQ: LT, IN: NOTIN, NOTIN: IN,
+	LIKE: NOTLIKE, NOTLIKE: LIKE, ILIKE: NOTILIKE, NOTILIKE: ILIKE, GLOB: NOTGLOB, NOTGLOB: GLOB,
+}
+
+// negateComparator returns the comparison with the opposite operator, e.g. "a<=1" for "a>1".
+// A user-defined comparator is negated by not, e.g. "a not within b" for "a within b".
+func negateComparator(this *l8api.L8Comparator) *l8api.L8Comparator {
+	op, ok := negatedOperations[ComparatorOperation(this.Oper)]
+	if !ok && strings.HasPrefix(this.Oper, " not ") {
+		op = ComparatorOperation(this.Oper[len(" not"):])
+	} else if !ok {
+		op = ComparatorOperation(" not" + this.Oper)
+	}
+	return &l8api.L8Comparator{Left: this.Left, Oper: string(op), Right: this.Right}
 }
 
 // StringComparator converts an L8Comparator into its string representation
@@ -79,46 +109,385 @@ func VisualizeComparator(this *l8api.L8Comparator, lvl int) string {
 }
 
 // NewCompare parses a comparison expression string (e.g., "age>18", "name='John'")
-// and creates an L8Comparator. It tries each comparator operator in order until
-// one is found. Returns an error if no valid comparator is found or if the
-// operands contain illegal characters (brackets).
+// and creates an L8Comparator. Returns an error if no valid comparator is found,
+// if the operands contain illegal characters (brackets) or if text follows the comparison.
 func NewCompare(ws string) (*l8api.L8Comparator, error) {
-	for _, op := range comparators {
-		loc := strings.Index(ws, string(op))
-		if loc != -1 {
-			cmp := &l8api.L8Comparator{}
-			cmp.Left = strings.TrimSpace(strings.ToLower(ws[0:loc]))
-			cmp.Right = stripQuotes(strings.TrimSpace(ws[loc+len(op):]))
-			cmp.Oper = string(op)
-			if validateValue(cmp.Left) != "" {
-				return nil, errors.New(validateValue(cmp.Left))
+	s, e := newTokenStream(ws)
+	if e != nil {
+		return nil, e
+	}
+	cmp, e := parseComparator(s)
+	if e != nil {
+		return nil, e
+	}
+	if s.peek().Type != TokenEOF {
+		return nil, s.errorAt(s.peek(), "Unexpected token after comparison", "and", "or", "end of query")
+	}
+	return cmp, nil
+}
+
+// parseComparator parses a single comparison from the token stream.
+// The left operand extends up to the comparison operator and the right operand
+// extends up to the next AND/OR keyword, the closing bracket of the enclosing
+// group or the end of the stream. Brackets are allowed in operands only as the
+// arguments of a function call, e.g. count(*), or to group an arithmetic
+// expression, e.g. (rxBytes + txBytes) * 8.
+func parseComparator(s *tokenStream) (*l8api.L8Comparator, error) {
+	first := s.peek()
+	start := s.pos
+	grouped := false
+	var last *Token
+	for {
+		tok := s.peek()
+		if comparatorOf(s) != "" || isNamedComparator(s, last) {
+			break
+		}
+		if tok.Type == TokenEOF || isConditionOp(tok) || tok.Type == TokenClose && tok.Value == ")" {
+			return nil, s.errorAt(tok, "Cannot find comparator operation in: "+spanOrEmpty(s, first, last), comparatorNames()...)
+		}
+		if tok.Type == TokenOpen && tok.Value == "(" {
+			if !isBracketAllowed(last) {
+				return nil, s.errorAt(tok, "Value "+s.text[first.Pos:tok.End]+" contain illegale brackets.", comparatorNames()...)
+			}
+			grouped = grouped || last == nil || last.Type != TokenIdent
+			call, e := skipCallArguments(s)
+			if e != nil {
+				return nil, e
+			}
+			last = call
+			continue
+		}
+		last = s.next()
+	}
+	op := comparatorOf(s)
+	if op == "" && isKeyword(s.peek(), "not") {
+		op = ComparatorOperation(" not " + strings.ToLower(s.tokens[s.pos+1].Text) + " ")
+	} else if op == "" {
+		op = ComparatorOperation(" " + strings.ToLower(s.peek().Text) + " ")
+	}
+	if last == nil {
+		return nil, s.errorAt(s.peek(), "Missing left operand for comparator "+strings.TrimSpace(string(op)), "property", "value")
+	}
+	if grouped {
+		if e := checkArithmetic(s, start, s.pos); e != nil {
+			return nil, e
+		}
+	}
+	cmp := &l8api.L8Comparator{}
+	cmp.Left = lowerOperand(s, start, s.pos)
+	cmp.Oper = string(op)
+	s.next()
+	if strings.HasPrefix(string(op), " not ") {
+		s.next()
+	}
+
+	first = s.peek()
+	start = s.pos
+	grouped = false
+	last = nil
+	depth := 0
+	for {
+		tok := s.peek()
+		if tok.Type == TokenEOF || depth == 0 && isConditionOp(tok) {
+			break
+		}
+		if tok.Type == TokenOpen {
+			if tok.Value == "(" {
+				if depth > 0 || !isBracketAllowed(last) {
+					return nil, s.errorAt(tok, "Value "+s.text[first.Pos:tok.End]+" contain illegale brackets.", "value")
+				}
+				grouped = grouped || last == nil || last.Type != TokenIdent
+				call, e := skipCallArguments(s)
+				if e != nil {
+					return nil, e
+				}
+				last = call
+				continue
+			}
+			depth++
+		} else if tok.Type == TokenClose {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		last = s.next()
+	}
+	if grouped {
+		if e := checkArithmetic(s, start, s.pos); e != nil {
+			return nil, e
+		}
+	}
+	if last != nil {
+		cmp.Right = operandValue(s, first, last)
+		if e := patternOperand(s, cmp, start, s.pos); e != nil {
+			return nil, e
+		}
+	}
+	return cmp, nil
+}
+
+// patternOperand checks the escape clause of the right operand spanning the tokens from
+// start up to end. Only like and ilike patterns, negated or not, may have one, after the
+// pattern operand, with an escape of at most one character. The right operand is kept
+// with its clause, e.g. "'10!%' escape '!'".
+func patternOperand(s *tokenStream, cmp *l8api.L8Comparator, start, end int) error {
+	op := ComparatorOperation(cmp.Oper)
+	if op == NOTLIKE || op == NOTILIKE || op == NOTGLOB {
+		op = negatedOperations[op]
+	}
+	if op != LIKE && op != ILIKE && op != GLOB {
+		return nil
+	}
+	cmp.Right = patternValue(s, s.tokens[start], s.tokens[end-1], op)
+	clause := -1
+	depth := 0
+	for i := start; i < end && clause == -1; i++ {
+		tok := s.tokens[i]
+		switch {
+		case tok.Type == TokenOpen:
+			depth++
+		case tok.Type == TokenClose:
+			depth--
+		case depth == 0 && tok.Type == TokenIdent && strings.ToLower(tok.Text) == Escape:
+			clause = i
+		}
+	}
+	if clause == -1 {
+		return nil
+	}
+	tok := s.tokens[clause]
+	if op == GLOB {
+		return s.errorAt(tok, "Escape clause is allowed only after a like or ilike pattern", "and", "or", "end of clause")
+	}
+	if clause == start {
+		return s.errorAt(tok, "Missing pattern before the escape clause", "pattern")
+	}
+	escape := s.tokens[clause+1]
+	if escape.Type != TokenString || clause+2 != end || len([]rune(escape.Value)) > 1 {
+		return s.errorAt(escape, "Escape must be a single character string", "'<character>'")
+	}
+	cmp.Right = patternValue(s, s.tokens[start], s.tokens[clause-1], op) + " " + Escape + " '" +
+		strings.ReplaceAll(escape.Value, "'", "''") + "'"
+	return nil
+}
+
+// PatternOperand splits the right operand of a like or ilike comparison into the pattern
+// operand and the escape character of its escape clause. The escape is empty and ok is
+// false if the operand has no escape clause.
+func PatternOperand(right string) (operand, escape string, ok bool) {
+	tokens, e := Tokenize(right)
+	if e != nil || len(tokens) < 4 {
+		return right, "", false
+	}
+	clause := tokens[len(tokens)-3]
+	if clause.Type != TokenIdent || strings.ToLower(clause.Text) != Escape || tokens[len(tokens)-2].Type != TokenString {
+		return right, "", false
+	}
+	return strings.TrimSpace(right[:clause.Pos]), tokens[len(tokens)-2].Value, true
+}
+
+// lowerOperand returns the text of the operand spanning the tokens from start up to end,
+// lowercased except for its quoted strings, such as the arguments of "contains(name, 'ETH')".
+func lowerOperand(s *tokenStream, start, end int) string {
+	buff := strings.Builder{}
+	pos := s.tokens[start].Pos
+	for _, tok := range s.tokens[start:end] {
+		buff.WriteString(s.text[pos:tok.Pos])
+		if tok.Type == TokenString {
+			buff.WriteString(tok.Text)
+		} else {
+			buff.WriteString(strings.ToLower(tok.Text))
+		}
+		pos = tok.End
+	}
+	return buff.String()
+}
+
+// checkArithmetic verifies that the operand spanning the tokens from start up to end,
+// which has bracketed groups, is a valid arithmetic expression with an operator.
+func checkArithmetic(s *tokenStream, start, end int) error {
+	sub := s.subStream(s.tokens[start:end], s.tokens[end-1].End)
+	expr, e := parseSum(sub)
+	if e != nil {
+		return e
+	}
+	if sub.peek().Type != TokenEOF {
+		return sub.errorAt(sub.peek(), "Unexpected token in arithmetic expression", "+", "-", "*", "/", "%")
+	}
+	if expr.Operation == "" {
+		return s.errorAt(s.tokens[start], "Value "+s.span(s.tokens[start], s.tokens[end-1])+" contain illegale brackets.", "value")
+	}
+	return nil
+}
+
+// isBracketAllowed reports whether an operand may have an opening bracket after the token:
+// the arguments of a function call, or a group at the start of the operand or after an
+// arithmetic operator.
+func isBracketAllowed(last *Token) bool {
+	return last == nil || last.Type == TokenIdent || isArithmeticToken(last)
+}
+
+// skipCallArguments consumes the bracketed arguments of a function call, such as the
+// "(*)" of "count(*)", and returns the closing bracket.
+func skipCallArguments(s *tokenStream) (*Token, error) {
+	open := s.next()
+	depth := 1
+	for {
+		tok := s.next()
+		switch {
+		case tok.Type == TokenEOF:
+			column := newSyntaxError(s.text, open, "").Column
+			return nil, s.errorAt(tok, "Missing close bracket for '(' at column "+strconv.Itoa(column), ")")
+		case tok.Type == TokenOpen && tok.Value == "(":
+			depth++
+		case tok.Type == TokenClose && tok.Value == ")":
+			depth--
+			if depth == 0 {
+				return tok, nil
 			}
-			if validateValue(cmp.Right) != "" {
-				return nil, errors.New(validateValue(cmp.Right))
+		}
+	}
+}
+
+// comparatorOf returns the comparison operator at the current position of the stream,
+// or an empty operation if the current token is not a comparison operator.
+func comparatorOf(s *tokenStream) ComparatorOperation {
+	tok := s.peek()
+	if tok.Type == TokenOper {
+		for _, op := range comparators {
+			if tok.Value == string(op) {
+				return op
 			}
-			return cmp, nil
 		}
+		return ""
 	}
-	return nil, errors.New("Cannot find comparator operation in: " + ws)
+	if isKeyword(tok, "not") && s.pos+1 < len(s.tokens) {
+		if op := keywordComparator(s.tokens[s.pos+1]); op != "" && negatedOperations[op] != "" {
+			return negatedOperations[op]
+		}
+	}
+	return keywordComparator(tok)
 }
 
-// validateValue checks if a comparator operand contains illegal bracket characters.
-// Returns an error message if brackets are found, empty string otherwise.
-func validateValue(ws string) string {
-	bo := strings.Index(ws, "(")
-	be := strings.Index(ws, ")")
-	if bo != -1 || be != -1 {
-		return "Value " + ws + " contain illegale brackets."
+// keywordComparator returns the comparison operator written as the keyword token,
+// such as in or like, or an empty operation if the token is not one.
+func keywordComparator(tok *Token) ComparatorOperation {
+	if tok.Type != TokenKeyword {
+		return ""
+	}
+	for _, op := range comparators {
+		if string(op) == " "+tok.Value+" " {
+			return op
+		}
 	}
 	return ""
 }
 
-// stripQuotes removes surrounding double quotes from a string value.
-// If the string is not quoted, it is converted to lowercase.
-// This allows for case-sensitive matching when values are explicitly quoted.
-func stripQuotes(s string) string {
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return s[1 : len(s)-1]
+// isNamedComparator reports whether the current token is the name of a user-defined
+// comparator between two operands, e.g. "within" in "address within '10.0.0.0/8'": a word
+// following the left operand, followed by the right operand, in a comparison that has
+// no built-in comparator. The name may be negated by a preceding not, e.g. "address not
+// within '10.0.0.0/8'".
+func isNamedComparator(s *tokenStream, last *Token) bool {
+	if isKeyword(s.peek(), "not") {
+		return isNamedComparatorAt(s, s.pos+1, last)
+	}
+	return isNamedComparatorAt(s, s.pos, last)
+}
+
+// isNamedComparatorAt reports whether the token at index i of the stream is the name of a
+// user-defined comparator, following the last token of the left operand.
+func isNamedComparatorAt(s *tokenStream, i int, last *Token) bool {
+	tok := s.tokens[i]
+	if tok.Type != TokenIdent || last == nil || IsReserved(tok.Value) {
+		return false
+	}
+	if last.Type != TokenIdent && last.Type != TokenNumber && last.Type != TokenString && last.Type != TokenClose {
+		return false
+	}
+	next := s.tokens[i+1]
+	if next.Type == TokenEOF || next.Type == TokenClose || next.Type == TokenComma || isConditionOp(next) ||
+		next.Type == TokenOpen && next.Value == "(" {
+		return false
+	}
+	depth := 0
+	for _, ahead := range s.tokens[i+1:] {
+		switch {
+		case ahead.Type == TokenEOF || depth == 0 && (isConditionOp(ahead) || ahead.Type == TokenClose):
+			return true
+		case ahead.Type == TokenOpen:
+			depth++
+		case ahead.Type == TokenClose:
+			depth--
+		case isComparisonToken(ahead):
+			return false
+		}
+	}
+	return true
+}
+
+// comparatorNames returns the display names of the comparison operators,
+// used as the expected token set of syntax errors.
+func comparatorNames() []string {
+	names := make([]string, 0, len(comparators))
+	for _, op := range comparators {
+		names = append(names, strings.TrimSpace(string(op)))
+	}
+	return names
+}
+
+// operandValue returns the value of an operand spanning the tokens from first to last.
+// A single quoted string is unescaped; double quotes are removed, while single quotes
+// are kept so the comparators can tell literal strings apart.
+func operandValue(s *tokenStream, first, last *Token) string {
+	if first == last && first.Type == TokenString {
+		if first.Text[0] == '"' {
+			return first.Value
+		}
+		return "'" + first.Value + "'"
+	}
+	return s.span(first, last)
+}
+
+// patternValue returns the pattern operand spanning the tokens like operandValue, except
+// that a quoted pattern keeps the backslashes its raw text has before a wildcard of the
+// operation or a backslash, so that 'a\%b' matches a literal '%' rather than any characters.
+func patternValue(s *tokenStream, first, last *Token, op ComparatorOperation) string {
+	if first != last || first.Type != TokenString || first.Text[0] != '\'' {
+		return operandValue(s, first, last)
+	}
+	wildcards := "%_\\"
+	if op == GLOB {
+		wildcards = "*?\\"
+	}
+	raw := first.Text[1 : len(first.Text)-1]
+	buff := strings.Builder{}
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '\\' && i+1 < len(raw):
+			i++
+			if strings.IndexByte(wildcards, raw[i]) >= 0 {
+				buff.WriteByte(c)
+				buff.WriteByte(raw[i])
+			} else {
+				buff.WriteByte(unescape(raw[i]))
+			}
+		case c == '\'' && i+1 < len(raw) && raw[i+1] == '\'':
+			buff.WriteByte(c)
+			i++
+		default:
+			buff.WriteByte(c)
+		}
+	}
+	return "'" + buff.String() + "'"
+}
+
+// spanOrEmpty returns the text covered by the tokens, or the current token text if last is nil.
+func spanOrEmpty(s *tokenStream, first, last *Token) string {
+	if last == nil {
+		return first.Text
 	}
-	return s
+	return s.span(first, last)
 }
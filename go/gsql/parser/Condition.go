@@ -16,7 +16,6 @@ package parser
 
 import (
 	"bytes"
-	"errors"
 	"strings"
 
 	"github.com/saichler/l8types/go/types/l8api"
@@ -29,6 +28,7 @@ type ConditionOperation string
 const (
 	And                 ConditionOperation = " and " // Logical AND operator
 	Or                  ConditionOperation = " or "  // Logical OR operator
+	Not                 ConditionOperation = "not"   // Logical NOT operator, rewritten into the operand it negates
 	MAX_EXPRESSION_SIZE                    = 999999  // Maximum expression size used as sentinel value
 )
 
@@ -79,95 +79,44 @@ func toString(this *l8api.L8Condition, buff *bytes.Buffer) {
 // Comparisons are parsed left-to-right and linked together in a chain.
 // Returns an error if the condition string contains invalid syntax.
 func NewCondition(ws string) (*l8api.L8Condition, error) {
-	wsLower := strings.ToLower(ws)
-	loc := MAX_EXPRESSION_SIZE
-	var op ConditionOperation
-	and := strings.Index(wsLower, string(And))
-	if and != -1 {
-		loc = and
-		op = And
-	}
-	or := strings.Index(wsLower, string(Or))
-	if or != -1 && or < loc {
-		loc = or
-		op = Or
-	}
-
-	condition := &l8api.L8Condition{}
-	if loc == MAX_EXPRESSION_SIZE {
-		cmpr, e := NewCompare(ws)
-		if e != nil {
-			return nil, e
-		}
-		condition.Comparator = cmpr
-		return condition, nil
-	}
-
-	cmpr, e := NewCompare(ws[0:loc])
+	s, e := newTokenStream(ws)
 	if e != nil {
 		return nil, e
 	}
-
-	condition.Comparator = cmpr
-	condition.Oper = string(op)
-
-	ws = ws[loc+len(op):]
-	next, e := NewCondition(ws)
+	condition, e := parseCondition(s)
 	if e != nil {
 		return nil, e
 	}
-
-	condition.Next = next
+	if s.peek().Type != TokenEOF {
+		return nil, s.errorAt(s.peek(), "Unexpected token after condition", "and", "or", "end of query")
+	}
 	return condition, nil
 }
 
-// getLastConditionOp finds the last occurrence of an AND or OR operator in the string.
-// Returns the operator type, its position, and nil error if found.
-// Returns an error if no operator is found.
-func getLastConditionOp(ws string) (ConditionOperation, int, error) {
-	wsLower := strings.ToLower(ws)
-	loc := -1
-	var op ConditionOperation
-
-	and := strings.LastIndex(wsLower, string(And))
-	if and > loc {
-		op = And
-		loc = and
+// parseCondition parses a chain of comparisons connected by AND/OR operators
+// from the token stream, stopping at the first token that does not continue the chain.
+func parseCondition(s *tokenStream) (*l8api.L8Condition, error) {
+	cmp, e := parseComparator(s)
+	if e != nil {
+		return nil, e
 	}
-
-	or := strings.LastIndex(wsLower, string(Or))
-	if or > loc {
-		op = Or
-		loc = or
+	condition := &l8api.L8Condition{Comparator: cmp}
+	if !isConditionOp(s.peek()) {
+		return condition, nil
 	}
-
-	if loc == -1 {
-		return "", 0, errors.New("No last condition was found.")
+	condition.Oper = conditionOperation(s.next())
+	next, e := parseCondition(s)
+	if e != nil {
+		return nil, e
 	}
-	return op, loc, nil
+	condition.Next = next
+	return condition, nil
 }
 
-// getFirstConditionOp finds the first occurrence of an AND or OR operator in the string.
-// Returns the operator type, its position, and nil error if found.
-// Returns an error if no operator is found.
-func getFirstConditionOp(ws string) (ConditionOperation, int, error) {
-	wsLower := strings.ToLower(ws)
-	loc := MAX_EXPRESSION_SIZE
-	var op ConditionOperation
-	and := strings.Index(wsLower, string(And))
-	if and != -1 {
-		loc = and
-		op = And
-	}
-	or := strings.Index(wsLower, string(Or))
-	if or != -1 && or < loc {
-		loc = or
-		op = Or
-	}
-
-	if loc == MAX_EXPRESSION_SIZE {
-		return "", 0, errors.New("No first condition was found.")
+// conditionOperation returns the ConditionOperation value for an AND/OR keyword token.
+func conditionOperation(tok *Token) string {
+	if isKeyword(tok, "or") {
+		return string(Or)
 	}
-
-	return op, loc, nil
+	return string(And)
 }
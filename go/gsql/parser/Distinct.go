@@ -0,0 +1,80 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Distinct.go supports distinct projections, "select distinct region, model from Device",
+// which return the unique combinations of the values of the selected columns, optionally
+// with the number of objects of each combination: "select distinct region, count(*) from Device".
+// The distinct modifier is carried in l8api.L8Query as a leading "distinct" property.
+package parser
+
+import (
+	"strings"
+
+	"github.com/saichler/l8types/go/types/l8api"
+)
+
+// IsDistinct reports whether the query is a distinct projection.
+func IsDistinct(query *l8api.L8Query) bool {
+	return len(query.Properties) > 0 && query.Properties[0] == Distinct
+}
+
+// DistinctColumns returns the selected columns of a distinct projection, without the
+// distinct modifier and the count of duplicates.
+func DistinctColumns(query *l8api.L8Query) []string {
+	if !IsDistinct(query) {
+		return nil
+	}
+	return query.Properties[1:]
+}
+
+// checkDistinct validates the columns of a distinct projection: properties, and at most
+// one count(*) for the number of duplicates. Distinct projections cannot be grouped or
+// filtered by HAVING.
+func checkDistinct(s *tokenStream, p *parsed, items [][]*Token, cols []string, ext Extensions) error {
+	properties := 0
+	counts := 0
+	for i, col := range cols {
+		if _, ok, _ := ParseWindowFunction(col); ok {
+			return s.errorAt(items[i][0], "Window functions are not supported in distinct projections")
+		}
+		agg, ok, e := parseAggregateCall(s, items[i], ext)
+		if e != nil {
+			return e
+		}
+		switch {
+		case ok && agg.Function == "count" && agg.Field == "*":
+			counts++
+			if counts > 1 {
+				return s.errorAt(items[i][0], "Duplicate count(*) in distinct projection")
+			}
+		case ok:
+			return s.errorAt(items[i][0], "Distinct projections support only count(*): "+col, "count(*)")
+		case strings.TrimSpace(col) == "*":
+			return s.errorAt(items[i][0], "Distinct projections require columns", "column")
+		default:
+			properties++
+		}
+	}
+	if properties == 0 {
+		return s.errorAt(p.select_[0], "Distinct projections require columns", "column")
+	}
+	if p.groupby_ != nil {
+		return s.errorAt(p.select_[0], "Distinct projections cannot be grouped", "select without distinct")
+	}
+	if p.having_ != nil {
+		return s.errorAt(p.select_[0], "Distinct projections do not support having", "where")
+	}
+	return nil
+}
@@ -16,7 +16,7 @@ package parser
 
 import (
 	"bytes"
-	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/saichler/l8types/go/types/l8api"
@@ -79,105 +79,136 @@ func space(lvl int) string {
 }
 
 // parseExpression is the main entry point for parsing WHERE clause expressions.
-// It handles expressions with or without parentheses and delegates to specialized
-// parsing functions based on the structure of the expression.
-func parseExpression(ws string) (*l8api.L8Expression, error) {
-	ws = strings.TrimSpace(ws)
-	bo := getBO(ws)
-	if bo == -1 {
-		return parseNoBrackets(ws)
+// It parses a disjunction of conjunctions, so AND binds tighter than OR, and stops
+// at the first token that does not continue the expression (a closing bracket or
+// the end of the stream).
+func parseExpression(s *tokenStream) (*BoolExpression, error) {
+	operands := make([]*BoolExpression, 0)
+	for {
+		operand, e := parseAnd(s)
+		if e != nil {
+			return nil, e
+		}
+		operands = append(operands, operand)
+		if !isKeyword(s.peek(), "or") {
+			return newBoolOperation(BoolOr, operands), nil
+		}
+		s.next()
 	}
+}
 
-	if bo > 0 {
-		return parseBeforeBrackets(ws, bo)
+// parseAnd parses a conjunction of possibly negated operands.
+func parseAnd(s *tokenStream) (*BoolExpression, error) {
+	operands := make([]*BoolExpression, 0)
+	for {
+		operand, e := parseNot(s)
+		if e != nil {
+			return nil, e
+		}
+		operands = append(operands, operand)
+		if !isKeyword(s.peek(), "and") {
+			return newBoolOperation(BoolAnd, operands), nil
+		}
+		s.next()
 	}
-
-	return parseWithBrackets(ws, bo)
 }
 
-// parseWithBrackets handles expressions that start with an opening parenthesis.
-// It recursively parses the content within the brackets and any following expressions.
-func parseWithBrackets(ws string, bo int) (*l8api.L8Expression, error) {
-	be, e := getBE(ws, bo)
-	if e != nil {
-		return nil, e
+// parseNot parses an operand preceded by any number of NOT operators.
+// Pairs of NOT operators cancel each other.
+func parseNot(s *tokenStream) (*BoolExpression, error) {
+	negated := false
+	for isKeyword(s.peek(), "not") {
+		s.next()
+		negated = !negated
 	}
-	expr := &l8api.L8Expression{}
-	child, e := parseExpression(ws[1:be])
+	operand, e := parseOperand(s)
 	if e != nil {
 		return nil, e
 	}
+	if negated {
+		return &BoolExpression{Operation: BoolNot, Operands: []*BoolExpression{operand}}, nil
+	}
+	return operand, nil
+}
 
-	expr.Child = child
-
-	if be < len(ws)-1 {
-		op, loc, e := getFirstConditionOp(ws[be+1:])
+// parseOperand parses a parenthesized group or a single comparison. A bracket that
+// opens an arithmetic operand, such as "(a + b) * 2 > 10", starts a comparison, and a
+// bool scalar function call on its own, such as "contains(name, 'eth')", is compared with true.
+func parseOperand(s *tokenStream) (*BoolExpression, error) {
+	tok := s.peek()
+	if tok.Type == TokenOpen && tok.Value == "(" && !isArithmeticGroup(s) {
+		child, e := parseWithBrackets(s)
 		if e != nil {
 			return nil, e
 		}
-		expr.AndOr = string(op)
-		next, e := parseExpression(ws[be+1+loc+len(op):])
-		if e != nil {
-			return nil, e
+		return &BoolExpression{Operation: BoolGroup, Operands: []*BoolExpression{child}}, nil
+	}
+	cmp, ok, e := parsePredicateCall(s)
+	if !ok {
+		cmp, e = parseComparator(s)
+	}
+	if e != nil {
+		return nil, e
+	}
+	return &BoolExpression{Operation: BoolCompare, Comparator: cmp}, nil
+}
+
+// isArithmeticGroup reports whether the bracket at the current position of the stream
+// groups part of an arithmetic operand: its closing bracket is followed by an arithmetic
+// or comparison operator, or by the name of a user-defined comparator, rather than by
+// AND/OR or the end of the group.
+func isArithmeticGroup(s *tokenStream) bool {
+	depth := 0
+	for i := s.pos; i < len(s.tokens); i++ {
+		tok := s.tokens[i]
+		switch {
+		case tok.Type == TokenOpen && tok.Value == "(":
+			depth++
+		case tok.Type == TokenClose && tok.Value == ")":
+			depth--
+			if depth == 0 {
+				next := s.tokens[i+1]
+				return isArithmeticToken(next) || isComparisonToken(next) ||
+					isKeyword(next, "not") && (keywordComparator(s.tokens[i+2]) != "" || isNamedComparatorAt(s, i+2, tok)) ||
+					isNamedComparatorAt(s, i+1, tok)
+			}
+		case tok.Type == TokenEOF:
+			return false
 		}
-		expr.Next = next
 	}
-	return expr, nil
+	return false
 }
 
-// parseBeforeBrackets handles expressions where content appears before the first opening parenthesis.
-// It parses the prefix conditions and links them to the bracketed expression that follows.
-func parseBeforeBrackets(ws string, bo int) (*l8api.L8Expression, error) {
-	prefix := ws[0:bo]
-	op, loc, e := getLastConditionOp(prefix)
+// parseWithBrackets parses a parenthesized group, consuming both brackets,
+// and returns the expression inside them.
+func parseWithBrackets(s *tokenStream) (*BoolExpression, error) {
+	open := s.next()
+	child, e := parseExpression(s)
 	if e != nil {
 		return nil, e
 	}
-	expr, e := parseNoBrackets(prefix[0:loc])
-	if e != nil {
-		return nil, e
+	tok := s.peek()
+	if tok.Type == TokenEOF {
+		column := newSyntaxError(s.text, open, "").Column
+		return nil, s.errorAt(tok, "Missing close bracket for '(' at column "+strconv.Itoa(column), ")", "and", "or")
 	}
-	expr.AndOr = string(op)
-	next, e := parseExpression(ws[bo:])
-	if e != nil {
-		return nil, e
+	if tok.Type != TokenClose || tok.Value != ")" {
+		return nil, s.errorAt(tok, "Unexpected token in brackets", ")", "and", "or")
 	}
-	expr.Next = next
-	return expr, nil
+	s.next()
+	return child, nil
 }
 
-// parseNoBrackets handles simple expressions without parentheses.
-// It creates an expression containing just a condition chain.
-func parseNoBrackets(ws string) (*l8api.L8Expression, error) {
-	expr := &l8api.L8Expression{}
-	condition, e := NewCondition(ws)
+// parseWhereExpression parses a complete WHERE or HAVING clause from the token stream
+// and returns it in the linked L8Expression form. Returns an error if any tokens
+// remain after the expression.
+func parseWhereExpression(s *tokenStream) (*l8api.L8Expression, error) {
+	expr, e := parseExpression(s)
 	if e != nil {
 		return nil, e
 	}
-	expr.Condition = condition
-	return expr, nil
-}
-
-// getBO (get Bracket Open) finds the position of the first opening parenthesis in the string.
-// Returns -1 if no opening parenthesis is found.
-func getBO(ws string) int {
-	return strings.Index(ws, "(")
-}
-
-// getBE (get Bracket End) finds the matching closing parenthesis for an opening parenthesis.
-// It counts nested brackets to find the correct matching close bracket.
-// Returns an error if no matching closing bracket is found.
-func getBE(ws string, bo int) (int, error) {
-	count := 0
-	for i := bo; i < len(ws); i++ {
-		if byte(ws[i]) == byte('(') {
-			count++
-		} else if byte(ws[i]) == byte(')') {
-			count--
-		}
-		if count == 0 {
-			return i, nil
-		}
+	if s.peek().Type != TokenEOF {
+		return nil, s.errorAt(s.peek(), "Unexpected token after expression", "and", "or", "end of clause")
 	}
-	return -1, errors.New("Missing close bracket in: " + ws)
+	return expr.L8Expression(), nil
 }
@@ -0,0 +1,219 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Function.go parses the calls of the built-in scalar functions, which transform the
+// values of single objects in SELECT, WHERE, GROUP-BY and SORT-BY, e.g. "lower(name)",
+// "substr(name, 1, 3)" or "cast(port as string)".
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/saichler/l8types/go/types/l8api"
+)
+
+// Scalar functions.
+const (
+	LowerFunction      = "lower"       // lower(s) is s in lowercase
+	UpperFunction      = "upper"       // upper(s) is s in uppercase
+	TrimFunction       = "trim"        // trim(s) is s without leading and trailing white space
+	LenFunction        = "len"         // len(x) is the number of characters of a string, or of values of a collection
+	SubstrFunction     = "substr"      // substr(s, start[, length]) is the substring from the 1-based start
+	ConcatFunction     = "concat"      // concat(x, ...) is the concatenated text of the values
+	ContainsFunction   = "contains"    // contains(s, sub) reports whether s contains sub
+	StartsWithFunction = "starts_with" // starts_with(s, prefix) reports whether s starts with prefix
+	EndsWithFunction   = "ends_with"   // ends_with(s, suffix) reports whether s ends with suffix
+	AbsFunction        = "abs"         // abs(x) is the absolute value of x
+	RoundFunction      = "round"       // round(x[, digits]) is x rounded half away from zero
+	FloorFunction      = "floor"       // floor(x) is the largest integer value not above x
+	CeilFunction       = "ceil"        // ceil(x) is the smallest integer value not below x
+	CoalesceFunction   = "coalesce"    // coalesce(x, ...) is the first value that is not nil
+	CastFunction       = "cast"        // cast(x as type) converts x to int, uint, float, string or bool
+)
+
+// Types of cast.
+const (
+	CastInt    = "int"    // int64
+	CastUint   = "uint"   // uint64
+	CastFloat  = "float"  // float64
+	CastString = "string" // string
+	CastBool   = "bool"   // bool
+)
+
+// castTypes lists the types of cast.
+var castTypes = []string{CastInt, CastUint, CastFloat, CastString, CastBool}
+
+// functionSpec describes the arguments a scalar function accepts.
+type functionSpec struct {
+	args     int  // The number of required arguments
+	optional int  // The number of optional arguments following the required ones
+	variadic bool // Whether any number of arguments may follow the required ones
+	boolean  bool // Whether the function returns a bool, so a call can be a WHERE predicate
+}
+
+// scalarFunctions maps the scalar function names to their arguments.
+var scalarFunctions = map[string]*functionSpec{
+	LowerFunction:      {args: 1},
+	UpperFunction:      {args: 1},
+	TrimFunction:       {args: 1},
+	LenFunction:        {args: 1},
+	SubstrFunction:     {args: 2, optional: 1},
+	ConcatFunction:     {args: 1, variadic: true},
+	ContainsFunction:   {args: 2, boolean: true},
+	StartsWithFunction: {args: 2, boolean: true},
+	EndsWithFunction:   {args: 2, boolean: true},
+	AbsFunction:        {args: 1},
+	RoundFunction:      {args: 1, optional: 1},
+	FloorFunction:      {args: 1},
+	CeilFunction:       {args: 1},
+	CoalesceFunction:   {args: 1, variadic: true},
+	CastFunction:       {args: 2},
+}
+
+// IsScalarFunction reports whether the name is a scalar function.
+func IsScalarFunction(name string) bool {
+	_, ok := scalarFunctions[strings.ToLower(name)]
+	return ok
+}
+
+// IsReserved reports whether the name is a keyword or the name of a built-in function,
+// which user-defined comparators and functions cannot use.
+func IsReserved(name string) bool {
+	name = strings.ToLower(name)
+	return keywords[name] || IsScalarFunction(name) || isBuiltinCall(name) ||
+		name == As || name == Distinct || name == Escape || name == "true" || name == "false"
+}
+
+// isBuiltinCall reports whether the name is a built-in aggregate, window or group-by
+// function, whose calls are kept as operands rather than parsed as scalar function calls.
+func isBuiltinCall(name string) bool {
+	_, aggregate := aggregateFunctions[name]
+	_, window := windowFunctions[name]
+	return aggregate || window || name == BucketFunction || name == DateTruncFunction ||
+		name == RollupFunction || name == CubeFunction
+}
+
+// parseCall parses the bracketed arguments of a scalar function call, the name token having
+// been consumed. The type of cast(x as type) is kept as a quoted literal second argument.
+// The arguments of a function that is not built in, such as a user-defined function, are
+// checked when the query is interpreted.
+func parseCall(s *tokenStream, name *Token) (*ArithmeticExpression, error) {
+	fn := strings.ToLower(name.Text)
+	spec := scalarFunctions[fn]
+	open := s.next()
+	call := &ArithmeticExpression{Function: fn}
+	if tok := s.peek(); tok.Type != TokenClose || tok.Value != ")" {
+		for {
+			arg, e := parseSum(s)
+			if e != nil {
+				return nil, e
+			}
+			call.Operands = append(call.Operands, arg)
+			if fn == CastFunction {
+				if !isWord(s.peek(), As) {
+					return nil, s.errorAt(s.peek(), "Missing type of cast", As)
+				}
+				s.next()
+				typ := s.next()
+				if typ.Type != TokenIdent || !isCastType(strings.ToLower(typ.Text)) {
+					return nil, s.errorAt(typ, "Unknown cast type", castTypes...)
+				}
+				call.Operands = append(call.Operands, &ArithmeticExpression{Operand: strings.ToLower(typ.Text), Literal: true, Quoted: true})
+			}
+			if s.peek().Type != TokenComma {
+				break
+			}
+			s.next()
+		}
+	}
+	if tok := s.peek(); tok.Type != TokenClose || tok.Value != ")" {
+		column := newSyntaxError(s.text, open, "").Column
+		return nil, s.errorAt(tok, "Missing close bracket for '(' at column "+strconv.Itoa(column), ")", ",")
+	}
+	s.next()
+	n := len(call.Operands)
+	if spec == nil {
+		return call, nil
+	}
+	if n < spec.args || !spec.variadic && n > spec.args+spec.optional {
+		expected := strconv.Itoa(spec.args)
+		if spec.variadic {
+			expected += " or more"
+		} else if spec.optional > 0 {
+			expected += " to " + strconv.Itoa(spec.args+spec.optional)
+		}
+		if fn == CastFunction {
+			return nil, s.errorAt(name, "Function cast expects a value and a type, e.g. cast(x as int)")
+		}
+		return nil, s.errorAt(name, "Function "+fn+" expects "+expected+" argument(s)")
+	}
+	if fn == RoundFunction && n == 2 {
+		if _, e := strconv.Atoi(call.Operands[1].Operand); e != nil || !call.Operands[1].Literal {
+			return nil, s.errorAt(name, "Function round expects an integer number of digits")
+		}
+	}
+	return call, nil
+}
+
+// isCastType reports whether the name is a type of cast.
+func isCastType(name string) bool {
+	for _, typ := range castTypes {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePredicateCall parses a call of a scalar function that returns a bool, used as a
+// WHERE predicate on its own, e.g. "contains(name, 'eth')", into the comparison of the
+// call with true. A call of a function that is not built in is taken as a predicate too.
+// Returns false if the stream is not at such a call.
+func parsePredicateCall(s *tokenStream) (*l8api.L8Comparator, bool, error) {
+	name := s.peek()
+	spec, ok := scalarFunctions[strings.ToLower(name.Text)]
+	if name.Type != TokenIdent || ok && !spec.boolean || !ok && isBuiltinCall(strings.ToLower(name.Text)) {
+		return nil, false, nil
+	}
+	if open := s.tokens[s.pos+1]; open.Type != TokenOpen || open.Value != "(" {
+		return nil, false, nil
+	}
+	depth := 0
+	for i := s.pos + 1; i < len(s.tokens); i++ {
+		tok := s.tokens[i]
+		switch {
+		case tok.Type == TokenOpen && tok.Value == "(":
+			depth++
+		case tok.Type == TokenClose && tok.Value == ")":
+			depth--
+			if depth == 0 {
+				next := s.tokens[i+1]
+				if next.Type != TokenEOF && !isConditionOp(next) && (next.Type != TokenClose || next.Value != ")") {
+					return nil, false, nil
+				}
+				i = len(s.tokens)
+			}
+		case tok.Type == TokenEOF:
+			return nil, false, nil
+		}
+	}
+	start := s.pos
+	s.next()
+	if _, e := parseCall(s, name); e != nil {
+		return nil, true, e
+	}
+	return &l8api.L8Comparator{Left: lowerOperand(s, start, s.pos), Oper: string(Eq), Right: "true"}, true, nil
+}
@@ -0,0 +1,397 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// GroupBy.go parses the items of a GROUP-BY clause: plain properties, the time
+// bucketing expressions bucket(field, width) and date_trunc('unit', field) that group
+// timestamps into fixed or calendar intervals, and the rollup and cube grouping sets
+// that add subtotal rows.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Grouping functions of a GROUP-BY clause.
+const (
+	BucketFunction    = "bucket"     // bucket(field, width[, s|ms][, fill])
+	DateTruncFunction = "date_trunc" // date_trunc('unit', field[, s|ms][, fill])
+)
+
+// Grouping set functions of a GROUP-BY clause.
+const (
+	RollupFunction = "rollup" // rollup(a, b, ...) groups by a, b, ..., then by each shorter prefix, down to the grand total
+	CubeFunction   = "cube"   // cube(a, b, ...) groups by every subset of a, b, ...
+)
+
+// MaxGroupingColumns is the largest number of group-by columns of a query with grouping sets.
+const MaxGroupingColumns = 62
+
+// MaxGroupingSets is the largest number of grouping sets of a query.
+const MaxGroupingSets = 4096
+
+// Options of the grouping functions.
+const (
+	EpochSeconds = "s"    // Numeric timestamps are seconds since the epoch
+	EpochMillis  = "ms"   // Numeric timestamps are milliseconds since the epoch
+	FillGaps     = "fill" // Empty buckets between the first and last bucket are added
+)
+
+// truncUnits lists the units of date_trunc.
+var truncUnits = map[string]bool{"second": true, "minute": true, "hour": true, "day": true, "week": true,
+	"month": true, "year": true}
+
+// GroupExpression is an item of a GROUP-BY clause: a property, a grouping function of a property,
+// or a computed expression such as "lower(region)".
+type GroupExpression struct {
+	Function   string                // Empty for a property or a computed expression, or BucketFunction or DateTruncFunction
+	Field      string                // The property path, empty for a computed expression
+	Expression *ArithmeticExpression // The arithmetic expression or scalar function call of a computed expression
+	Width      time.Duration         // The bucket width of bucket
+	Unit       string                // The unit of date_trunc: second, minute, hour, day, week, month or year
+	Epoch      string                // The unit of numeric timestamps, EpochSeconds or EpochMillis, empty to detect by magnitude
+	Fill       bool                  // Whether empty buckets between the first and last bucket are added
+	width      string                // The bucket width as written
+}
+
+// ParseGroupExpression parses an item of a GROUP-BY clause, such as "region",
+// "bucket(timestamp, 5m)", "date_trunc('hour', timestamp, ms, fill)" or "lower(region)".
+// Bucket widths are Go durations, such as 30s, 5m or 1h30m, or a number of days or weeks such as 1d or 2w.
+func ParseGroupExpression(text string) (*GroupExpression, error) {
+	s, e := newTokenStream(text)
+	if e != nil {
+		return nil, e
+	}
+	if len(s.tokens) == 1 {
+		return nil, s.errorAt(s.peek(), "Missing group-by column", "property")
+	}
+	return parseGroupExpression(s, s.tokens[:len(s.tokens)-1])
+}
+
+// parseGroupExpression parses the tokens of an item of a GROUP-BY clause.
+// Errors are reported as a *SyntaxError located at the offending token.
+func parseGroupExpression(s *tokenStream, tokens []*Token) (*GroupExpression, error) {
+	if expr, ok, _ := parseArithmetic(s.subStream(tokens, tokens[len(tokens)-1].End)); ok {
+		return &GroupExpression{Expression: expr}, nil
+	}
+	if tokens[0].Type != TokenIdent {
+		return nil, s.errorAt(tokens[0], "Invalid group-by column", "property")
+	}
+	if len(tokens) == 1 {
+		return &GroupExpression{Field: tokens[0].Text}, nil
+	}
+	if tokens[1].Value != "(" {
+		return nil, s.errorAt(tokens[1], "Invalid group-by column", ",")
+	}
+	fn := strings.ToLower(tokens[0].Text)
+	if fn != BucketFunction && fn != DateTruncFunction {
+		return nil, s.errorAt(tokens[0], "Unknown group-by function "+fn, BucketFunction, DateTruncFunction)
+	}
+	end := closeOf(tokens, 1)
+	if end == -1 {
+		return nil, s.errorAt(tokens[1], "Missing close bracket of group-by function "+fn, ")")
+	}
+	if end != len(tokens)-1 {
+		return nil, s.errorAt(tokens[end+1], "Unexpected token after group-by function "+fn, ",")
+	}
+	args, e := clauseItems(s, tokens[2:end])
+	if e != nil {
+		return nil, e
+	}
+	if len(args) < 2 {
+		return nil, s.errorAt(tokens[end], "Group-by function "+fn+" expects at least 2 arguments", ",")
+	}
+	expr := &GroupExpression{Function: fn}
+	field := args[0]
+	if fn == BucketFunction {
+		arg := args[1]
+		if len(arg) > 1 {
+			return nil, s.errorAt(arg[0], "Invalid bucket width", "duration")
+		}
+		width, e := parseWidth(arg[0].Text)
+		if e != nil {
+			return nil, s.errorAt(arg[0], e.Error(), "duration")
+		}
+		expr.width = arg[0].Text
+		expr.Width = width
+	} else {
+		arg := args[0]
+		expr.Unit = strings.ToLower(arg[0].Value)
+		if len(arg) > 1 || !truncUnits[expr.Unit] || (arg[0].Type != TokenString && arg[0].Type != TokenIdent) {
+			return nil, s.errorAt(arg[0], "Unknown date_trunc unit", "second", "minute", "hour", "day", "week", "month", "year")
+		}
+		field = args[1]
+	}
+	if field[0].Type != TokenIdent || len(field) > 1 {
+		return nil, s.errorAt(field[0], "Invalid field in group-by function", "property")
+	}
+	expr.Field = field[0].Text
+	for _, option := range args[2:] {
+		switch strings.ToLower(clauseText(s, option)) {
+		case EpochSeconds, EpochMillis:
+			if expr.Epoch != "" {
+				return nil, s.errorAt(option[0], "Duplicate timestamp unit in group-by function "+fn)
+			}
+			expr.Epoch = strings.ToLower(option[0].Text)
+		case FillGaps:
+			expr.Fill = true
+		default:
+			return nil, s.errorAt(option[0], "Unknown option in group-by function "+fn, EpochSeconds, EpochMillis, FillGaps)
+		}
+	}
+	return expr, nil
+}
+
+// parseWidth parses a bucket width.
+func parseWidth(text string) (time.Duration, error) {
+	var width time.Duration
+	var e error
+	if strings.HasSuffix(text, "d") || strings.HasSuffix(text, "w") {
+		var n int
+		n, e = strconv.Atoi(text[:len(text)-1])
+		width = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(text, "w") {
+			width *= 7
+		}
+	} else {
+		width, e = time.ParseDuration(text)
+	}
+	if e != nil || width <= 0 {
+		return 0, errors.New("Invalid bucket width '" + text + "'")
+	}
+	return width, nil
+}
+
+// String returns the canonical text of the expression, which is also the name of its
+// column in the aggregate rows, e.g. "bucket(timestamp, 5m)" or "date_trunc('hour', timestamp)".
+func (this *GroupExpression) String() string {
+	if this.Expression != nil {
+		return this.Expression.String()
+	}
+	if this.Function == "" {
+		return this.Field
+	}
+	buff := strings.Builder{}
+	buff.WriteString(this.Function)
+	buff.WriteString("(")
+	if this.Function == BucketFunction {
+		buff.WriteString(this.Field)
+		buff.WriteString(", ")
+		buff.WriteString(this.width)
+	} else {
+		buff.WriteString("'")
+		buff.WriteString(this.Unit)
+		buff.WriteString("', ")
+		buff.WriteString(this.Field)
+	}
+	if this.Epoch != "" {
+		buff.WriteString(", ")
+		buff.WriteString(this.Epoch)
+	}
+	if this.Fill {
+		buff.WriteString(", ")
+		buff.WriteString(FillGaps)
+	}
+	buff.WriteString(")")
+	return buff.String()
+}
+
+// GroupItem is an item of a GROUP-BY clause: a column, or a rollup or cube of columns.
+type GroupItem struct {
+	Function string             // Empty for a single column, or RollupFunction or CubeFunction
+	Columns  []*GroupExpression // The columns of the item
+	token    *Token             // The first token of the item, where its errors are reported
+}
+
+// ParseGroupItem parses an item of a GROUP-BY clause, such as "region", "bucket(timestamp, 5m)"
+// or "rollup(region, site)". The columns of rollup and cube may be time bucketing expressions.
+func ParseGroupItem(text string) (*GroupItem, error) {
+	s, e := newTokenStream(text)
+	if e != nil {
+		return nil, e
+	}
+	if len(s.tokens) == 1 {
+		return nil, s.errorAt(s.peek(), "Missing group-by column", "property")
+	}
+	return parseGroupItem(s, s.tokens[:len(s.tokens)-1])
+}
+
+// parseGroupItem parses the tokens of an item of a GROUP-BY clause. Errors of a malformed
+// rollup or cube are reported as a *SyntaxError located at its rollup or cube token.
+func parseGroupItem(s *tokenStream, tokens []*Token) (*GroupItem, error) {
+	fn := ""
+	if len(tokens) > 1 && tokens[0].Type == TokenIdent && tokens[1].Type == TokenOpen {
+		fn = strings.ToLower(tokens[0].Text)
+	}
+	if fn != RollupFunction && fn != CubeFunction {
+		expr, e := parseGroupExpression(s, tokens)
+		if e != nil {
+			return nil, e
+		}
+		return &GroupItem{Columns: []*GroupExpression{expr}, token: tokens[0]}, nil
+	}
+	end := closeOf(tokens, 1)
+	if end != len(tokens)-1 {
+		return nil, s.errorAt(tokens[0], "Invalid group-by "+fn, fn+"(column, ...)")
+	}
+	args, e := clauseItems(s, tokens[2:end])
+	if e != nil {
+		return nil, e
+	}
+	if len(args) == 0 {
+		return nil, s.errorAt(tokens[0], "Group-by "+fn+" expects at least 1 column", "property")
+	}
+	item := &GroupItem{Function: fn, token: tokens[0]}
+	for _, arg := range args {
+		expr, e := parseGroupExpression(s, arg)
+		if e != nil {
+			return nil, e
+		}
+		item.Columns = append(item.Columns, expr)
+	}
+	return item, nil
+}
+
+// String returns the canonical text of the item, e.g. "rollup(region, bucket(timestamp, 5m))".
+func (this *GroupItem) String() string {
+	if this.Function == "" {
+		return this.Columns[0].String()
+	}
+	columns := make([]string, 0, len(this.Columns))
+	for _, column := range this.Columns {
+		columns = append(columns, column.String())
+	}
+	return this.Function + "(" + strings.Join(columns, ", ") + ")"
+}
+
+// sets returns the grouping sets of the item, as a list of flags telling for each of its
+// columns whether it is grouped by (true) or rolled up (false). The number of sets is
+// checked against MaxGroupingColumns and MaxGroupingSets before they are built, so a
+// cube over many columns is rejected without enumerating its sets.
+func (this *GroupItem) sets(s *tokenStream) ([][]bool, error) {
+	n := len(this.Columns)
+	if this.Function == RollupFunction || this.Function == CubeFunction {
+		if n > MaxGroupingColumns {
+			return nil, s.errorAt(this.token, "Grouping sets support at most "+strconv.Itoa(MaxGroupingColumns)+" group-by columns")
+		}
+		count := n + 1
+		if this.Function == CubeFunction {
+			count = 1 << n
+		}
+		if count > MaxGroupingSets {
+			return nil, s.errorAt(this.token, "Group-by exceeds "+strconv.Itoa(MaxGroupingSets)+" grouping sets")
+		}
+	}
+	sets := make([][]bool, 0)
+	switch this.Function {
+	case RollupFunction:
+		for kept := n; kept >= 0; kept-- {
+			set := make([]bool, n)
+			for i := 0; i < kept; i++ {
+				set[i] = true
+			}
+			sets = append(sets, set)
+		}
+	case CubeFunction:
+		// Count the rolled up columns in binary, the first column being the most significant
+		for mask := 0; mask < 1<<n; mask++ {
+			set := make([]bool, n)
+			for i := 0; i < n; i++ {
+				set[i] = mask&(1<<(n-1-i)) == 0
+			}
+			sets = append(sets, set)
+		}
+	default:
+		sets = append(sets, []bool{true})
+	}
+	return sets, nil
+}
+
+// GroupingSets expands the items of a GROUP-BY clause into the group-by columns and the
+// grouping sets over them. Each set flags, for each column, whether it is grouped by (true)
+// or rolled up (false). The sets of several items are combined, so "a, rollup(b, c)" has
+// the sets (a, b, c), (a, b) and (a). Without rollup or cube there is a single set of all
+// the columns. Duplicate sets are dropped, and a column may appear only once.
+func GroupingSets(items []string) ([]*GroupExpression, [][]bool, error) {
+	s, e := newTokenStream(strings.Join(items, ", "))
+	if e != nil {
+		return nil, nil, e
+	}
+	itemTokens, e := clauseItems(s, s.tokens[:len(s.tokens)-1])
+	if e != nil {
+		return nil, nil, e
+	}
+	groupItems := make([]*GroupItem, 0, len(itemTokens))
+	for _, tokens := range itemTokens {
+		item, e := parseGroupItem(s, tokens)
+		if e != nil {
+			return nil, nil, e
+		}
+		groupItems = append(groupItems, item)
+	}
+	return groupingSets(s, groupItems)
+}
+
+// groupingSets expands the parsed items of a GROUP-BY clause, reporting a duplicate column
+// or too many grouping sets as a *SyntaxError located at the offending item.
+func groupingSets(s *tokenStream, items []*GroupItem) ([]*GroupExpression, [][]bool, error) {
+	columns := make([]*GroupExpression, 0)
+	sets := [][]bool{{}}
+	seen := make(map[string]bool)
+	var grouping *Token
+	for _, item := range items {
+		for _, column := range item.Columns {
+			name := column.String()
+			if seen[name] {
+				return nil, nil, s.errorAt(item.token, "Duplicate group-by column: "+name)
+			}
+			seen[name] = true
+			columns = append(columns, column)
+		}
+		if item.Function != "" && grouping == nil {
+			grouping = item.token
+		}
+		itemSets, e := item.sets(s)
+		if e != nil {
+			return nil, nil, e
+		}
+		if len(sets)*len(itemSets) > MaxGroupingSets {
+			return nil, nil, s.errorAt(item.token, "Group-by exceeds "+strconv.Itoa(MaxGroupingSets)+" grouping sets")
+		}
+		combined := make([][]bool, 0, len(sets)*len(itemSets))
+		for _, set := range sets {
+			for _, itemSet := range itemSets {
+				combined = append(combined, append(append([]bool{}, set...), itemSet...))
+			}
+		}
+		sets = combined
+	}
+	if len(sets) > 1 && len(columns) > MaxGroupingColumns {
+		return nil, nil, s.errorAt(grouping, "Grouping sets support at most "+strconv.Itoa(MaxGroupingColumns)+" group-by columns")
+	}
+	unique := make([][]bool, 0, len(sets))
+	keys := make(map[string]bool)
+	for _, set := range sets {
+		key := fmt.Sprint(set)
+		if !keys[key] {
+			keys[key] = true
+			unique = append(unique, set)
+		}
+	}
+	return columns, unique, nil
+}
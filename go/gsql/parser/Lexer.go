@@ -0,0 +1,313 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Lexer.go splits L8QL query text into tokens. Clause splitting and WHERE/HAVING
+// parsing work on tokens, so keywords that appear inside quoted literals or as part
+// of property names never change the parse.
+package parser
+
+import (
+	"bytes"
+	"strings"
+)
+
+// TokenType identifies the lexical category of a Token.
+type TokenType int
+
+// Token categories produced by the lexer.
+const (
+	TokenEOF     TokenType = iota // End of the query text
+	TokenIdent                    // Identifier or property path (e.g. name, addresses.country)
+	TokenKeyword                  // Reserved word (select, from, where, and, or, not, in, ...)
+	TokenString                   // Quoted string literal ('...' or "...")
+	TokenNumber                   // Numeric literal (e.g. 42, 3.14, 1e-3)
+	TokenOper                     // Operator or punctuation (=, !=, >=, *, -, ...)
+	TokenOpen                     // Opening bracket: ( or [
+	TokenClose                    // Closing bracket: ) or ]
+	TokenComma                    // Comma separator
+)
+
+// Token is a single lexical unit of a query, with its position in the original text.
+type Token struct {
+	Type  TokenType // The token category
+	Text  string    // The raw text of the token as it appears in the query
+	Value string    // The decoded value; unquoted and unescaped for strings, lowercased for keywords
+	Pos   int       // Byte offset of the first character of the token
+	End   int       // Byte offset just past the last character of the token
+}
+
+// keywords holds the reserved words recognized by the lexer: the clause keywords
+// and the logical, membership and pattern operators.
+var keywords = make(map[string]bool)
+
+// hyphenated lists the keywords that contain a hyphen, so the lexer can join
+// their parts into a single keyword token.
+var hyphenated = []string{SortBy, GroupBy, MatchCase}
+
+// init registers the clause keywords and operator keywords.
+func init() {
+	for _, word := range words {
+		keywords[word] = true
+	}
+	for _, word := range []string{"and", "or", "not", "in", "like", "ilike", "glob"} {
+		keywords[word] = true
+	}
+}
+
+// multiCharOpers lists the two character operators, checked before single characters.
+var multiCharOpers = []string{">=", "<=", "!="}
+
+// Tokenize splits the query text into tokens. The returned slice always ends
+// with a TokenEOF token. Returns an error for unterminated string literals.
+func Tokenize(text string) ([]*Token, error) {
+	tokens := make([]*Token, 0)
+	i := 0
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			tok, e := lexString(text, i)
+			if e != nil {
+				return nil, e
+			}
+			tokens = append(tokens, tok)
+			i = tok.End
+		case isDigit(c) || (c == '.' && i+1 < len(text) && isDigit(text[i+1])):
+			tok := lexWord(text, i)
+			tokens = append(tokens, tok)
+			i = tok.End
+		case isIdentStart(c):
+			tok := lexWord(text, i)
+			tokens = append(tokens, tok)
+			i = tok.End
+		case c == '(' || c == '[':
+			tokens = append(tokens, &Token{Type: TokenOpen, Text: text[i : i+1], Value: text[i : i+1], Pos: i, End: i + 1})
+			i++
+		case c == ')' || c == ']':
+			tokens = append(tokens, &Token{Type: TokenClose, Text: text[i : i+1], Value: text[i : i+1], Pos: i, End: i + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, &Token{Type: TokenComma, Text: ",", Value: ",", Pos: i, End: i + 1})
+			i++
+		default:
+			tok := lexOper(text, i)
+			tokens = append(tokens, tok)
+			i = tok.End
+		}
+	}
+	tokens = append(tokens, &Token{Type: TokenEOF, Pos: len(text), End: len(text)})
+	return tokens, nil
+}
+
+// lexString scans a quoted string literal starting at pos. A backslash escapes
+// the next character and a doubled quote character stands for a single quote.
+func lexString(text string, pos int) (*Token, error) {
+	quote := text[pos]
+	buff := bytes.Buffer{}
+	i := pos + 1
+	for i < len(text) {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) {
+			buff.WriteByte(unescape(text[i+1]))
+			i += 2
+			continue
+		}
+		if c == quote {
+			if i+1 < len(text) && text[i+1] == quote {
+				buff.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return &Token{Type: TokenString, Text: text[pos : i+1], Value: buff.String(), Pos: pos, End: i + 1}, nil
+		}
+		buff.WriteByte(c)
+		i++
+	}
+	return nil, newSyntaxError(text, &Token{Type: TokenString, Text: text[pos:], Pos: pos, End: len(text)},
+		"Unterminated string literal", string(quote))
+}
+
+// unescape translates the character following a backslash in a string literal.
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	}
+	return c
+}
+
+// lexWord scans an identifier, keyword or number starting at pos.
+// Words may contain letters, digits, '_' and '.', so property paths such as
+// "mymodelslice.mystring" and values such as "10.0.0.1" are a single token.
+func lexWord(text string, pos int) *Token {
+	i := pos
+	for i < len(text) && isWordChar(text[i]) {
+		i++
+	}
+	word := text[pos:i]
+	// Scientific notation, e.g. 1e-5 or 2.5E+3
+	if len(word) > 1 && (word[len(word)-1] == 'e' || word[len(word)-1] == 'E') && isNumber(word[:len(word)-1]) && i+1 < len(text) &&
+		(text[i] == '-' || text[i] == '+') && isDigit(text[i+1]) {
+		i++
+		for i < len(text) && isDigit(text[i]) {
+			i++
+		}
+		word = text[pos:i]
+	}
+	lower := strings.ToLower(word)
+	for _, kw := range hyphenated {
+		if strings.HasPrefix(kw, lower+"-") && strings.HasPrefix(strings.ToLower(text[pos:]), kw) {
+			end := pos + len(kw)
+			if end == len(text) || !isWordChar(text[end]) {
+				return &Token{Type: TokenKeyword, Text: text[pos:end], Value: kw, Pos: pos, End: end}
+			}
+		}
+	}
+	tok := &Token{Text: word, Value: word, Pos: pos, End: i}
+	switch {
+	case keywords[lower]:
+		tok.Type = TokenKeyword
+		tok.Value = lower
+	case isNumber(word):
+		tok.Type = TokenNumber
+	default:
+		tok.Type = TokenIdent
+	}
+	return tok
+}
+
+// lexOper scans an operator starting at pos, preferring two character operators.
+func lexOper(text string, pos int) *Token {
+	for _, op := range multiCharOpers {
+		if strings.HasPrefix(text[pos:], op) {
+			return &Token{Type: TokenOper, Text: op, Value: op, Pos: pos, End: pos + len(op)}
+		}
+	}
+	return &Token{Type: TokenOper, Text: text[pos : pos+1], Value: text[pos : pos+1], Pos: pos, End: pos + 1}
+}
+
+// isNumber reports whether the word is a decimal number, optionally with a
+// fraction and a signed exponent.
+func isNumber(word string) bool {
+	digits := false
+	dot := false
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case isDigit(c):
+			digits = true
+		case c == '.' && !dot:
+			dot = true
+		case (c == 'e' || c == 'E') && digits && i > 0:
+			rest := strings.TrimLeft(word[i+1:], "+-")
+			if rest == "" || len(word[i+1:])-len(rest) > 1 {
+				return false
+			}
+			for j := 0; j < len(rest); j++ {
+				if !isDigit(rest[j]) {
+					return false
+				}
+			}
+			return true
+		default:
+			return false
+		}
+	}
+	return digits
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isWordChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.'
+}
+
+// tokenStream is a cursor over a sequence of tokens that always ends with a TokenEOF token.
+type tokenStream struct {
+	text   string   // The original query text the tokens were produced from
+	tokens []*Token // The tokens, terminated by a TokenEOF token
+	pos    int      // Index of the current token
+}
+
+// newTokenStream tokenizes the text and returns a stream positioned at the first token.
+func newTokenStream(text string) (*tokenStream, error) {
+	tokens, e := Tokenize(text)
+	if e != nil {
+		return nil, e
+	}
+	return &tokenStream{text: text, tokens: tokens}, nil
+}
+
+// subStream returns a stream over a subset of tokens from the same text,
+// terminated by a TokenEOF token positioned at the end of the subset.
+func (this *tokenStream) subStream(tokens []*Token, end int) *tokenStream {
+	sub := make([]*Token, 0, len(tokens)+1)
+	sub = append(sub, tokens...)
+	sub = append(sub, &Token{Type: TokenEOF, Pos: end, End: end})
+	return &tokenStream{text: this.text, tokens: sub}
+}
+
+// peek returns the current token without consuming it.
+func (this *tokenStream) peek() *Token {
+	return this.tokens[this.pos]
+}
+
+// next consumes and returns the current token. The TokenEOF token is never consumed.
+func (this *tokenStream) next() *Token {
+	tok := this.tokens[this.pos]
+	if tok.Type != TokenEOF {
+		this.pos++
+	}
+	return tok
+}
+
+// span returns the original query text covered by the tokens from first to last, inclusive.
+func (this *tokenStream) span(first, last *Token) string {
+	if last.End <= first.Pos {
+		return ""
+	}
+	return this.text[first.Pos:last.End]
+}
+
+// errorAt returns a SyntaxError located at the given token.
+func (this *tokenStream) errorAt(tok *Token, message string, expected ...string) error {
+	return newSyntaxError(this.text, tok, message, expected...)
+}
+
+// isKeyword reports whether the token is the given keyword.
+func isKeyword(tok *Token, keyword string) bool {
+	return tok.Type == TokenKeyword && tok.Value == keyword
+}
+
+// isConditionOp reports whether the token is a logical AND/OR keyword.
+func isConditionOp(tok *Token) bool {
+	return isKeyword(tok, "and") || isKeyword(tok, "or")
+}
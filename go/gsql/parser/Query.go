@@ -20,9 +20,11 @@ limitations under the License.
 // The parser supports the following clauses:
 //   - SELECT: Specify which properties/columns to retrieve (comma-separated)
 //   - FROM: Specify the root type to query
-//   - WHERE: Filter conditions with comparators (=, !=, >, <, >=, <=, in, not in)
-//   - SORT-BY: Property to sort results by
-//   - DESCENDING/ASCENDING: Sort order modifiers
+//   - WHERE: Filter conditions with comparators (=, !=, >, <, >=, <=, in, not in) and
+//     user-defined comparators
+//   - SORT-BY: Comma separated properties to sort results by, each with an optional
+//     asc/desc direction and nulls first/last order
+//   - DESCENDING/ASCENDING: Sort order modifiers for keys without a direction
 //   - LIMIT: Maximum number of results (up to 1000)
 //   - PAGE: Pagination offset
 //   - MATCH-CASE: Enable case-sensitive matching
@@ -35,35 +37,47 @@ package parser
 
 import (
 	"bytes"
-	"github.com/saichler/l8types/go/ifs"
-	"github.com/saichler/l8types/go/types/l8api"
-
 	"strconv"
 	"strings"
+
+	"github.com/saichler/l8types/go/ifs"
+	"github.com/saichler/l8types/go/types/l8api"
 )
 
 // PQuery is the parsed query wrapper that holds the parsed L8Query protobuf message
 // along with a logger for error reporting during parsing.
 type PQuery struct {
-	log    ifs.ILogger
-	pquery l8api.L8Query
+	log      ifs.ILogger
+	pquery   l8api.L8Query
+	sortKeys []*SortKey
+	ext      Extensions
+}
+
+// Extensions tells the parser about user-defined extensions of the language. The calls of
+// user-defined scalar functions and comparators parse without it, as their arguments are
+// checked when the query is interpreted, while the calls of user-defined aggregate
+// functions must be told apart from scalar function calls in the SELECT clause.
+type Extensions interface {
+	// IsAggregate reports whether the lowercase name is a user-defined aggregate function.
+	IsAggregate(name string) bool
 }
 
-// parsed is an internal struct that holds the raw parsed values extracted from
+// parsed is an internal struct that holds the tokens of each clause extracted from
 // the query string before they are converted to their final typed representations.
 type parsed struct {
-	select_     []string
-	from_       string
-	where_      string
-	sortby_     string
-	descending_ string
-	ascending_  string
-	limit_      string
-	page_       string
-	matchcase_  string
-	mapreduce_  string
-	groupby_    string
-	having_     string
+	select_     []*Token
+	from_       []*Token
+	where_      []*Token
+	sortby_     []*Token
+	descending_ []*Token
+	ascending_  []*Token
+	limit_      []*Token
+	page_       []*Token
+	matchcase_  []*Token
+	mapreduce_  []*Token
+	groupby_    []*Token
+	having_     []*Token
+	follow      map[string]*Token // The token following each clause, used to locate empty clause errors
 }
 
 // Query clause keywords used for parsing L8QL query strings.
@@ -91,13 +105,25 @@ func (this *PQuery) Query() *l8api.L8Query {
 	return &this.pquery
 }
 
+// SortKeys returns the keys of the sort-by clause, or nil if there is none.
+func (this *PQuery) SortKeys() []*SortKey {
+	return this.sortKeys
+}
+
 // NewQuery parses an L8QL query string and returns a new PQuery instance.
 // The query string should follow L8QL syntax with clauses like SELECT, FROM, WHERE, etc.
-// Returns an error if the query string contains invalid syntax or values.
+// Returns a *SyntaxError locating the problem if the query string contains invalid syntax or values.
 func NewQuery(query string, log ifs.ILogger) (*PQuery, error) {
+	return NewQueryWithExtensions(query, log, nil)
+}
+
+// NewQueryWithExtensions parses an L8QL query string like NewQuery, detecting the calls of
+// the user-defined aggregate functions of the extensions. The extensions may be nil.
+func NewQueryWithExtensions(query string, log ifs.ILogger, ext Extensions) (*PQuery, error) {
 	cwql := &PQuery{}
 	cwql.pquery.Text = query
 	cwql.log = log
+	cwql.ext = ext
 	e := cwql.init()
 	return cwql, e
 }
@@ -124,139 +150,350 @@ func TrimAndLowerNoKeys(sql string) string {
 	return buff.String()
 }
 
-func (this *PQuery) split() *parsed {
-	sql := TrimAndLowerNoKeys(this.pquery.Text)
-	data := &parsed{}
-	data.select_ = getSplitTag(sql, this.pquery.Text, Select)
-	data.from_ = getTag(sql, this.pquery.Text, From)
-	data.where_ = getTag(sql, this.pquery.Text, Where)
-	data.descending_ = getBoolTag(sql, Descending)
-	data.ascending_ = getBoolTag(sql, Ascending)
-	data.limit_ = getTag(sql, this.pquery.Text, Limit)
-	data.page_ = getTag(sql, this.pquery.Text, Page)
-	data.sortby_ = getTag(sql, this.pquery.Text, SortBy)
-	data.matchcase_ = getBoolTag(sql, MatchCase)
-	data.mapreduce_ = getBoolTag(sql, MapReduce)
-	data.groupby_ = getTag(sql, this.pquery.Text, GroupBy)
-	data.having_ = getTag(sql, this.pquery.Text, Having)
-	return data
+// split tokenizes the query text and distributes the tokens among the clauses.
+// A clause keyword starts a new clause only when it appears outside brackets and
+// is not an operand of a comparison, so keywords inside literals and property
+// names never change the parse.
+func (this *PQuery) split() (*tokenStream, *parsed, error) {
+	s, e := newTokenStream(this.pquery.Text)
+	if e != nil {
+		return nil, nil, e
+	}
+	data := &parsed{follow: make(map[string]*Token)}
+	var current *[]*Token
+	var currentKeyword string
+	depth := 0
+	for i, tok := range s.tokens {
+		if tok.Type == TokenEOF {
+			break
+		}
+		if tok.Type == TokenOpen {
+			depth++
+		} else if tok.Type == TokenClose {
+			depth--
+		}
+		if depth == 0 && isClauseKeyword(s, i) {
+			clause := data.clause(tok.Value)
+			if *clause != nil {
+				return nil, nil, s.errorAt(tok, "Duplicate "+tok.Value+" clause")
+			}
+			*clause = make([]*Token, 0)
+			current = clause
+			currentKeyword = tok.Value
+			data.follow[currentKeyword] = s.tokens[i+1]
+			continue
+		}
+		if tok.Type == TokenKeyword && !isOperatorKeyword(tok.Value) {
+			// A clause keyword used as a property name or value
+			tok.Type = TokenIdent
+			tok.Value = tok.Text
+		}
+		if current == nil {
+			return nil, nil, s.errorAt(tok, "Unexpected token before the first clause", words...)
+		}
+		*current = append(*current, tok)
+		data.follow[currentKeyword] = s.tokens[i+1]
+	}
+	return s, data, nil
+}
+
+// clause returns a pointer to the token slice of the given clause keyword.
+func (this *parsed) clause(keyword string) *[]*Token {
+	switch keyword {
+	case Select:
+		return &this.select_
+	case From:
+		return &this.from_
+	case Where:
+		return &this.where_
+	case SortBy:
+		return &this.sortby_
+	case Descending:
+		return &this.descending_
+	case Ascending:
+		return &this.ascending_
+	case Limit:
+		return &this.limit_
+	case Page:
+		return &this.page_
+	case MatchCase:
+		return &this.matchcase_
+	case MapReduce:
+		return &this.mapreduce_
+	case GroupBy:
+		return &this.groupby_
+	case Having:
+		return &this.having_
+	}
+	return nil
+}
+
+// isClauseKeyword reports whether the token at index i starts a clause.
+// A clause keyword adjacent to a comparison operator is an operand, not a clause.
+func isClauseKeyword(s *tokenStream, i int) bool {
+	tok := s.tokens[i]
+	if tok.Type != TokenKeyword || isOperatorKeyword(tok.Value) {
+		return false
+	}
+	if i > 0 && isComparisonToken(s.tokens[i-1]) {
+		return false
+	}
+	return !isComparisonToken(s.tokens[i+1])
+}
+
+// isOperatorKeyword reports whether the keyword is a logical, membership or pattern
+// operator rather than a clause keyword.
+func isOperatorKeyword(keyword string) bool {
+	return keyword == "and" || keyword == "or" || keyword == "not" || keyword == "in" ||
+		keyword == "like" || keyword == "ilike" || keyword == "glob"
 }
 
-func getBoolTag(str, tag string) string {
-	index := strings.Index(str, tag)
-	if index != -1 {
-		return "true"
+// isComparisonToken reports whether the token is a comparison operator.
+func isComparisonToken(tok *Token) bool {
+	if keywordComparator(tok) != "" {
+		return true
 	}
-	return "false"
+	if tok.Type != TokenOper {
+		return false
+	}
+	for _, op := range comparators {
+		if tok.Value == string(op) {
+			return true
+		}
+	}
+	return false
 }
 
-func getTag(str, orig, tag string) string {
-	index := strings.Index(str, tag)
-	if index == -1 {
+// clauseText returns the original query text covered by the clause tokens.
+func clauseText(s *tokenStream, tokens []*Token) string {
+	if len(tokens) == 0 {
 		return ""
 	}
-	index += len(tag)
-	index2 := len(str)
-	for _, t := range words {
-		if t != tag {
-			index3 := strings.Index(str, t)
-			if index3 > index && index3 < index2 {
-				index2 = index3
+	return s.span(tokens[0], tokens[len(tokens)-1])
+}
+
+// clauseItems splits the clause tokens at top level commas and returns the tokens of each item.
+// Commas inside brackets, such as function arguments, do not split.
+func clauseItems(s *tokenStream, tokens []*Token) ([][]*Token, error) {
+	result := make([][]*Token, 0)
+	if len(tokens) == 0 {
+		return result, nil
+	}
+	depth := 0
+	start := 0
+	for i, tok := range tokens {
+		if tok.Type == TokenOpen {
+			depth++
+		} else if tok.Type == TokenClose {
+			depth--
+		} else if tok.Type == TokenComma && depth == 0 {
+			if i == start {
+				return nil, s.errorAt(tok, "Missing value before ','", "value")
 			}
+			result = append(result, tokens[start:i])
+			start = i + 1
 		}
 	}
-	return strings.TrimSpace(orig[index:index2])
+	if start == len(tokens) {
+		return nil, s.errorAt(tokens[len(tokens)-1], "Missing value after ','", "value")
+	}
+	result = append(result, tokens[start:])
+	return result, nil
+}
+
+// clauseList splits the clause tokens at top level commas and returns the text of each item.
+func clauseList(s *tokenStream, tokens []*Token) ([]string, error) {
+	items, e := clauseItems(s, tokens)
+	if e != nil {
+		return nil, e
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		result = append(result, clauseText(s, item))
+	}
+	return result, nil
 }
 
-func getSplitTag(str, orig, tag string) []string {
-	result := make([]string, 0)
-	data := getTag(str, orig, tag)
-	if data == "" {
-		return result
+// clauseBool returns the value of a boolean clause such as "descending" or "mapreduce true".
+// A present clause is true unless it is followed by an explicit false.
+func clauseBool(s *tokenStream, keyword string, tokens []*Token) (bool, error) {
+	if tokens == nil {
+		return false, nil
 	}
-	split := strings.Split(data, ",")
-	for _, t := range split {
-		result = append(result, t)
+	if len(tokens) == 0 {
+		return true, nil
+	}
+	if len(tokens) == 1 {
+		switch strings.ToLower(tokens[0].Text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, s.errorAt(tokens[0], "Unexpected token after "+keyword, "true", "false")
+}
+
+// clauseExpression parses the tokens of a WHERE or HAVING clause into an expression.
+func clauseExpression(s *tokenStream, p *parsed, keyword string, tokens []*Token) (*l8api.L8Expression, error) {
+	if len(tokens) == 0 {
+		return nil, s.errorAt(p.follow[keyword], "Missing expression after "+keyword, "expression")
 	}
-	return result
+	return parseWhereExpression(s.subStream(tokens, tokens[len(tokens)-1].End))
 }
 
 func (this *PQuery) init() error {
-	p := this.split()
-	this.pquery.Properties = make([]string, 0)
-	this.pquery.RootType = strings.TrimSpace(p.from_)
-	for _, col := range p.select_ {
-		this.pquery.Properties = append(this.pquery.Properties, col)
-	}
-	if p.where_ != "" {
-		where, e := parseExpression(p.where_)
+	s, p, e := this.split()
+	if e != nil {
+		return e
+	}
+	for _, clause := range []struct {
+		keyword string
+		tokens  []*Token
+	}{{Select, p.select_}, {From, p.from_}, {SortBy, p.sortby_}, {Limit, p.limit_}, {Page, p.page_}, {GroupBy, p.groupby_}} {
+		if clause.tokens != nil && len(clause.tokens) == 0 {
+			return s.errorAt(p.follow[clause.keyword], "Missing value after "+clause.keyword, "value")
+		}
+	}
+
+	selectTokens := p.select_
+	distinct := len(selectTokens) > 1 && isWord(selectTokens[0], Distinct) && selectTokens[1].Type != TokenComma
+	if distinct {
+		selectTokens = selectTokens[1:]
+	}
+	items, e := clauseItems(s, selectTokens)
+	if e != nil {
+		return e
+	}
+	cols := make([]string, 0, len(items))
+	for _, item := range items {
+		cols = append(cols, clauseText(s, item))
+	}
+	if e = canonicalWindows(s, items, cols); e != nil {
+		return e
+	}
+	if e = canonicalArithmetic(s, items, cols, this.ext); e != nil {
+		return e
+	}
+	if e = canonicalAliases(s, items, cols); e != nil {
+		return e
+	}
+	if distinct {
+		if e = checkDistinct(s, p, items, cols, this.ext); e != nil {
+			return e
+		}
+		cols = append([]string{Distinct}, cols...)
+		items = append([][]*Token{p.select_[:1]}, items...)
+	}
+	this.pquery.Properties = cols
+	this.pquery.RootType = clauseText(s, p.from_)
+	if p.where_ != nil {
+		where, e := clauseExpression(s, p, Where, p.where_)
 		if e != nil {
 			return e
 		}
 		this.pquery.Criteria = where
 	}
-	if p.limit_ != "" {
-		limit, e := strconv.Atoi(p.limit_)
+	if p.limit_ != nil {
+		limit, e := strconv.Atoi(clauseText(s, p.limit_))
 		if e != nil {
-			this.log.Error("Invalid limit:", p.limit_, ", setting limity to 10")
+			this.log.Error("Invalid limit:", clauseText(s, p.limit_), ", setting limity to 10")
 			limit = 10
 		}
 		if limit >= 1000 {
-			return this.log.Error("Invalid limit: Limit is limited up to 1000 elements")
+			this.log.Error("Invalid limit: Limit is limited up to 1000 elements")
+			return s.errorAt(p.limit_[0], "Invalid limit: Limit is limited up to 1000 elements", "number below 1000")
 		}
 		this.pquery.Limit = int32(limit)
 	}
-	if p.page_ != "" {
-		page, e := strconv.Atoi(p.page_)
+	if p.page_ != nil {
+		page, e := strconv.Atoi(clauseText(s, p.page_))
 		if e != nil {
-			return this.log.Error("Invalid page:", p.page_, ":", e.Error())
+			this.log.Error("Invalid page:", clauseText(s, p.page_), ":", e.Error())
+			return s.errorAt(p.page_[0], "Invalid page", "number")
 		}
 		this.pquery.Page = int32(page)
 	}
-	this.pquery.SortBy = p.sortby_
-	if p.descending_ == "true" {
-		this.pquery.Descending = true
+	this.pquery.SortBy = clauseText(s, p.sortby_)
+	descending, e := clauseBool(s, Descending, p.descending_)
+	if e != nil {
+		return e
 	}
-	if p.ascending_ == "true" {
-		this.pquery.Descending = false
+	ascending, e := clauseBool(s, Ascending, p.ascending_)
+	if e != nil {
+		return e
+	}
+	this.pquery.Descending = descending && !ascending
+	if p.sortby_ != nil {
+		this.sortKeys, e = parseSortKeys(s.subStream(p.sortby_, p.sortby_[len(p.sortby_)-1].End), this.pquery.Descending)
+		if e != nil {
+			return e
+		}
 	}
-	if p.matchcase_ == "true" {
-		this.pquery.MatchCase = true
+	this.pquery.MatchCase, e = clauseBool(s, MatchCase, p.matchcase_)
+	if e != nil {
+		return e
 	}
-	if p.mapreduce_ == "true" {
-		this.pquery.MapReduce = true
+	this.pquery.MapReduce, e = clauseBool(s, MapReduce, p.mapreduce_)
+	if e != nil {
+		return e
 	}
 
 	// Parse GROUP BY clause
-	if p.groupby_ != "" {
-		groupByCols := strings.Split(p.groupby_, ",")
-		this.pquery.GroupBy = make([]string, 0, len(groupByCols))
-		for _, col := range groupByCols {
-			col = strings.TrimSpace(col)
-			if col != "" {
-				this.pquery.GroupBy = append(this.pquery.GroupBy, col)
+	if p.groupby_ != nil {
+		groupByItems, e := clauseItems(s, p.groupby_)
+		if e != nil {
+			return e
+		}
+		groupItems := make([]*GroupItem, 0, len(groupByItems))
+		groupByCols := make([]string, 0, len(groupByItems))
+		for _, tokens := range groupByItems {
+			item, e := parseGroupItem(s, tokens)
+			if e != nil {
+				return e
 			}
+			groupItems = append(groupItems, item)
+			groupByCols = append(groupByCols, item.String())
+		}
+		if _, _, e := groupingSets(s, groupItems); e != nil {
+			return e
 		}
+		this.pquery.GroupBy = groupByCols
 	}
 
 	// Detect and extract aggregate functions from SELECT properties
-	if isAggregateQuery(this.pquery.Properties) {
+	if isAggregateQuery(this.pquery.Properties, this.ext) {
+		if i := windowColumn(this.pquery.Properties); i != -1 {
+			return s.errorAt(items[i][0], "Window functions are not supported in aggregate queries")
+		}
+		if i := computedColumn(this.pquery.Properties, this.pquery.GroupBy, this.ext); i != -1 {
+			text, _, _ := SplitAlias(this.pquery.Properties[i])
+			return s.errorAt(items[i][0], "Computed column "+text+" must be a group-by column")
+		}
 		remaining := make([]string, 0)
 		this.pquery.Aggregates = make([]*l8api.L8AggregateFunction, 0)
-		for _, prop := range this.pquery.Properties {
-			aggFn, ok := parseAggregateFunction(prop)
+		for i, prop := range this.pquery.Properties {
+			aggFn, ok, e := parseAggregateCall(s, items[i], this.ext)
+			if e != nil {
+				return e
+			}
 			if ok {
 				this.pquery.Aggregates = append(this.pquery.Aggregates, aggFn)
 			} else {
-				remaining = append(remaining, prop)
+				text, alias, _ := SplitAlias(prop)
+				tokens, _, _ := splitAlias(s, items[i])
+				if expr, e := parseGroupExpression(s, tokens); e == nil && expr.Function != "" {
+					text = expr.String()
+				}
+				remaining = append(remaining, WithAlias(text, alias))
 			}
 		}
 		this.pquery.Properties = remaining
 	}
 
 	// Parse HAVING clause
-	if p.having_ != "" {
-		having, e := parseExpression(p.having_)
+	if p.having_ != nil {
+		having, e := clauseExpression(s, p, Having, p.having_)
 		if e != nil {
 			return e
 		}
@@ -0,0 +1,110 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// SortBy.go parses the keys of a sort-by clause, e.g. "sort-by region asc, cpu desc nulls first, name".
+package parser
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SortKey is a single key of a sort-by clause.
+type SortKey struct {
+	Property   string // The property path, or the canonical expression, to sort by
+	Descending bool   // Sort this key in descending order
+	NullsFirst bool   // Sort nil values before all other values
+}
+
+// ParseSortBy parses the text of a sort-by clause into its keys. Each comma separated key
+// is a property, an aggregate such as count(*), or an expression such as lower(name) or
+// rxBytes + txBytes, optionally followed by "asc" or "desc" and by "nulls first" or "nulls last".
+// Keys without a direction use the given query-wide direction. Nil values sort first in
+// ascending keys and last in descending keys unless stated otherwise.
+// Returns a *SyntaxError if the text is not a valid sort-by clause.
+func ParseSortBy(text string, descending bool) ([]*SortKey, error) {
+	s, e := newTokenStream(text)
+	if e != nil {
+		return nil, e
+	}
+	return parseSortKeys(s, descending)
+}
+
+// String returns the string representation of the key, e.g. "cpu desc nulls first".
+func (this *SortKey) String() string {
+	buff := bytes.Buffer{}
+	buff.WriteString(this.Property)
+	if this.Descending {
+		buff.WriteString(" desc")
+	} else {
+		buff.WriteString(" asc")
+	}
+	if this.NullsFirst {
+		buff.WriteString(" nulls first")
+	} else {
+		buff.WriteString(" nulls last")
+	}
+	return buff.String()
+}
+
+// parseSortKeys parses the comma separated sort keys of the stream.
+func parseSortKeys(s *tokenStream, descending bool) ([]*SortKey, error) {
+	keys := make([]*SortKey, 0)
+	for {
+		key, e := parseSortKey(s, descending)
+		if e != nil {
+			return nil, e
+		}
+		keys = append(keys, key)
+		tok := s.next()
+		if tok.Type == TokenEOF {
+			return keys, nil
+		}
+		if tok.Type != TokenComma {
+			return nil, s.errorAt(tok, "Unexpected token in sort-by", "asc", "desc", "nulls", ",")
+		}
+	}
+}
+
+// parseSortKey parses a single sort key: a property or expression with an optional direction and nulls order.
+func parseSortKey(s *tokenStream, descending bool) (*SortKey, error) {
+	tok := s.peek()
+	if tok.Type != TokenIdent && tok.Type != TokenOpen && (tok.Type != TokenOper || tok.Value != string(Minus)) {
+		return nil, s.errorAt(tok, "Missing sort-by property", "property")
+	}
+	expr, e := parseSum(s)
+	if e != nil {
+		return nil, e
+	}
+	key := &SortKey{Property: expr.String(), Descending: descending}
+	if isWord(s.peek(), "asc") || isWord(s.peek(), "desc") {
+		key.Descending = isWord(s.next(), "desc")
+	}
+	key.NullsFirst = !key.Descending
+	if isWord(s.peek(), "nulls") {
+		s.next()
+		tok = s.next()
+		if !isWord(tok, "first") && !isWord(tok, "last") {
+			return nil, s.errorAt(tok, "Missing nulls order", "first", "last")
+		}
+		key.NullsFirst = isWord(tok, "first")
+	}
+	return key, nil
+}
+
+// isWord reports whether the token is the given identifier, ignoring case.
+func isWord(tok *Token, word string) bool {
+	return tok.Type == TokenIdent && strings.EqualFold(tok.Text, word)
+}
@@ -0,0 +1,105 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// SyntaxError.go defines the structured error returned when a query string cannot be parsed.
+// It carries the exact location of the problem so user interfaces can underline it.
+package parser
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes a parse failure at a specific location in the query text.
+type SyntaxError struct {
+	Message  string   // Description of the problem
+	Text     string   // The full query text
+	Offset   int      // Byte offset of the offending token in Text
+	Length   int      // Length in bytes of the offending token (at least 1)
+	Line     int      // 1-based line number of the offending token
+	Column   int      // 1-based column (in characters) of the offending token
+	Token    string   // The offending token text, empty at the end of the query
+	Expected []string // The tokens that would have been accepted at this location
+}
+
+// newSyntaxError creates a SyntaxError for the given token in the query text,
+// computing the line and column from the token offset.
+func newSyntaxError(text string, tok *Token, message string, expected ...string) *SyntaxError {
+	err := &SyntaxError{Message: message, Text: text, Offset: tok.Pos, Token: tok.Text, Expected: expected}
+	if err.Offset > len(text) {
+		err.Offset = len(text)
+	}
+	err.Length = tok.End - tok.Pos
+	if err.Length < 1 {
+		err.Length = 1
+	}
+	err.Line = 1 + strings.Count(text[:err.Offset], "\n")
+	lineStart := strings.LastIndex(text[:err.Offset], "\n") + 1
+	err.Column = 1 + utf8.RuneCountInString(text[lineStart:err.Offset])
+	return err
+}
+
+// Error returns the message with its location, the expected tokens and the annotated snippet.
+func (this *SyntaxError) Error() string {
+	buff := bytes.Buffer{}
+	buff.WriteString("Syntax error at line ")
+	buff.WriteString(strconv.Itoa(this.Line))
+	buff.WriteString(", column ")
+	buff.WriteString(strconv.Itoa(this.Column))
+	buff.WriteString(": ")
+	buff.WriteString(this.Message)
+	if this.Token != "" {
+		buff.WriteString(" near '")
+		buff.WriteString(this.Token)
+		buff.WriteString("'")
+	} else {
+		buff.WriteString(" at end of query")
+	}
+	if len(this.Expected) > 0 {
+		buff.WriteString(", expected ")
+		buff.WriteString(strings.Join(this.Expected, " or "))
+	}
+	buff.WriteString("\n")
+	buff.WriteString(this.Snippet())
+	return buff.String()
+}
+
+// Snippet returns the query line containing the error, followed by a line with
+// carets underlining the offending token.
+func (this *SyntaxError) Snippet() string {
+	lineStart := strings.LastIndex(this.Text[:this.Offset], "\n") + 1
+	lineEnd := strings.Index(this.Text[this.Offset:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(this.Text)
+	} else {
+		lineEnd += this.Offset
+	}
+	length := this.Length
+	if this.Offset+length > lineEnd {
+		length = lineEnd - this.Offset
+	}
+	carets := 1
+	if length > 1 {
+		carets = utf8.RuneCountInString(this.Text[this.Offset : this.Offset+length])
+	}
+	buff := bytes.Buffer{}
+	buff.WriteString(this.Text[lineStart:lineEnd])
+	buff.WriteString("\n")
+	buff.WriteString(strings.Repeat(" ", this.Column-1))
+	buff.WriteString(strings.Repeat("^", carets))
+	return buff.String()
+}
@@ -0,0 +1,323 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Window.go parses the window functions of a SELECT clause, which compute a value for each
+// result object from the objects around it: row_number(), rank(), dense_rank(), lag(field[, n]),
+// lead(field[, n]) and the running or moving sum, avg, min, max and count of a field, e.g.
+// "sum(bytes) over (partition by device sort-by time)" or
+// "avg(cpu) over (partition by device sort-by time rows 4 preceding)".
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Words of the OVER clause of a window function.
+const (
+	Over      = "over"      // Introduces the window of a function
+	Partition = "partition" // partition by p1, p2 splits the objects into independent windows
+	Rows      = "rows"      // rows N preceding limits the frame to the current and N preceding objects
+	Preceding = "preceding" // Ends the rows frame
+)
+
+// windowSpec describes the arguments a window function accepts.
+type windowSpec struct {
+	field     bool // Whether the function takes a field
+	star      bool // Whether the field may be "*"
+	offset    bool // Whether the field may be followed by an offset
+	aggregate bool // Whether the function aggregates a frame, and is a window function only with OVER
+}
+
+// windowFunctions maps the supported window function names to their arguments.
+var windowFunctions = map[string]*windowSpec{
+	"row_number": {},
+	"rank":       {},
+	"dense_rank": {},
+	"lag":        {field: true, offset: true},
+	"lead":       {field: true, offset: true},
+	"sum":        {field: true, aggregate: true},
+	"avg":        {field: true, aggregate: true},
+	"min":        {field: true, aggregate: true},
+	"max":        {field: true, aggregate: true},
+	"count":      {field: true, star: true, aggregate: true},
+}
+
+// WindowFunction is a window function of a SELECT clause.
+type WindowFunction struct {
+	Function    string     // The function name, e.g. row_number, lag or sum
+	Field       string     // The property of the function, empty for row_number, rank and dense_rank
+	Offset      int        // The offset of lag and lead, 1 by default
+	PartitionBy []string   // The properties whose values partition the objects, none for a single partition
+	SortBy      []*SortKey // The order of the objects within a partition, none for the order of the query
+	Preceding   int        // The number of preceding objects in a moving frame, or -1 for no moving frame
+	Alias       string     // The name of the result column
+}
+
+// ParseWindowFunction detects if a SELECT column is a window function call.
+// Returns the parsed WindowFunction and true if it is, or nil and false otherwise.
+// A call of a window function with invalid arguments or window returns true and an error.
+// Aggregate functions are window functions only when followed by an OVER clause.
+// The column may end with an alias, which replaces the default alias.
+// Examples: "row_number()" -> {function:"row_number", alias:"rowNumber"}
+//
+//	"lag(bytes, 2) over (partition by device sort-by time)" -> {function:"lag", field:"bytes", offset:2, alias:"lag2Bytes"}
+//	"sum(bytes) over (sort-by time)" -> {function:"sum", field:"bytes", alias:"sumBytes"}
+func ParseWindowFunction(col string) (*WindowFunction, bool, error) {
+	s, e := newTokenStream(strings.TrimSpace(col))
+	if e != nil {
+		return nil, false, nil
+	}
+	return parseWindowFunction(s, s.tokens[:len(s.tokens)-1])
+}
+
+// parseWindowFunction parses the tokens of a SELECT column. A column with an invalid alias
+// is not a window function, and errors are reported as a *SyntaxError located at the offending token.
+func parseWindowFunction(s *tokenStream, tokens []*Token) (*WindowFunction, bool, error) {
+	tokens, alias, e := splitAlias(s, tokens)
+	if e != nil || len(tokens) == 0 {
+		return nil, false, nil
+	}
+	s = s.subStream(tokens, tokens[len(tokens)-1].End)
+	name := s.next()
+	spec, ok := windowFunctions[strings.ToLower(name.Text)]
+	if name.Type != TokenIdent || !ok || s.peek().Type != TokenOpen || s.peek().Value != "(" {
+		return nil, false, nil
+	}
+	window := &WindowFunction{Function: strings.ToLower(name.Text), Offset: 1, Preceding: -1}
+	args, end, e := windowArguments(s)
+	if e != nil {
+		if spec.aggregate {
+			return nil, false, nil
+		}
+		return nil, true, e
+	}
+	over := s.next()
+	if over.Type == TokenEOF {
+		if spec.aggregate {
+			return nil, false, nil
+		}
+	} else if !isWord(over, Over) {
+		return nil, true, s.errorAt(over, "Unexpected token after window function", Over)
+	} else if e = parseWindow(s, window); e != nil {
+		return nil, true, e
+	}
+	if e = window.setArguments(s, spec, args, end); e != nil {
+		return nil, true, e
+	}
+	if alias != nil {
+		window.Alias = alias.Value
+	}
+	return window, true, nil
+}
+
+// windowArguments returns the tokens of the comma separated arguments of a function call,
+// and its closing bracket.
+func windowArguments(s *tokenStream) ([][]*Token, *Token, error) {
+	first := s.pos + 1
+	end, e := skipCallArguments(s)
+	if e != nil {
+		return nil, nil, e
+	}
+	args, e := clauseItems(s, s.tokens[first:s.pos-1])
+	if e != nil {
+		return nil, nil, e
+	}
+	return args, end, nil
+}
+
+// setArguments validates the arguments of the function call and sets its field, offset and alias.
+func (this *WindowFunction) setArguments(s *tokenStream, spec *windowSpec, args [][]*Token, end *Token) error {
+	count := 0
+	if spec.field {
+		count = 1
+	}
+	if len(args) < count {
+		return s.errorAt(end, "Window function "+this.Function+" expects "+strconv.Itoa(count)+" argument(s)", "field")
+	}
+	if len(args) > count+boolInt(spec.offset) {
+		return s.errorAt(args[count+boolInt(spec.offset)][0], "Window function "+this.Function+" expects "+strconv.Itoa(count)+" argument(s)", ")")
+	}
+	aliasArgs := make([]string, 0, 1)
+	if spec.field {
+		field := args[0][0]
+		if len(args[0]) != 1 || (field.Type != TokenIdent && (field.Value != "*" || !spec.star)) {
+			return s.errorAt(field, "Invalid field in window function", "property")
+		}
+		this.Field = field.Text
+	}
+	if len(args) > 1 {
+		tok := args[1][0]
+		offset, e := strconv.Atoi(tok.Text)
+		if len(args[1]) != 1 || tok.Type != TokenNumber || e != nil || offset < 0 {
+			return s.errorAt(tok, "Offset of "+this.Function+" must be a non-negative integer", "non-negative integer")
+		}
+		this.Offset = offset
+	}
+	if this.Offset != 1 {
+		aliasArgs = append(aliasArgs, strconv.Itoa(this.Offset))
+	}
+	this.Alias = buildAlias(this.Function, this.Field, aliasArgs...)
+	if this.Field == "" {
+		this.Alias = buildAlias(this.Function, "*", aliasArgs...)
+	}
+	return nil
+}
+
+// boolInt returns 1 for true and 0 for false.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parseWindow parses the OVER clause of a window function:
+// "(" [partition by p1, ...] [sort-by k1 [asc|desc], ...] [rows N preceding] ")".
+func parseWindow(s *tokenStream, window *WindowFunction) error {
+	if tok := s.next(); tok.Type != TokenOpen || tok.Value != "(" {
+		return s.errorAt(tok, "Missing window after over", "(")
+	}
+	if isWord(s.peek(), Partition) {
+		s.next()
+		if tok := s.next(); !isWord(tok, "by") {
+			return s.errorAt(tok, "Missing by after partition", "by")
+		}
+		for {
+			tok := s.next()
+			if tok.Type != TokenIdent {
+				return s.errorAt(tok, "Missing partition property", "property")
+			}
+			window.PartitionBy = append(window.PartitionBy, tok.Text)
+			if s.peek().Type != TokenComma {
+				break
+			}
+			s.next()
+		}
+	}
+	// The clause keywords of a query are identifiers inside its SELECT clause
+	if isKeyword(s.peek(), SortBy) || isWord(s.peek(), SortBy) {
+		s.next()
+		for {
+			key, e := parseSortKey(s, false)
+			if e != nil {
+				return e
+			}
+			window.SortBy = append(window.SortBy, key)
+			if s.peek().Type != TokenComma {
+				break
+			}
+			s.next()
+		}
+	}
+	if isWord(s.peek(), Rows) {
+		s.next()
+		tok := s.next()
+		n, e := strconv.Atoi(tok.Text)
+		if tok.Type != TokenNumber || e != nil || n < 0 {
+			return s.errorAt(tok, "Invalid number of preceding rows", "non-negative integer")
+		}
+		if tok = s.next(); !isWord(tok, Preceding) {
+			return s.errorAt(tok, "Missing preceding after rows", Preceding)
+		}
+		window.Preceding = n
+	}
+	if tok := s.next(); tok.Type != TokenClose || tok.Value != ")" {
+		return s.errorAt(tok, "Unexpected token in window", Partition, SortBy, Rows, ")")
+	}
+	if tok := s.next(); tok.Type != TokenEOF {
+		return s.errorAt(tok, "Unexpected token after window", "end of column")
+	}
+	return nil
+}
+
+// String returns the canonical text of the window function, e.g.
+// "sum(bytes) over (partition by device sort-by time asc nulls first rows 4 preceding)".
+func (this *WindowFunction) String() string {
+	buff := strings.Builder{}
+	buff.WriteString(this.Function)
+	buff.WriteString("(")
+	buff.WriteString(this.Field)
+	if this.Offset != 1 {
+		buff.WriteString(", ")
+		buff.WriteString(strconv.Itoa(this.Offset))
+	}
+	buff.WriteString(")")
+	if len(this.PartitionBy) == 0 && len(this.SortBy) == 0 && this.Preceding < 0 {
+		if windowFunctions[this.Function].aggregate {
+			buff.WriteString(" over ()")
+		}
+		return buff.String()
+	}
+	buff.WriteString(" over (")
+	sep := ""
+	if len(this.PartitionBy) > 0 {
+		buff.WriteString("partition by ")
+		buff.WriteString(strings.Join(this.PartitionBy, ", "))
+		sep = " "
+	}
+	if len(this.SortBy) > 0 {
+		buff.WriteString(sep)
+		buff.WriteString(SortBy)
+		buff.WriteString(" ")
+		for i, key := range this.SortBy {
+			if i > 0 {
+				buff.WriteString(", ")
+			}
+			buff.WriteString(key.String())
+		}
+		sep = " "
+	}
+	if this.Preceding >= 0 {
+		buff.WriteString(sep)
+		buff.WriteString("rows ")
+		buff.WriteString(strconv.Itoa(this.Preceding))
+		buff.WriteString(" preceding")
+	}
+	buff.WriteString(")")
+	return buff.String()
+}
+
+// canonicalWindows replaces the window functions of the SELECT columns by their canonical text,
+// keeping their alias. Returns a *SyntaxError for an invalid window function, or for two window
+// functions with the same alias.
+func canonicalWindows(s *tokenStream, items [][]*Token, cols []string) error {
+	aliases := make(map[string]bool)
+	for i, item := range items {
+		window, ok, e := parseWindowFunction(s, item)
+		if e != nil {
+			return e
+		}
+		if ok {
+			if aliases[window.Alias] {
+				return s.errorAt(item[0], "Duplicate window function column "+window.Alias)
+			}
+			aliases[window.Alias] = true
+			_, alias, _ := SplitAlias(cols[i])
+			cols[i] = WithAlias(window.String(), alias)
+		}
+	}
+	return nil
+}
+
+// windowColumn returns the index of the first window function of the SELECT columns, or -1 if there is none.
+func windowColumn(cols []string) int {
+	for i, col := range cols {
+		if _, ok, _ := ParseWindowFunction(col); ok {
+			return i
+		}
+	}
+	return -1
+}
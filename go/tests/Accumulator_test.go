@@ -0,0 +1,182 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+)
+
+// accumulate adds the values to a new accumulator of the function.
+func accumulate(fn string, values ...interface{}) *interpreter.Accumulator {
+	acc := interpreter.NewAccumulator(fn)
+	for _, value := range values {
+		acc.Add(value)
+	}
+	return acc
+}
+
+// checkAccumulator verifies the result and result type of the function over the values.
+func checkAccumulator(fn string, expected interface{}, expectedType interface{}, t *testing.T, values ...interface{}) bool {
+	acc := accumulate(fn, values...)
+	if acc.Err() != nil {
+		Log.Fail(t, "Unexpected error for ", fn, ": ", acc.Err())
+		return false
+	}
+	if acc.Result() != expected {
+		Log.Fail(t, "Expected ", expected, " for ", fn, " of ", values, ", got ", acc.Result())
+		return false
+	}
+	if acc.ResultType() != reflect.TypeOf(expectedType) {
+		Log.Fail(t, "Expected type ", reflect.TypeOf(expectedType), " for ", fn, " of ", values, ", got ", acc.ResultType())
+		return false
+	}
+	return true
+}
+
+// TestAccumulatorExactSums verifies that integer sums are exact and keep a signed or unsigned type.
+func TestAccumulatorExactSums(t *testing.T) {
+	big := int64(1) << 53
+	if !checkAccumulator("sum", 2*big+3, int64(0), t, big, int32(1), big, uint32(2)) {
+		return
+	}
+	if !checkAccumulator("sum", uint64(math.MaxUint64), uint64(0), t, uint64(math.MaxUint64-1), uint32(1)) {
+		return
+	}
+	if !checkAccumulator("sum", int64(-1), int64(0), t, uint64(1), int32(-2)) {
+		return
+	}
+	if !checkAccumulator("sum", 3.5, float64(0), t, int32(1), float32(0.5), uint64(2)) {
+		return
+	}
+	if !checkAccumulator("avg", float64(big+1), float64(0), t, big, big+2) {
+		return
+	}
+	checkAccumulator("avg", float64(math.MaxInt64), float64(0), t, int64(math.MaxInt64), int64(math.MaxInt64))
+}
+
+// TestAccumulatorOverflow verifies that an integer sum that does not fit fails with ErrOverflow.
+func TestAccumulatorOverflow(t *testing.T) {
+	for _, values := range [][]interface{}{
+		{int64(math.MaxInt64), int32(1)},
+		{int64(math.MinInt64), int64(-1)},
+		{uint64(math.MaxUint64), uint8(1)},
+		{uint64(math.MaxUint64), int32(-1)},
+		{int64(-1), uint64(math.MaxUint64)},
+	} {
+		acc := accumulate("sum", values...)
+		if !errors.Is(acc.Err(), interpreter.ErrOverflow) || acc.Result() != nil {
+			Log.Fail(t, "Expected an overflow for ", values, ", got ", acc.Result(), " ", acc.Err())
+			return
+		}
+	}
+}
+
+// TestAccumulatorMinMax verifies min and max over numbers, strings, bools and timestamps.
+func TestAccumulatorMinMax(t *testing.T) {
+	now := time.Now()
+	if !checkAccumulator("min", "apple", "", t, "pear", "apple", "zoo") ||
+		!checkAccumulator("max", "zoo", "", t, "pear", "apple", "zoo") ||
+		!checkAccumulator("min", false, false, t, true, false) ||
+		!checkAccumulator("max", now, now, t, now.Add(-time.Hour), now, now.Add(-time.Minute)) ||
+		!checkAccumulator("min", int32(-3), int32(0), t, int32(5), int32(-3), float64(2)) ||
+		!checkAccumulator("max", uint64(math.MaxUint64), uint64(0), t, int64(-1), uint64(math.MaxUint64)) {
+		return
+	}
+	for _, values := range [][]interface{}{{"a", int32(1)}, {true, "b"}, {[]int{1}}} {
+		acc := accumulate("min", values...)
+		var ee *interpreter.EvaluationError
+		if !errors.As(acc.Err(), &ee) || acc.Result() != nil {
+			Log.Fail(t, "Expected an evaluation error for min of ", values)
+			return
+		}
+	}
+	acc := accumulate("sum", int32(1), "a")
+	if acc.Err() == nil {
+		Log.Fail(t, "Expected an error for the sum of a string")
+	}
+}
+
+// TestAccumulatorEmpty verifies that aggregates over no values are nil, except for counts.
+func TestAccumulatorEmpty(t *testing.T) {
+	for _, fn := range []string{"sum", "avg", "min", "max", "median", "percentile:90", "stddev", "variance", "first", "last"} {
+		acc := accumulate(fn, nil, nil)
+		if acc.Err() != nil || acc.Result() != nil {
+			Log.Fail(t, "Expected nil for ", fn, " without values, got ", acc.Result())
+			return
+		}
+	}
+	if !checkAccumulator("count", int64(2), int64(0), t, nil, nil) ||
+		!checkAccumulator("count:distinct", int64(0), int64(0), t, nil) ||
+		!checkAccumulator("variance", nil, float64(0), t, int32(4)) {
+		return
+	}
+	acc := accumulate("avg")
+	if acc.ResultType() != reflect.TypeOf(float64(0)) {
+		Log.Fail(t, "Expected float64 type for avg without values, got ", acc.ResultType())
+	}
+}
+
+// TestAggregateResultTypes verifies the column types reported by Execute and the error
+// policy for aggregates that fail.
+func TestAggregateResultTypes(t *testing.T) {
+	q, _, e := createQuery("select myString,sum(myInt64),sum(myUint32),min(myString),avg(myInt32) from TestProto group-by myString")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	items := make([]interface{}, 0)
+	for i, v := range []int64{math.MaxInt64, 1, 5} {
+		node := CreateTestModelInstance(i)
+		node.MyString = []string{"A", "A", "B"}[i]
+		node.MyInt64 = v
+		node.MyUint32 = uint32(i)
+		items = append(items, node)
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := map[string]reflect.Type{
+		"myString":    reflect.TypeOf(""),
+		"sumMyInt64":  reflect.TypeOf(int64(0)),
+		"sumMyUint32": reflect.TypeOf(uint64(0)),
+		"minMyString": reflect.TypeOf(""),
+		"avgMyInt32":  reflect.TypeOf(float64(0)),
+	}
+	for column, typ := range expected {
+		if result.Types[column] != typ {
+			Log.Fail(t, "Expected type ", typ, " for ", column, ", got ", result.Types[column])
+			return
+		}
+	}
+	if len(result.Rows) != 2 || result.Rows[0]["sumMyInt64"] != nil || result.Rows[1]["sumMyInt64"] != int64(5) {
+		Log.Fail(t, "Expected a nil sum for the overflowing group, got ", result.Rows)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	_, e = q.Execute(items)
+	if !errors.Is(e, interpreter.ErrOverflow) || !strings.Contains(e.Error(), "sum(myInt64)") {
+		Log.Fail(t, "Expected an overflow error, got ", e)
+	}
+}
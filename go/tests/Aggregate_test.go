@@ -15,6 +15,11 @@ limitations under the License.
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"testing"
 
 	. "github.com/saichler/l8ql/go/gsql/parser"
@@ -199,3 +204,351 @@ func TestParseAllAggregateFunctions(t *testing.T) {
 		}
 	}
 }
+
+// TestParseStatisticalAggregates verifies parsing of the modifier and constant arguments
+// of aggregate functions, and the generated aliases.
+func TestParseStatisticalAggregates(t *testing.T) {
+	q, e := NewQuery("select count(distinct myString),median(myInt32),percentile(myInt32, 99.9),stddev(myInt32),"+
+		"variance(myInt32),first(myString),last(myString),collect(myString) from TestProto", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := [][]string{
+		{"count:distinct", "myString", "countDistinctMyString", "count(distinct myString)"},
+		{"median", "myInt32", "medianMyInt32", "median(myInt32)"},
+		{"percentile:99.9", "myInt32", "percentile99_9MyInt32", "percentile(myInt32, 99.9)"},
+		{"stddev", "myInt32", "stddevMyInt32", "stddev(myInt32)"},
+		{"variance", "myInt32", "varianceMyInt32", "variance(myInt32)"},
+		{"first", "myString", "firstMyString", "first(myString)"},
+		{"last", "myString", "lastMyString", "last(myString)"},
+		{"collect", "myString", "collectMyString", "collect(myString)"},
+	}
+	if len(q.Query().Aggregates) != len(expected) {
+		Log.Fail(t, "Expected ", len(expected), " aggregate functions, got ", len(q.Query().Aggregates))
+		return
+	}
+	for i, agg := range q.Query().Aggregates {
+		if agg.Function != expected[i][0] || agg.Field != expected[i][1] || agg.Alias != expected[i][2] ||
+			AggregateCall(agg) != expected[i][3] {
+			Log.Fail(t, "Unexpected aggregate:", agg.Function, agg.Field, agg.Alias, AggregateCall(agg))
+			return
+		}
+	}
+	for text, token := range map[string]string{"percentile(myInt32)": ")", "percentile(myInt32, 101)": "101",
+		"percentile(myInt32, x)": "x", "median(myInt32, 5)": "5", "sum(distinct myInt32)": "distinct",
+		"count(distinct *)": "*", "median(*)": "*", "sum(myInt32 + 1)": "+"} {
+		_, e = NewQuery("select myString, "+text+" from TestProto", Log)
+		var se *SyntaxError
+		if !errors.As(e, &se) || se.Token != token || se.Offset != len("select myString, ")+strings.Index(text, token) {
+			Log.Fail(t, "Expected a syntax error at ", token, " for ", text, ", got ", e)
+			return
+		}
+	}
+}
+
+// TestStatisticalAggregates verifies the results of the statistical aggregate functions.
+func TestStatisticalAggregates(t *testing.T) {
+	q, _, e := createQuery("select count(distinct myString),median(myInt32),percentile(myInt32,95),stddev(myInt32)," +
+		"variance(myInt32),first(myInt32),last(myInt32),collect(myString) from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	rows := q.Aggregate(havingItems())
+	if len(rows) != 1 {
+		Log.Fail(t, "Expected 1 row, got ", len(rows))
+		return
+	}
+	row := rows[0]
+	expected := map[string]interface{}{
+		"countDistinctMyString": int64(3),
+		"medianMyInt32":         float64(35),
+		"percentile95MyInt32":   57.5,
+		"varianceMyInt32":       float64(350),
+		"stddevMyInt32":         math.Sqrt(350),
+		"firstMyInt32":          int32(10),
+		"lastMyInt32":           int32(60),
+	}
+	for alias, value := range expected {
+		if row[alias] != value {
+			Log.Fail(t, "Expected ", value, " for ", alias, ", got ", row[alias])
+			return
+		}
+	}
+	collected, ok := row["collectMyString"].([]interface{})
+	if !ok || len(collected) != 6 || collected[0] != "A" || collected[5] != "C" {
+		Log.Fail(t, "Unexpected collect result ", row["collectMyString"])
+	}
+}
+
+// TestStatisticalAggregatesPerGroup verifies statistical aggregates per group and in HAVING.
+func TestStatisticalAggregatesPerGroup(t *testing.T) {
+	queries := map[string][]string{
+		"select myString,median(myInt32) from TestProto group-by myString having medianMyInt32 > 30":                  {"B", "C"},
+		"select myString,percentile(myInt32, 50) from TestProto group-by myString having percentile(myInt32,50) = 20": {"A"},
+		"select myString,count(distinct myInt32) from TestProto group-by myString having count(distinct myInt32) > 1": {"A", "B"},
+		"select myString,first(myInt32) from TestProto group-by myString sort-by firstMyInt32 desc":                   {"C", "B", "A"},
+		"select myString,stddev(myInt32) from TestProto group-by myString having stddevMyInt32 = 10":                  {"A"},
+	}
+	for query, expected := range queries {
+		if !checkHaving(query, expected, t) {
+			return
+		}
+	}
+}
+
+// havingItems creates test objects in groups A (3 objects), B (2 objects) and C (1 object),
+// with myInt32 values 10, 20, 30, 40, 50 and 60.
+func havingItems() []interface{} {
+	items := make([]interface{}, 0)
+	for i, group := range []string{"A", "A", "A", "B", "B", "C"} {
+		node := CreateTestModelInstance(i)
+		node.MyString = group
+		node.MyInt32 = int32(10 * (i + 1))
+		items = append(items, node)
+	}
+	return items
+}
+
+// checkHaving runs the aggregate query and verifies the group-by values of the resulting rows.
+func checkHaving(query string, expected []string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	rows := q.Aggregate(havingItems())
+	if len(rows) != len(expected) {
+		Log.Fail(t, "Expected ", len(expected), " rows for ", query, ", got ", len(rows))
+		return false
+	}
+	for i, row := range rows {
+		if row["myString"] != expected[i] {
+			Log.Fail(t, "Expected group ", expected[i], " for ", query, ", got ", row["myString"])
+			return false
+		}
+	}
+	return true
+}
+
+// TestHaving verifies that HAVING filters aggregate rows by aliases, function calls and group-by columns.
+func TestHaving(t *testing.T) {
+	queries := map[string][]string{
+		"select myString,count(*) from TestProto group-by myString having count > 1":                             {"A", "B"},
+		"select myString,count(*) from TestProto group-by myString having count(*) > 2":                          {"A"},
+		"select myString,count(*) from TestProto group-by myString having COUNT( * ) <= 2":                       {"B", "C"},
+		"select myString,sum(myInt32) from TestProto group-by myString having sumMyInt32 >= 60":                  {"A", "B", "C"},
+		"select myString,sum(myInt32) from TestProto group-by myString having sum(myint32) > 60":                 {"B"},
+		"select myString,count(*) from TestProto group-by myString having myString != B":                         {"A", "C"},
+		"select myString,count(*),avg(myInt32) from TestProto group-by myString having count=1 or avgMyInt32<30": {"A", "C"},
+	}
+	for query, expected := range queries {
+		if !checkHaving(query, expected, t) {
+			return
+		}
+	}
+}
+
+// TestHavingUnknownColumn verifies that HAVING must reference a column of the aggregate rows.
+func TestHavingUnknownColumn(t *testing.T) {
+	checkQuery("select myString,count(*) from TestProto group-by myString having myInt32 > 1", true, t)
+}
+
+// TestExecuteHaving verifies that Execute applies HAVING and counts the remaining rows.
+func TestExecuteHaving(t *testing.T) {
+	q, _, e := createQuery("select myString,count(*) from TestProto where myInt32>10 group-by myString having count(*) = 2")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(havingItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Total != 2 || len(result.Rows) != 2 {
+		Log.Fail(t, "Expected 2 rows, got ", result.Total)
+	}
+}
+
+// TestParseHavingFunctionCall verifies that aggregate function calls are accepted as HAVING operands.
+func TestParseHavingFunctionCall(t *testing.T) {
+	q, e := NewQuery("select myString,count(*) from TestProto group-by myString having count(*) > 5 and sum(myInt32)<=max(myInt32)", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	having := StringExpression(q.Query().Having)
+	if having != "(count(*)>5 and sum(myint32)<=max(myInt32))" {
+		Log.Fail(t, "Unexpected having ", having)
+		return
+	}
+	for _, text := range []string{"having (*) > 5", "having count(* > 5", "having a > (5)"} {
+		_, e = NewQuery("select myString,count(*) from TestProto group-by myString "+text, Log)
+		if e == nil {
+			Log.Fail(t, "Expected an error for ", text)
+			return
+		}
+	}
+}
+
+// sortItems creates test objects in groups with myString values A to F, where group i
+// holds i+1 objects with myInt32 values 1..i+1.
+func sortItems() []interface{} {
+	items := make([]interface{}, 0)
+	for i, group := range []string{"C", "A", "F", "B", "E", "D"} {
+		for j := 0; j <= i; j++ {
+			node := CreateTestModelInstance(j)
+			node.MyString = group
+			node.MyInt32 = int32(j + 1)
+			items = append(items, node)
+		}
+	}
+	return items
+}
+
+// checkAggregateOrder runs the aggregate query and verifies the order of the group-by values.
+func checkAggregateOrder(query string, expected []string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	rows := q.Aggregate(sortItems())
+	groups := make([]string, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, row["myString"].(string))
+	}
+	if strings.Join(groups, ",") != strings.Join(expected, ",") {
+		Log.Fail(t, "Expected ", expected, " for ", query, ", got ", groups)
+		return false
+	}
+	return true
+}
+
+// TestAggregateSortAndLimit verifies sorting and paging of aggregate rows.
+func TestAggregateSortAndLimit(t *testing.T) {
+	queries := map[string][]string{
+		"select myString,count(*) from TestProto group-by myString sort-by count descending":                {"D", "E", "B", "F", "A", "C"},
+		"select myString,count(*) from TestProto group-by myString sort-by count descending limit 2":        {"D", "E"},
+		"select myString,count(*) from TestProto group-by myString sort-by count(*) desc limit 2 page 1":    {"B", "F"},
+		"select myString,count(*) from TestProto group-by myString sort-by myString limit 4":                {"A", "B", "C", "D"},
+		"select myString,sum(myInt32) from TestProto group-by myString sort-by sumMyInt32 limit 3 page 1":   {"B", "E", "D"},
+		"select myString,count(*) from TestProto group-by myString having count > 2 sort-by myString desc":  {"F", "E", "D", "B"},
+		"select myString,count(*) from TestProto group-by myString limit 2":                                 {"A", "B"},
+		"select myString,count(*) from TestProto group-by myString sort-by count descending limit 4 page 2": {},
+	}
+	for query, expected := range queries {
+		if !checkAggregateOrder(query, expected, t) {
+			return
+		}
+	}
+	checkQuery("select myString,count(*) from TestProto group-by myString sort-by myInt32", true, t)
+}
+
+// TestExecuteAggregateTotal verifies that the total counts all aggregate rows before paging.
+func TestExecuteAggregateTotal(t *testing.T) {
+	q, _, e := createQuery("select myString,count(*) from TestProto group-by myString sort-by count desc limit 2")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(sortItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Total != 6 || len(result.Rows) != 2 || result.Rows[0]["count"] != int64(6) {
+		Log.Fail(t, "Expected 2 of 6 rows starting with the largest group")
+	}
+}
+
+// unnestItems creates test objects with repeated myStringSlice and myInt32Slice values.
+func unnestItems() []interface{} {
+	slices := [][]string{{"US", "UK"}, {"UK"}, {}, {"FR", "US", "US"}}
+	items := make([]interface{}, 0)
+	for i, slice := range slices {
+		node := CreateTestModelInstance(i)
+		node.MyString = "item" + strconv.Itoa(i)
+		node.MyStringSlice = slice
+		node.MyInt32Slice = []int32{int32(i % 2)}
+		node.MyInt32 = int32(i + 1)
+		items = append(items, node)
+	}
+	return items
+}
+
+// checkGroups runs the aggregate query over the items and verifies the rows, written as
+// the values of the given columns separated by commas.
+func checkGroups(query string, items []interface{}, columns []string, expected []string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	rows := q.Aggregate(items)
+	result := make([]string, 0, len(rows))
+	for _, row := range rows {
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, fmt.Sprint(row[column]))
+		}
+		result = append(result, strings.Join(values, ","))
+	}
+	if strings.Join(result, " ") != strings.Join(expected, " ") {
+		Log.Fail(t, "Expected ", expected, " for ", query, ", got ", result)
+		return false
+	}
+	return true
+}
+
+// TestGroupByUnnest verifies that group-by over repeated fields puts each object in the
+// group of each of its distinct elements, with typed and ordered group values.
+func TestGroupByUnnest(t *testing.T) {
+	items := unnestItems()
+	if !checkGroups("select myStringSlice,count(*),sum(myInt32),collect(myString) from TestProto group-by myStringSlice",
+		items, []string{"myStringSlice", "count", "sumMyInt32", "collectMyString"},
+		[]string{"<nil>,1,3,[item2]", "FR,1,4,[item3]", "UK,2,3,[item0 item1]", "US,2,5,[item0 item3]"}, t) {
+		return
+	}
+	if !checkGroups("select myInt32Slice,myStringSlice,count(*) from TestProto group-by myInt32Slice,myStringSlice",
+		items, []string{"myInt32Slice", "myStringSlice", "count"},
+		[]string{"0,<nil>,1", "0,UK,1", "0,US,1", "1,FR,1", "1,UK,1", "1,US,1"}, t) {
+		return
+	}
+	q, _, e := createQuery("select myInt32Slice,count(*) from TestProto group-by myInt32Slice")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	rows := q.Aggregate(items)
+	if len(rows) != 2 || rows[0]["myInt32Slice"] != int32(0) || rows[1]["myInt32Slice"] != int32(1) {
+		Log.Fail(t, "Expected typed int32 group values, got ", rows)
+	}
+}
+
+// TestGroupByOrder verifies that the order of the groups, and of the groups that tie in the
+// sort-by keys, does not depend on the order of the objects.
+func TestGroupByOrder(t *testing.T) {
+	items := sortItems()
+	reversed := make([]interface{}, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		reversed = append(reversed, items[i])
+	}
+	expected := []string{"A,2", "B,4", "C,1", "D,6", "E,5", "F,3"}
+	query := "select myString,count(*) from TestProto group-by myString"
+	if !checkGroups(query, items, []string{"myString", "count"}, expected, t) {
+		return
+	}
+	if !checkGroups(query, reversed, []string{"myString", "count"}, expected, t) {
+		return
+	}
+	// Every group has the same minimum, so the rows of the page are chosen by their group-by values
+	query = "select myString,min(myInt32) from TestProto group-by myString sort-by minMyInt32 limit 2 page 1"
+	for _, list := range [][]interface{}{items, reversed} {
+		if !checkGroups(query, list, []string{"myString", "minMyInt32"}, []string{"C,1", "D,1"}, t) {
+			return
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+)
+
+// arithmeticItems returns objects with MyInt32 1..4, MyInt64 10 times MyInt32,
+// MyUint32 equal to MyInt32 and MyFloat64 half of MyInt32.
+func arithmeticItems() []interface{} {
+	items := make([]interface{}, 0, 4)
+	for i := 1; i <= 4; i++ {
+		node := CreateTestModelInstance(i)
+		node.MyString = fmt.Sprint("n", i)
+		node.MyInt32 = int32(i)
+		node.MyInt64 = int64(i) * 10
+		node.MyUint32 = uint32(i)
+		node.MyFloat64 = float64(i) / 2
+		items = append(items, node)
+	}
+	return items
+}
+
+// checkFilterNames filters the items by the query and verifies the MyString values of the matches.
+func checkFilterNames(query string, items []interface{}, expected string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	names := make([]string, 0)
+	for _, item := range q.Filter(items, false) {
+		v := reflect.ValueOf(item).Elem().FieldByName("MyString").String()
+		names = append(names, v)
+	}
+	if fmt.Sprint(names) != expected {
+		Log.Fail(t, "Expected ", expected, " for ", query, ", got ", names)
+		return false
+	}
+	return true
+}
+
+// TestParseArithmetic verifies the precedence and canonical text of arithmetic expressions.
+func TestParseArithmetic(t *testing.T) {
+	for text, expected := range map[string]string{
+		"a+b*2":           "a + b * 2",
+		"(a+b)*2":         "(a + b) * 2",
+		"a-(b-c)":         "a - (b - c)",
+		"a-b-c":           "a - b - c",
+		"-(a+b) % 3":      "-(a + b) % 3",
+		"--a":             "--a",
+		"a / -b":          "a / -b",
+		"sum(x)/count(*)": "sum(x) / count(*)",
+	} {
+		expr, ok, e := parser.ParseArithmetic(text)
+		if e != nil || !ok || expr.String() != expected {
+			Log.Fail(t, "Expected ", expected, " for ", text, ", got ", expr, ok, e)
+			return
+		}
+	}
+	for _, text := range []string{"a", "-5", "count(*)", "abc*"} {
+		if parser.IsArithmetic(text) {
+			Log.Fail(t, "Expected ", text, " not to be arithmetic")
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where (myInt32 + myInt64) * 2 > 10 and myString = x",
+		"select * from TestProto where myInt32 > -(myInt64 - 3)",
+		"select * from TestProto where ((myInt32 + 1) * 2 > 10 or myInt32 = 1)",
+	} {
+		q, e := parser.NewQuery(query, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		where := parser.StringExpression(q.Query().Criteria)
+		if _, e = parser.NewQuery("select * from TestProto where "+where, Log); e != nil {
+			Log.Fail(t, "Cannot parse back ", where, ": ", e)
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where (myInt32 +) > 1",
+		"select * from TestProto where myInt32 > (5)",
+		"select myString, myInt32 + 1, count(*) from TestProto group-by myString",
+	} {
+		if _, e := parser.NewQuery(query, Log); e == nil {
+			Log.Fail(t, "Expected an error for ", query)
+			return
+		}
+	}
+	query := "select myString, myInt32 + 1, count(*) from TestProto group-by myString"
+	_, e := parser.NewQuery(query, Log)
+	var syntaxError *parser.SyntaxError
+	if !errors.As(e, &syntaxError) || syntaxError.Offset != strings.Index(query, "myInt32") {
+		Log.Fail(t, "Expected a syntax error at the arithmetic column, got ", e)
+	}
+}
+
+// TestArithmeticWhere verifies filtering by arithmetic operands.
+func TestArithmeticWhere(t *testing.T) {
+	items := arithmeticItems()
+	for query, expected := range map[string]string{
+		"select * from TestProto where myInt64 / myInt32 > 9.5":            "[n1 n2 n3 n4]",
+		"select * from TestProto where myInt32 / 4 >= 0.5":                 "[n2 n3 n4]",
+		"select * from TestProto where myInt32 % 2 = 0":                    "[n2 n4]",
+		"select * from TestProto where (myInt32 + myInt64) * 2 = 44":       "[n2]",
+		"select * from TestProto where -myInt32 < -2":                      "[n3 n4]",
+		"select * from TestProto where myUint32 - 3 < 0":                   "[n1 n2]",
+		"select * from TestProto where myFloat64 * 2 = myInt32":            "[n1 n2 n3 n4]",
+		"select * from TestProto where myInt32 = myInt64 / 10 - 1 + 1":     "[n1 n2 n3 n4]",
+		"select * from TestProto where 25 < myInt64 + myUint32":            "[n3 n4]",
+		"select * from TestProto where myInt32 * 3 > 6 and myString != n4": "[n3]",
+	} {
+		if !checkFilterNames(query, items, expected, t) {
+			return
+		}
+	}
+}
+
+// TestArithmeticDivisionByZero verifies that a division by zero follows the error policy.
+func TestArithmeticDivisionByZero(t *testing.T) {
+	items := arithmeticItems()
+	if !checkFilterNames("select * from TestProto where myInt64 / (myInt32 - 2) > 0", items, "[n3 n4]", t) {
+		return
+	}
+	q, _, e := createQuery("select * from TestProto where myInt64 % (myInt32 - 2) = 0")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	_, e = q.FilterWithError(items, false)
+	if !errors.Is(e, interpreter.ErrDivisionByZero) {
+		Log.Fail(t, "Expected a division by zero error, got ", e)
+	}
+}
+
+// TestArithmeticColumns verifies computed columns, their aliases and the promoted value types.
+func TestArithmeticColumns(t *testing.T) {
+	q, _, e := createQuery("select myString, myInt32+myInt64 as total, myInt32 * myUint32, myInt32 / 2 as half, " +
+		"myUint32 - 3 as diff, myFloat64 * 2 from TestProto sort-by myString")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if q.Query().Properties[2] != "myInt32 * myUint32" || q.Query().Properties[3] != "myInt32 / 2 as half" {
+		Log.Fail(t, "Unexpected canonical columns ", q.Query().Properties)
+		return
+	}
+	result, e := q.Execute(arithmeticItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(result.Rows) != 4 || len(result.Objects) != 4 {
+		Log.Fail(t, "Expected 4 rows and objects, got ", len(result.Rows), " and ", len(result.Objects))
+		return
+	}
+	row := result.Rows[0]
+	expected := map[string]interface{}{"myString": "n1", "total": int64(11), "myInt32 * myUint32": int64(1),
+		"half": 0.5, "diff": int64(-2), "myFloat64 * 2": 1.0}
+	for col, value := range expected {
+		if row[col] != value {
+			Log.Fail(t, "Expected ", value, " (", reflect.TypeOf(value), ") for ", col, ", got ", row[col], " (", reflect.TypeOf(row[col]), ")")
+			return
+		}
+	}
+	projected, e := q.Project(arithmeticItems()[3])
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if total, _ := projected.Get("total"); total != int64(44) {
+		Log.Fail(t, "Expected a projected total of 44, got ", total)
+	}
+}
+
+// TestArithmeticHaving verifies arithmetic over aggregate columns in HAVING.
+func TestArithmeticHaving(t *testing.T) {
+	items := append(arithmeticItems(), arithmeticItems()...)
+	checkExecuteRows("select myString, count(*), sum(myInt64) from TestProto group-by myString "+
+		"having sum(myInt64) / count(*) > 25 sort-by myString", items, []string{"myString"}, []string{"n3", "n4"}, t)
+}
@@ -0,0 +1,230 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// bucketItems returns objects whose MyInt64 holds the given epoch timestamps and whose
+// MyInt32 holds their index plus one.
+func bucketItems(timestamps ...int64) []interface{} {
+	items := make([]interface{}, 0, len(timestamps))
+	for i, ts := range timestamps {
+		node := CreateTestModelInstance(i)
+		node.MyInt64 = ts
+		node.MyInt32 = int32(i + 1)
+		items = append(items, node)
+	}
+	return items
+}
+
+// TestParseGroupExpression verifies the parsing and canonical form of group-by items.
+func TestParseGroupExpression(t *testing.T) {
+	valid := map[string]string{
+		"myInt64":                                 "myInt64",
+		"bucket(myInt64,5m)":                      "bucket(myInt64, 5m)",
+		"BUCKET( myInt64 , 1h30m , MS )":          "bucket(myInt64, 1h30m, ms)",
+		"bucket(myInt64, 1d, fill)":               "bucket(myInt64, 1d, fill)",
+		"date_trunc('HOUR', myInt64)":             "date_trunc('hour', myInt64)",
+		"date_trunc(\"month\", myInt64, s, fill)": "date_trunc('month', myInt64, s, fill)",
+	}
+	for text, expected := range valid {
+		expr, e := parser.ParseGroupExpression(text)
+		if e != nil {
+			Log.Fail(t, "Unexpected error for ", text, ": ", e)
+			return
+		}
+		if expr.String() != expected {
+			Log.Fail(t, "Expected ", expected, " for ", text, ", got ", expr.String())
+			return
+		}
+	}
+	expr, _ := parser.ParseGroupExpression("bucket(myInt64, 2w, ms, fill)")
+	if expr.Width != 14*24*time.Hour || expr.Epoch != parser.EpochMillis || !expr.Fill || expr.Field != "myInt64" {
+		Log.Fail(t, "Unexpected parsed expression ", expr)
+		return
+	}
+	invalid := map[string]string{"bucket(myInt64)": ")", "bucket(myInt64, 0m)": "0m", "bucket(myInt64, 5x)": "5x",
+		"bucket(myInt64, -5m)": "-", "date_trunc('fortnight', myInt64)": "'fortnight'", "date_trunc('hour', myInt64, ns)": "ns",
+		"bucket(myInt64, 5m, s, ms)": "ms", "round(myInt64, 5m)": "round", "bucket(, 5m)": ","}
+	for text, token := range invalid {
+		_, e := parser.ParseGroupExpression(text)
+		var se *parser.SyntaxError
+		if !errors.As(e, &se) || se.Token != token {
+			Log.Fail(t, "Expected a syntax error at ", token, " for ", text, ", got ", e)
+			return
+		}
+	}
+	query := "select count(*) from TestProto group-by bucket(myInt64, 5q)"
+	_, e := parser.NewQuery(query, Log)
+	var se *parser.SyntaxError
+	if !errors.As(e, &se) || se.Offset != strings.Index(query, "5q") {
+		Log.Fail(t, "Expected a syntax error at the invalid bucket width in a query, got ", e)
+	}
+}
+
+// TestGroupByBucket verifies grouping numeric timestamps into fixed width buckets,
+// keeping the unit and type of the timestamps.
+func TestGroupByBucket(t *testing.T) {
+	base := int64(1700000000) // 2023-11-14T22:13:20Z
+	items := bucketItems(base, base+10, base+299, base+601, base+1200)
+	if !checkGroups("select bucket(myInt64, 5m),count(*),sum(myInt32) from TestProto group-by bucket(myInt64, 5m)",
+		items, []string{"bucket(myInt64, 5m)", "count", "sumMyInt32"},
+		[]string{"1699999800,2,3", "1700000100,1,3", "1700000400,1,4", "1700001000,1,5"}, t) {
+		return
+	}
+	millis := bucketItems(base*1000, base*1000+10000, base*1000+299000, base*1000+601000)
+	if !checkGroups("select count(*) from TestProto group-by bucket(myInt64, 5m)",
+		millis, []string{"bucket(myInt64, 5m)", "count"},
+		[]string{"1699999800000,2", "1700000100000,1", "1700000400000,1"}, t) {
+		return
+	}
+	// An explicit unit overrides the detection by magnitude
+	if !checkGroups("select count(*) from TestProto group-by bucket(myInt64, 1s, ms)",
+		bucketItems(1500, 2500, 2999), []string{"bucket(myInt64, 1s, ms)", "count"},
+		[]string{"1000,1", "2000,2"}, t) {
+		return
+	}
+	q, _, e := createQuery("select count(*) from TestProto group-by bucket(myInt64, 5m)")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	rows := q.Aggregate(items)
+	if rows[0]["bucket(myInt64, 5m)"] != int64(1699999800) {
+		Log.Fail(t, "Expected an int64 bucket, got ", rows[0])
+	}
+}
+
+// TestGroupByDateTrunc verifies grouping timestamps into calendar intervals.
+func TestGroupByDateTrunc(t *testing.T) {
+	stamps := []time.Time{
+		time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2024, 2, 1, 0, 30, 0, 0, time.UTC),
+		time.Date(2024, 2, 4, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 5, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	timestamps := make([]int64, 0, len(stamps))
+	for _, stamp := range stamps {
+		timestamps = append(timestamps, stamp.Unix())
+	}
+	items := bucketItems(timestamps...)
+	day := func(y int, m time.Month, d int) int64 { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() }
+	expected := map[string][]int64{
+		"hour":  {day(2024, 1, 31) + 23*3600, day(2024, 2, 1), day(2024, 2, 4) + 12*3600, day(2024, 2, 5) + 8*3600, day(2024, 3, 1)},
+		"day":   {day(2024, 1, 31), day(2024, 2, 1), day(2024, 2, 4), day(2024, 2, 5), day(2024, 3, 1)},
+		"week":  {day(2024, 1, 29), day(2024, 2, 5), day(2024, 2, 26)},
+		"month": {day(2024, 1, 1), day(2024, 2, 1), day(2024, 3, 1)},
+		"year":  {day(2024, 1, 1)},
+	}
+	for unit, buckets := range expected {
+		column := "date_trunc('" + unit + "', myInt64)"
+		q, _, e := createQuery("select count(*) from TestProto group-by " + column)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		rows := q.Aggregate(items)
+		if len(rows) != len(buckets) {
+			Log.Fail(t, "Expected ", len(buckets), " rows for ", unit, ", got ", rows)
+			return
+		}
+		for i, bucket := range buckets {
+			if rows[i][column] != bucket {
+				Log.Fail(t, "Expected bucket ", bucket, " for ", unit, ", got ", rows[i])
+				return
+			}
+		}
+	}
+}
+
+// TestGroupByBucketFill verifies that gap filling adds empty buckets, per combination of
+// the other group-by values, with a zero count and nil aggregates.
+func TestGroupByBucketFill(t *testing.T) {
+	base := int64(1699999800)
+	items := bucketItems(base, base+900, base+60, base+600)
+	for i, item := range items {
+		item.(*testtypes.TestProto).MyString = []string{"a", "a", "b", "b"}[i]
+	}
+	if !checkGroups("select myString,count(*),sum(myInt32) from TestProto group-by myString,bucket(myInt64, 5m, fill)",
+		items, []string{"myString", "bucket(myInt64, 5m, fill)", "count", "sumMyInt32"},
+		[]string{"a,1699999800,1,1", "a,1700000100,0,<nil>", "a,1700000400,0,<nil>", "a,1700000700,1,2",
+			"b,1699999800,1,3", "b,1700000100,0,<nil>", "b,1700000400,1,4"}, t) {
+		return
+	}
+	if !checkGroups("select count(*) from TestProto group-by bucket(myInt64, 5m)",
+		items, []string{"bucket(myInt64, 5m)", "count"},
+		[]string{"1699999800,2", "1700000400,1", "1700000700,1"}, t) {
+		return
+	}
+	q, _, e := createQuery("select count(*) from TestProto group-by bucket(myInt64, 1s, fill)")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if _, e = q.Execute(bucketItems(0, 1000000)); e == nil {
+		Log.Fail(t, "Expected an error for too many filled buckets")
+	}
+}
+
+// TestGroupByBucketSortAndHaving verifies that bucket columns can be sorted and filtered by name.
+func TestGroupByBucketSortAndHaving(t *testing.T) {
+	base := int64(1699999800)
+	items := bucketItems(base, base+1, base+300, base+600, base+601, base+602)
+	checkGroups("select count(*) from TestProto group-by bucket(myInt64, 5m) having count(*) > 1 "+
+		"sort-by bucket(myInt64, 5m) descending",
+		items, []string{"bucket(myInt64, 5m)", "count"},
+		[]string{"1700000400,3", "1699999800,2"}, t)
+}
+
+// TestGroupByBucketErrors verifies the error policy for values that are not timestamps.
+func TestGroupByBucketErrors(t *testing.T) {
+	items := bucketItems(1699999800, 1700000100)
+	q, _, e := createQuery("select count(*) from TestProto group-by bucket(myString, 5m)")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	if _, e = q.Execute(items); e == nil {
+		Log.Fail(t, "Expected an error for a string bucket value")
+		return
+	}
+	q.SetErrorPolicy(interpreter.SkipOnError)
+	if rows := q.Aggregate(items); len(rows) != 0 {
+		Log.Fail(t, "Expected the objects to be skipped, got ", rows)
+	}
+}
+
+// TestSelectBucketColumn verifies that a selected time bucketing column must be grouped by.
+func TestSelectBucketColumn(t *testing.T) {
+	if _, _, e := createQuery("select bucket(myInt64,5m),count(*) from TestProto group-by bucket(myInt64, 5m)"); e != nil {
+		Log.Fail(t, "Unexpected error for a grouped bucket column: ", e)
+		return
+	}
+	if _, _, e := createQuery("select bucket(myInt64, 5m),count(*) from TestProto group-by myString"); e == nil {
+		Log.Fail(t, "Expected an error for a bucket column that is not grouped by")
+	}
+}
@@ -0,0 +1,151 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// distinctItems returns objects with duplicate values in MyString, MyInt32, MyStringSlice and MySingle.
+func distinctItems() []interface{} {
+	values := []struct {
+		region string
+		model  int32
+		tags   []string
+	}{{"A", 1, []string{"x", "y"}}, {"B", 1, []string{"x"}}, {"A", 1, []string{"x", "y"}},
+		{"A", 2, []string{}}, {"B", 1, nil}, {"A", 1, []string{"y", "x"}}}
+	items := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		node := CreateTestModelInstance(i)
+		node.MyString = value.region
+		node.MyInt32 = value.model
+		node.MyStringSlice = value.tags
+		node.MySingle = &testtypes.TestProtoSub{MyString: "single", MyInt64: int64(i % 2)}
+		items = append(items, node)
+	}
+	return items
+}
+
+// checkExecuteRows executes the query over the items and verifies the result rows, written
+// as the values of the given columns separated by commas.
+func checkExecuteRows(query string, items []interface{}, columns []string, expected []string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	rows := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, fmt.Sprint(row[column]))
+		}
+		rows = append(rows, strings.Join(values, ","))
+	}
+	if strings.Join(rows, " ") != strings.Join(expected, " ") {
+		Log.Fail(t, "Expected ", expected, " for ", query, ", got ", rows)
+		return false
+	}
+	return true
+}
+
+// TestParseDistinct verifies the parsing and validation of distinct projections.
+func TestParseDistinct(t *testing.T) {
+	q, e := parser.NewQuery("select distinct myString, myInt32, count(*) from TestProto", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if !parser.IsDistinct(q.Query()) || len(parser.DistinctColumns(q.Query())) != 2 || len(q.Query().Aggregates) != 1 {
+		Log.Fail(t, "Expected a distinct projection with a count, got ", q.Query().Properties, q.Query().Aggregates)
+		return
+	}
+	q, e = parser.NewQuery("select myString from TestProto", Log)
+	if e != nil || parser.IsDistinct(q.Query()) {
+		Log.Fail(t, "Expected no distinct projection")
+		return
+	}
+	invalid := map[string]string{"select distinct sum(myInt32) from TestProto": "sum", "select distinct * from TestProto": "*",
+		"select distinct count(*) from TestProto": "distinct", "select distinct myString from TestProto group-by myString": "distinct",
+		"select distinct myString, row_number() from TestProto": "row_number", "select distinct myString, count(*), count(*) from TestProto": "count",
+		"select distinct myString, count(*) from TestProto having count(*) > 1": "distinct"}
+	for query, token := range invalid {
+		_, e = parser.NewQuery(query, Log)
+		var syntaxError *parser.SyntaxError
+		if !errors.As(e, &syntaxError) || syntaxError.Token != token || syntaxError.Offset != strings.LastIndex(query, token) {
+			Log.Fail(t, "Expected a syntax error at the last ", token, " of ", query, ", got ", e)
+			return
+		}
+	}
+}
+
+// TestDistinct verifies the unique rows of a distinct projection, their order and the count of duplicates.
+func TestDistinct(t *testing.T) {
+	items := distinctItems()
+	q, _, e := createQuery("select distinct myString, myInt32, count(*) from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if !q.IsDistinct() || q.IsAggregate() {
+		Log.Fail(t, "Expected a distinct, non-aggregate query")
+		return
+	}
+	if !checkExecuteRows("select distinct myString, myInt32, count(*) from TestProto", items,
+		[]string{"myString", "myInt32", "count"}, []string{"A,1,3", "B,1,2", "A,2,1"}, t) {
+		return
+	}
+	if !checkExecuteRows("select distinct myString, myInt32 from TestProto where myInt32 = 1 sort-by myString desc", items,
+		[]string{"myString", "myInt32", "count"}, []string{"B,1,<nil>", "A,1,<nil>"}, t) {
+		return
+	}
+	checkExecuteRows("select distinct myString, myInt32 from TestProto sort-by myString limit 2 page 1", items,
+		[]string{"myString", "myInt32"}, []string{"B,1"}, t)
+}
+
+// TestDistinctNestedValues verifies that repeated fields and nested messages are compared by contents.
+func TestDistinctNestedValues(t *testing.T) {
+	items := distinctItems()
+	if !checkExecuteRows("select distinct myStringSlice, count(*) from TestProto", items,
+		[]string{"myStringSlice", "count"}, []string{"[x y],2", "[x],1", "[],2", "[y x],1"}, t) {
+		return
+	}
+	q, _, e := createQuery("select distinct mySingle, count(*) from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(result.Rows) != 2 || result.Rows[0]["count"] != int64(3) || result.Rows[1]["count"] != int64(3) ||
+		result.Total != 2 || result.Rows[0]["mySingle"] == nil {
+		Log.Fail(t, "Expected 2 distinct nested messages, got ", result.Rows)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"testing"
+
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// executeItems creates test objects whose myInt32 values are 5, 3, 8, 3, 1, 9, 3, 7
+// and whose myString values are their positions in the list.
+func executeItems() []interface{} {
+	items := make([]interface{}, 0)
+	for i, v := range []int32{5, 3, 8, 3, 1, 9, 3, 7} {
+		node := CreateTestModelInstance(i)
+		node.MyString = "item-" + string(rune('a'+i))
+		node.MyInt32 = v
+		items = append(items, node)
+	}
+	return items
+}
+
+// myStrings returns the myString values of the result objects.
+func myStrings(objects []interface{}) []string {
+	result := make([]string, 0, len(objects))
+	for _, o := range objects {
+		result = append(result, o.(*testtypes.TestProto).MyString)
+	}
+	return result
+}
+
+// checkStrings fails the test if the values are not the expected ones, in order.
+func checkStrings(values, expected []string, t *testing.T) bool {
+	if len(values) != len(expected) {
+		Log.Fail(t, "Expected ", expected, " but got ", values)
+		return false
+	}
+	for i := range values {
+		if values[i] != expected[i] {
+			Log.Fail(t, "Expected ", expected, " but got ", values)
+			return false
+		}
+	}
+	return true
+}
+
+// TestExecuteSortIsStable verifies that equal sort values keep their original order.
+func TestExecuteSortIsStable(t *testing.T) {
+	q, _, e := createQuery("select * from testproto where myint32>1 sort-by myint32")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(executeItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Total != 7 {
+		Log.Fail(t, "Expected a total of 7, got ", result.Total)
+		return
+	}
+	checkStrings(myStrings(result.Objects), []string{"item-b", "item-d", "item-g", "item-a", "item-h", "item-c", "item-f"}, t)
+}
+
+// TestExecuteDescendingPage verifies descending sort with limit and a zero-based page.
+func TestExecuteDescendingPage(t *testing.T) {
+	q, _, e := createQuery("select * from testproto sort-by myint32 descending limit 3 page 1")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(executeItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Total != 8 {
+		Log.Fail(t, "Expected a total of 8, got ", result.Total)
+		return
+	}
+	if !checkStrings(myStrings(result.Objects), []string{"item-a", "item-b", "item-d"}, t) {
+		return
+	}
+	q, _, _ = createQuery("select * from testproto sort-by myint32 limit 3 page 5")
+	result, _ = q.Execute(executeItems())
+	if len(result.Objects) != 0 || result.Total != 8 {
+		Log.Fail(t, "Expected an empty page past the end")
+	}
+}
+
+// TestExecuteProjection verifies that only the selected columns are returned.
+func TestExecuteProjection(t *testing.T) {
+	q, _, e := createQuery("select mystring from testproto where myint32=9")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	items := executeItems()
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(result.Objects) != 1 {
+		Log.Fail(t, "Expected one object, got ", len(result.Objects))
+		return
+	}
+	obj := result.Objects[0].(*testtypes.TestProto)
+	if obj.MyString != "item-f" || obj.MyInt32 != 0 || obj == items[5] {
+		Log.Fail(t, "Expected a clone with only mystring")
+	}
+}
+
+// TestExecuteAggregate verifies that aggregate queries return the aggregate rows.
+func TestExecuteAggregate(t *testing.T) {
+	q, _, e := createQuery("select myint32,count(*) from testproto where myint32<8 group-by myint32")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(executeItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Total != 4 || len(result.Rows) != 4 || len(result.Objects) != 0 {
+		Log.Fail(t, "Expected 4 rows, got ", len(result.Rows))
+		return
+	}
+	for _, row := range result.Rows {
+		if row["myint32"] == int32(3) && row["count"] != int64(3) {
+			Log.Fail(t, "Expected a count of 3 for 3, got ", row["count"])
+		}
+	}
+}
+
+// TestExecuteMultiColumnSort verifies sorting by several keys with their own directions.
+func TestExecuteMultiColumnSort(t *testing.T) {
+	items := executeItems()
+	for i, item := range items {
+		item.(*testtypes.TestProto).MyBool = i%2 == 0
+	}
+	q, _, e := createQuery("select * from testproto sort-by mybool desc, myint32, mystring desc")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	checkStrings(myStrings(result.Objects), []string{"item-e", "item-g", "item-a", "item-c", "item-d", "item-b", "item-h", "item-f"}, t)
+}
+
+// TestExecuteSortNulls verifies the nulls first and nulls last orders.
+func TestExecuteSortNulls(t *testing.T) {
+	items := executeItems()[:4]
+	for i, item := range items {
+		node := item.(*testtypes.TestProto)
+		node.MySingle = nil
+		if i%2 == 1 {
+			node.MySingle = &testtypes.TestProtoSub{MyString: string(rune('z' - i))}
+		}
+	}
+	texts := map[string][]string{
+		"sort-by mysingle.mystring":                  {"item-a", "item-c", "item-d", "item-b"},
+		"sort-by mysingle.mystring nulls last":       {"item-d", "item-b", "item-a", "item-c"},
+		"sort-by mysingle.mystring desc":             {"item-b", "item-d", "item-a", "item-c"},
+		"sort-by mysingle.mystring desc nulls first": {"item-a", "item-c", "item-b", "item-d"},
+	}
+	for text, expected := range texts {
+		q, _, e := createQuery("select * from testproto " + text)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		result, e := q.Execute(items)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		if !checkStrings(myStrings(result.Objects), expected, t) {
+			Log.Fail(t, "Failed on ", text)
+			return
+		}
+	}
+}
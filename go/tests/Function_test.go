@@ -0,0 +1,170 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// functionItems returns objects with MyString " Host-1 ".." Host-4 " padded with spaces,
+// MyInt32 1..4, MyInt64 -10 times MyInt32 and MyFloat64 MyInt32 plus 0.25 times MyInt32.
+func functionItems() []interface{} {
+	items := make([]interface{}, 0, 4)
+	for i := 1; i <= 4; i++ {
+		node := CreateTestModelInstance(i)
+		node.MyString = fmt.Sprint(" Host-", i, " ")
+		node.MyInt32 = int32(i)
+		node.MyInt64 = int64(i) * -10
+		node.MyFloat64 = float64(i) * 1.25
+		items = append(items, node)
+	}
+	return items
+}
+
+// TestParseFunctions verifies the canonical text of function calls and the validation of their arguments.
+func TestParseFunctions(t *testing.T) {
+	for text, expected := range map[string]string{
+		"LOWER(a)":                   "lower(a)",
+		"substr(a,2,3)":              "substr(a, 2, 3)",
+		"concat(a, '-', b)":          "concat(a, '-', b)",
+		"concat(a, 'it''s')":         "concat(a, 'it''s')",
+		"cast(a + 1 as STRING)":      "cast(a + 1 as string)",
+		"round(a / 3, 2) * 10":       "round(a / 3, 2) * 10",
+		"coalesce(a, b, 0)":          "coalesce(a, b, 0)",
+		"len(trim(upper(a)))":        "len(trim(upper(a)))",
+		"starts_with(lower(a), 'x')": "starts_with(lower(a), 'x')",
+	} {
+		expr, ok, e := parser.ParseArithmetic(text)
+		if e != nil || !ok || expr.String() != expected {
+			Log.Fail(t, "Expected ", expected, " for ", text, ", got ", expr, ok, e)
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where lower(myString) = abc and contains(myString, 'x')",
+		"select * from TestProto where not ends_with(myString, 'x') or len(myString) > 3",
+		"select lower(myString) as host, cast(myInt32 as float) from TestProto sort-by lower(myString)",
+		"select lower(myString), count(*) from TestProto group-by lower(myString)",
+	} {
+		if !checkQuery(query, false, t) {
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where lower(myString, myInt32) = x",
+		"select * from TestProto where substr(myString) = x",
+		"select cast(myInt32 as date) from TestProto",
+		"select round(myFloat64, myInt32) from TestProto",
+		"select * from TestProto where lower(myString)",
+		"select upper(myString), count(*) from TestProto group-by myString",
+	} {
+		if !checkQuery(query, true, t) {
+			return
+		}
+	}
+}
+
+// TestFunctionWhere verifies filtering by function calls and boolean function predicates.
+func TestFunctionWhere(t *testing.T) {
+	items := functionItems()
+	for query, expected := range map[string]string{
+		"select * from TestProto where trim(myString) = 'Host-2'":                   "[ Host-2 ]",
+		"select * from TestProto where upper(trim(myString)) = 'HOST-3' match-case": "[ Host-3 ]",
+		"select * from TestProto where lower(trim(myString)) = 'HOST-3' match-case": "[]",
+		"select * from TestProto where ends_with(trim(myString), '4')":              "[ Host-4 ]",
+		"select * from TestProto where contains(myString, 'host') or myInt32 = 1":   "[ Host-1 ]",
+		"select * from TestProto where not starts_with(myString, ' Host-1')":        "[ Host-2   Host-3   Host-4 ]",
+		"select * from TestProto where len(myString) = 8 and abs(myInt64) > 25":     "[ Host-3   Host-4 ]",
+		"select * from TestProto where substr(trim(myString), 6) = '2'":             "[ Host-2 ]",
+		"select * from TestProto where cast(myInt32 as string) = '1'":               "[ Host-1 ]",
+		"select * from TestProto where floor(myFloat64) = 2 or ceil(myFloat64) = 5": "[ Host-2   Host-4 ]",
+		"select * from TestProto where round(myFloat64) = 4":                        "[ Host-3 ]",
+		"select * from TestProto where concat(myInt32, '-', myInt32 * 2) = '2-4'":   "[ Host-2 ]",
+	} {
+		if !checkFilterNames(query, items, expected, t) {
+			return
+		}
+	}
+}
+
+// TestFunctionColumns verifies computed function columns and the types of their values.
+func TestFunctionColumns(t *testing.T) {
+	q, _, e := createQuery("select lower(trim(myString)) as host, len(myString), substr(trim(myString), 1, 4) as prefix, " +
+		"abs(myInt64), round(myFloat64 / 3, 2) as third, coalesce(myInt32, 0), cast(myFloat64 as int) as whole, " +
+		"cast(myInt32 as bool) as flag, cast(myInt32 as uint) as unsigned from TestProto sort-by myInt32 descending")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(functionItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(result.Rows) != 4 {
+		Log.Fail(t, "Expected 4 rows, got ", len(result.Rows))
+		return
+	}
+	expected := map[string]interface{}{"host": "host-4", "len(myString)": int64(8), "prefix": "Host",
+		"abs(myInt64)": int64(40), "third": 1.67, "coalesce(myInt32, 0)": int32(4), "whole": int64(5),
+		"flag": true, "unsigned": uint64(4)}
+	for col, value := range expected {
+		if result.Rows[0][col] != value {
+			Log.Fail(t, "Expected ", value, " (", reflect.TypeOf(value), ") for ", col, ", got ",
+				result.Rows[0][col], " (", reflect.TypeOf(result.Rows[0][col]), ")")
+			return
+		}
+	}
+}
+
+// TestFunctionGroupAndSort verifies grouping and sorting by function calls.
+func TestFunctionGroupAndSort(t *testing.T) {
+	items := functionItems()
+	for _, item := range items[2:] {
+		reflect.ValueOf(item).Elem().FieldByName("MyString").SetString(" HOST-1")
+	}
+	if !checkExecuteRows("select trim(lower(myString)) as host, count(*) from TestProto group-by trim(lower(myString)) "+
+		"sort-by host", items, []string{"host", "count"}, []string{"host-1,3", "host-2,1"}, t) {
+		return
+	}
+	if !checkExecuteRows("select trim(lower(myString)), sum(myInt32) from TestProto group-by trim(lower(myString)) "+
+		"having sum(myInt32) > 2 sort-by trim(lower(myString))", items, []string{"trim(lower(myString))", "sumMyInt32"},
+		[]string{"host-1,8"}, t) {
+		return
+	}
+	q, _, e := createQuery("select * from TestProto sort-by abs(myInt64) - myInt32 * 20")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	order := make([]int32, 0, len(result.Objects))
+	for _, obj := range result.Objects {
+		order = append(order, obj.(*testtypes.TestProto).MyInt32)
+	}
+	if fmt.Sprint(order) != "[4 3 2 1]" {
+		Log.Fail(t, "Expected the objects sorted by the expression, got ", order)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+)
+
+// regionItems returns objects with a region in MyString, a site in MyInt32 and a value in MyInt64.
+func regionItems() []interface{} {
+	rows := []struct {
+		region string
+		site   int32
+		value  int64
+	}{{"A", 1, 10}, {"B", 1, 7}, {"A", 2, 5}, {"A", 1, 20}}
+	items := make([]interface{}, 0, len(rows))
+	for i, row := range rows {
+		node := CreateTestModelInstance(i)
+		node.MyString = row.region
+		node.MyInt32 = row.site
+		node.MyInt64 = row.value
+		items = append(items, node)
+	}
+	return items
+}
+
+// TestParseGroupingSets verifies the parsing, canonical form and expansion of rollup and cube.
+func TestParseGroupingSets(t *testing.T) {
+	q, e := parser.NewQuery("select count(*) from TestProto group-by myBool, ROLLUP(myString,bucket(myInt64,5m))", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	groupBy := q.Query().GroupBy
+	if len(groupBy) != 2 || groupBy[0] != "myBool" || groupBy[1] != "rollup(myString, bucket(myInt64, 5m))" {
+		Log.Fail(t, "Unexpected group-by items ", groupBy)
+		return
+	}
+	columns, sets, e := parser.GroupingSets(groupBy)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(columns) != 3 || columns[2].Function != parser.BucketFunction || len(sets) != 3 ||
+		!sets[0][2] || sets[1][2] || !sets[1][1] || sets[2][1] || !sets[2][0] {
+		Log.Fail(t, "Unexpected rollup columns ", columns, " and sets ", sets)
+		return
+	}
+	_, sets, _ = parser.GroupingSets([]string{"cube(a, b, c)"})
+	if len(sets) != 8 || !sets[0][0] || !sets[0][2] || sets[1][2] || sets[7][0] {
+		Log.Fail(t, "Unexpected cube sets ", sets)
+		return
+	}
+	_, sets, _ = parser.GroupingSets([]string{"rollup(a, b)", "cube(a2)"})
+	if len(sets) != 6 {
+		Log.Fail(t, "Expected the product of the grouping sets, got ", sets)
+		return
+	}
+	for _, n := range []int{13, 22, 63, 70} {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = fmt.Sprint("c", i)
+		}
+		for _, fn := range []string{"cube", "rollup"} {
+			item := fn + "(" + strings.Join(names, ", ") + ")"
+			_, sets, e = parser.GroupingSets([]string{item})
+			if (fn == "cube" || n > parser.MaxGroupingColumns) != (e != nil) || e != nil && sets != nil {
+				Log.Fail(t, "Unexpected grouping sets of ", fn, " over ", n, " columns: ", len(sets), " ", e)
+				return
+			}
+			var syntaxError *parser.SyntaxError
+			if e != nil && (!errors.As(e, &syntaxError) || syntaxError.Token != fn) {
+				Log.Fail(t, "Expected a syntax error at ", fn, ", got ", e)
+				return
+			}
+		}
+	}
+	invalid := map[string]string{"group-by rollup()": "rollup", "group-by rollup(myString, myString)": "rollup",
+		"group-by myString, cube(myString)": "cube", "group-by cube(myString, bucket(myInt64))": ")",
+		"group-by rollup(myString": "rollup", "group-by myString, cube(myString) x": "cube"}
+	for clause, token := range invalid {
+		_, e = parser.NewQuery("select count(*) from TestProto "+clause, Log)
+		var syntaxError *parser.SyntaxError
+		if !errors.As(e, &syntaxError) || syntaxError.Token != token {
+			Log.Fail(t, "Expected a syntax error at ", token, " for ", clause, ", got ", e)
+			return
+		}
+	}
+}
+
+// TestGroupByRollup verifies the rows, subtotals and grand total of a rollup.
+func TestGroupByRollup(t *testing.T) {
+	items := regionItems()
+	columns := []string{"myString", "myInt32", "count", "sumMyInt64", interpreter.GroupingColumn}
+	if !checkGroups("select myString,myInt32,count(*),sum(myInt64) from TestProto group-by rollup(myString, myInt32)",
+		items, columns,
+		[]string{"A,1,2,30,0", "A,2,1,5,0", "A,<nil>,3,35,1", "B,1,1,7,0", "B,<nil>,1,7,1", "<nil>,<nil>,4,42,3"}, t) {
+		return
+	}
+	if !checkGroups("select count(*),sum(myInt64) from TestProto group-by myString, rollup(myInt32)",
+		items, columns,
+		[]string{"A,1,2,30,0", "A,2,1,5,0", "A,<nil>,3,35,1", "B,1,1,7,0", "B,<nil>,1,7,1"}, t) {
+		return
+	}
+	// A rolled up column differs from a nil group-by value
+	unnest := unnestItems()
+	if !checkGroups("select count(*) from TestProto group-by rollup(myStringSlice)",
+		unnest, []string{"myStringSlice", "count", interpreter.GroupingColumn},
+		[]string{"<nil>,1,0", "FR,1,0", "UK,2,0", "US,2,0", "<nil>,4,1"}, t) {
+		return
+	}
+	if !checkGroups("select count(*) from TestProto group-by rollup(myString, myInt32) having grouping = 1",
+		items, columns, []string{"A,<nil>,3,<nil>,1", "B,<nil>,1,<nil>,1"}, t) {
+		return
+	}
+	checkGroups("select count(*) from TestProto group-by rollup(myString, myInt32) sort-by count(*) descending limit 2",
+		items, []string{"myString", "myInt32", "count"}, []string{"<nil>,<nil>,4", "A,<nil>,3"}, t)
+}
+
+// TestGroupByCube verifies the rows of all the subsets of the columns of a cube.
+func TestGroupByCube(t *testing.T) {
+	checkGroups("select count(*),sum(myInt64) from TestProto group-by cube(myString, myInt32)",
+		regionItems(), []string{"myString", "myInt32", "count", "sumMyInt64", interpreter.GroupingColumn},
+		[]string{"A,1,2,30,0", "A,2,1,5,0", "A,<nil>,3,35,1", "B,1,1,7,0", "B,<nil>,1,7,1",
+			"<nil>,1,3,37,2", "<nil>,2,1,5,2", "<nil>,<nil>,4,42,3"}, t)
+}
+
+// TestGroupingSetsTypes verifies the type of the grouping column and that plain group-by rows have none.
+func TestGroupingSetsTypes(t *testing.T) {
+	q, _, e := createQuery("select count(*) from TestProto group-by rollup(myString)")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(regionItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Types[interpreter.GroupingColumn] == nil || result.Types[interpreter.GroupingColumn].Kind().String() != "int64" {
+		Log.Fail(t, "Expected an int64 grouping column, got ", result.Types)
+		return
+	}
+	rows := q.Aggregate(regionItems())
+	if rows[len(rows)-1][interpreter.GroupingColumn] != int64(1) {
+		Log.Fail(t, "Expected the grand total last, got ", rows)
+		return
+	}
+	q, _, _ = createQuery("select count(*) from TestProto group-by myString")
+	if _, ok := q.Aggregate(regionItems())[0][interpreter.GroupingColumn]; ok {
+		Log.Fail(t, "Expected no grouping column without rollup or cube")
+	}
+}
+
+// TestGroupingSetsFillAndMerge verifies gap filling within grouping sets and merging their partial states.
+func TestGroupingSetsFillAndMerge(t *testing.T) {
+	base := int64(1699999800)
+	items := bucketItems(base, base+900)
+	if !checkGroups("select count(*) from TestProto group-by rollup(bucket(myInt64, 5m, fill))",
+		items, []string{"bucket(myInt64, 5m, fill)", "count", interpreter.GroupingColumn},
+		[]string{"1699999800,1,0", "1700000100,0,0", "1700000400,0,0", "1700000700,1,0", "<nil>,2,1"}, t) {
+		return
+	}
+	if !checkMergedAggregate("select count(*),sum(myInt32) from TestProto group-by cube(myString, myInt32)", t) {
+		return
+	}
+	checkMergedAggregate("select count(*) from TestProto group-by myString, rollup(myInt32) having grouping = 1", t)
+}
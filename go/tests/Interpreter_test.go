@@ -18,8 +18,13 @@ package tests
 // verifying that queries correctly match and filter data objects.
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
 	. "github.com/saichler/l8test/go/infra/t_resources"
 	"github.com/saichler/l8types/go/testtypes"
 )
@@ -174,3 +179,241 @@ func TestValueOf(t *testing.T) {
 		Log.Fail(t, "V2 does not match")
 	}
 }
+
+// TestSyntaxErrorFromInterpreter verifies that interpreter.NewQuery returns the parser SyntaxError.
+func TestSyntaxErrorFromInterpreter(t *testing.T) {
+	_, _, e := createQuery("select * from testproto where mystring='abc")
+	var se *parser.SyntaxError
+	if !errors.As(e, &se) {
+		Log.Fail(t, "Expected a SyntaxError, got ", e)
+		return
+	}
+	if se.Column != 40 {
+		Log.Fail(t, "Expected column 40, got ", se.Column)
+	}
+}
+
+// TestMatchNot tests matching of negated comparisons and negated groups.
+func TestMatchNot(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	node.MyString = "hello"
+	node.MyInt32 = 5
+	if !checkMatch("select * from testproto where not mystring=world", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where not mystring=hello", node, false, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where myint32=5 and not (mystring=world or myint32>10)", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where myint32=5 and not (mystring=hello or myint32>10)", node, false, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where not myint32=5 or mystring=hello", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where not mystring=hel*", node, false, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where not mystring=wor*", node, true, t) {
+		return
+	}
+}
+
+// TestMatchPrecedence verifies that AND is evaluated before OR.
+func TestMatchPrecedence(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	node.MyString = "hello"
+	node.MyInt32 = 5
+	if !checkMatch("select * from testproto where mystring=hello or myint32=6 and mybool=true", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where myint32=6 and mybool=true or mystring=hello", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where (mystring=hello or myint32=6) and myint32=7", node, false, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where mystring=world or myint32=5 and (mystring=hello or myint32=6)", node, true, t) {
+		return
+	}
+}
+
+// TestMatchFloat verifies all comparators against float32 and float64 properties.
+func TestMatchFloat(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	node.MyFloat64 = 0.1 + 0.2
+	node.MyFloat32 = 0.1
+	matches := map[string]bool{
+		"myfloat64=0.3":              true,
+		"myfloat64!=0.3":             false,
+		"myfloat64>0.3":              false,
+		"myfloat64>=0.3":             true,
+		"myfloat64<0.3":              false,
+		"myfloat64<=0.3":             true,
+		"myfloat64>0.25":             true,
+		"myfloat64<3e-1":             false,
+		"myfloat64=3E-1":             true,
+		"myfloat64>2.5e-1":           true,
+		"myfloat64 in [0.1,0.3]":     true,
+		"myfloat64 not in [0.1,0.3]": false,
+		"myfloat64 in [1e-1,2e-1]":   false,
+		"myfloat32=0.1":              true,
+		"myfloat32>=1e-1":            true,
+		"myfloat32<0.1":              false,
+		"myfloat32 in [0.1]":         true,
+		"myint64=1e3":                true,
+		"myint32<1e1":                true,
+	}
+	for where, expected := range matches {
+		if !checkMatch("select * from testproto where "+where, node, expected, t) {
+			return
+		}
+	}
+}
+
+// TestMatchIntegerWithFraction verifies that integer properties are compared with
+// literals that have a fraction as floating point values.
+func TestMatchIntegerWithFraction(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	matches := map[string]bool{
+		"myint32>0.5":              true,
+		"myint32!=0.5":             true,
+		"myint32=1.5":              false,
+		"myint32<=1.5":             true,
+		"myint32>=1.5":             false,
+		"myuint32<1.5":             true,
+		"myuint32>='0.5'":          true,
+		"myint32 in [0.5,1.0]":     true,
+		"myint32 in [0.5,1.5]":     false,
+		"myint32 not in [0.5,1.5]": true,
+	}
+	for where, expected := range matches {
+		if !checkMatch("select * from testproto where "+where, node, expected, t) {
+			return
+		}
+	}
+}
+
+// TestEvaluationErrorPolicy verifies that evaluation failures are returned as errors
+// and handled by the query's error policy instead of panicking.
+func TestEvaluationErrorPolicy(t *testing.T) {
+	q, _, e := createQuery("select * from testproto where mysingle > 5")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	var nilNode *testtypes.TestProto
+	list := []interface{}{CreateTestModelInstance(1), nilNode, CreateTestModelInstance(2)}
+	if q.Match(list[0]) {
+		Log.Fail(t, "Expected no match for a failed evaluation")
+		return
+	}
+	result, e := q.FilterWithError(list, false)
+	if e != nil || len(result) != 0 {
+		Log.Fail(t, "Expected skipped objects and no error, got ", len(result), " ", e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	result, e = q.FilterWithError(list, false)
+	evalErr := &interpreter.EvaluationError{}
+	if !errors.As(e, &evalErr) || result != nil {
+		Log.Fail(t, "Expected an EvaluationError, got ", e)
+		return
+	}
+	if evalErr.Operator != ">" || evalErr.LeftKind == reflect.Invalid || !strings.Contains(strings.ToLower(evalErr.Property), "mysingle") {
+		Log.Fail(t, "Unexpected evaluation error ", evalErr.Error())
+		return
+	}
+	if len(q.Filter(list, false)) != 0 {
+		Log.Fail(t, "Expected an aborted filter to return no objects")
+		return
+	}
+
+	q, _, e = createQuery("select * from testproto where mystring=string-2 or mysingle > 5")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	if _, e = q.FilterWithError(list[2:], false); e != nil {
+		Log.Fail(t, "Expected the OR to short-circuit before the failing comparison: ", e)
+	}
+}
+
+// TestMatchPropertyToProperty verifies comparisons between two properties of the same object.
+func TestMatchPropertyToProperty(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	node.MyInt32 = 5
+	node.MyInt64 = 7
+	node.MyUint32 = 5
+	node.MyFloat64 = 5.5
+	node.MyString = "Hello"
+	node.MySingle = &testtypes.TestProtoSub{MyString: "hello"}
+	matches := map[string]bool{
+		"myint32<myint64":               true,
+		"myint32>myint64":               false,
+		"myint32=myuint32":              true,
+		"myint32!=myuint32":             false,
+		"myint32>=myuint32":             true,
+		"myint64<=myint32":              false,
+		"myfloat64>myint32":             true,
+		"myint32<myfloat64":             true,
+		"myint32=myfloat64":             false,
+		"mystring=mysingle.mystring":    true,
+		"mystring!=mysingle.mystring":   false,
+		"mystring in mysingle.mystring": true,
+		"myint32=myint64 or myint32=myuint32 and myint64>myfloat64": true,
+	}
+	for where, expected := range matches {
+		if !checkMatch("select * from testproto where "+where, node, expected, t) {
+			Log.Fail(t, "Failed on ", where)
+			return
+		}
+	}
+	if !checkMatch("select * from testproto where mystring=mysingle.mystring match-case", node, false, t) {
+		return
+	}
+}
+
+// TestMatchPropertyToPropertyMultiValue verifies any/all semantics when either side holds several values.
+func TestMatchPropertyToPropertyMultiValue(t *testing.T) {
+	node := CreateTestModelInstance(1)
+	node.MyInt32 = 5
+	node.MyInt32Slice = []int32{3, 5, 8}
+	node.MyString = "b"
+	node.MyStringSlice = []string{"a", "b"}
+	node.MyString2StringMap = map[string]string{"x": "c", "y": "a"}
+	matches := map[string]bool{
+		"myint32=myint32slice":                    true,
+		"myint32!=myint32slice":                   false,
+		"myint32 in myint32slice":                 true,
+		"myint32 not in myint32slice":             false,
+		"myint32<myint32slice":                    true,
+		"myint32>myint32slice":                    true,
+		"myint32slice>=myint32":                   true,
+		"mystring=mystringslice":                  true,
+		"mystring=mystring2stringmap":             false,
+		"mystring!=mystring2stringmap":            true,
+		"mystringslice=mystring2stringmap":        true,
+		"mystringslice not in mystring2stringmap": false,
+		"myint32slice=5":                          true,
+		"myint32slice!=5":                         false,
+		"myint32slice>8":                          false,
+		"myint32slice in [1,8]":                   true,
+	}
+	for where, expected := range matches {
+		if !checkMatch("select * from testproto where "+where, node, expected, t) {
+			Log.Fail(t, "Failed on ", where)
+			return
+		}
+	}
+	node.MyInt32Slice = []int32{}
+	if !checkMatch("select * from testproto where myint32!=myint32slice", node, true, t) {
+		return
+	}
+	if !checkMatch("select * from testproto where myint32=myint32slice", node, false, t) {
+		return
+	}
+}
@@ -0,0 +1,173 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// likeItems returns objects with MyString "Edge-10%", "edge_1", "core-1.lan" and "a*b",
+// and MyInt32 1..4.
+func likeItems() []interface{} {
+	items := make([]interface{}, 0, 4)
+	for i, name := range []string{"Edge-10%", "edge_1", "core-1.lan", "a*b"} {
+		node := CreateTestModelInstance(i + 1)
+		node.MyString = name
+		node.MyInt32 = int32(i + 1)
+		items = append(items, node)
+	}
+	return items
+}
+
+// TestParseLike verifies the canonical text of like, ilike and glob comparisons and the
+// validation of their patterns and escape clauses.
+func TestParseLike(t *testing.T) {
+	for query, expected := range map[string]string{
+		"select * from TestProto where myString LIKE 'Edge%'":               "(mystring like 'Edge%')",
+		"select * from TestProto where myString ilike 'e_' and myInt32 > 1": "(mystring ilike 'e_' and myint32>1)",
+		"select * from TestProto where myString Glob '*.lan'":               "(mystring glob '*.lan')",
+		"select * from TestProto where myString like '10!%' ESCAPE '!'":     "(mystring like '10!%' escape '!')",
+		"select * from TestProto where not myString like 'a%' escape ''":    "(mystring not like 'a%' escape '')",
+		`select * from TestProto where myString like 'a\%b\n'`:              `(mystring like 'a\%b` + "\n')",
+	} {
+		q, e := parser.NewQuery(query, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		if where := parser.StringExpression(q.Query().Criteria); where != expected {
+			Log.Fail(t, "Expected ", expected, " for ", query, ", got ", where)
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where myString like 'a%' and myInt32 like 1%",
+		"select * from TestProto where myString like 'a%' escape '!' sort-by myInt32",
+		"select * from TestProto where (myInt32 + 1) like '2%'",
+		"select * from TestProto where myString like concat('a', '%')",
+	} {
+		if _, e := parser.NewQuery(query, Log); e != nil {
+			Log.Fail(t, "Unexpected error for ", query, ": ", e)
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where myString glob 'a*' escape '!'",
+		"select * from TestProto where myString like 'a%' escape 'ab'",
+		"select * from TestProto where myString like 'a%' escape myInt32",
+		"select * from TestProto where myString like escape '!'",
+		"select * from TestProto where myString like 'a%' escape",
+	} {
+		if _, e := parser.NewQuery(query, Log); e == nil {
+			Log.Fail(t, "Expected a syntax error for ", query)
+			return
+		}
+	}
+	for _, query := range []string{
+		"select * from TestProto where myString like 'a!b' escape '!'",
+		"select * from TestProto where myString like 'ab!' escape '!'",
+	} {
+		if !checkQuery(query, true, t) {
+			return
+		}
+	}
+}
+
+// TestLikeFilter verifies the anchored matching of like, ilike and glob patterns, their
+// escapes and their case sensitivity.
+func TestLikeFilter(t *testing.T) {
+	items := likeItems()
+	for query, expected := range map[string]string{
+		"select * from TestProto where myString like 'edge%'":                             "[edge_1]",
+		"select * from TestProto where myString like 'EDGE%'":                             "[]",
+		"select * from TestProto where myString like 'edge%' match-case":                  "[edge_1]",
+		"select * from TestProto where myString ilike 'EDGE%'":                            "[Edge-10% edge_1]",
+		"select * from TestProto where myString ilike 'EDGE%' match-case":                 "[Edge-10% edge_1]",
+		"select * from TestProto where myString like 'edge_1'":                            "[edge_1]",
+		"select * from TestProto where myString like 'core-1'":                            "[]",
+		"select * from TestProto where myString like '%-1%'":                              "[Edge-10% core-1.lan]",
+		`select * from TestProto where myString like '%10\%'`:                             "[Edge-10%]",
+		`select * from TestProto where myString like 'edge\_%'`:                           "[edge_1]",
+		`select * from TestProto where myString like '%\\%'`:                              "[]",
+		"select * from TestProto where myString like '%!%' escape '!'":                    "[Edge-10%]",
+		"select * from TestProto where myString like 'edge!_%' escape '!'":                "[edge_1]",
+		"select * from TestProto where myString like 'a*b'":                               "[a*b]",
+		"select * from TestProto where not myString like 'edge%'":                         "[Edge-10% core-1.lan a*b]",
+		"select * from TestProto where myString not ilike 'edge%' and myInt32 < 4":        "[core-1.lan]",
+		"select * from TestProto where not (myString not glob '*.lan' or myInt32 > 3)":    "[core-1.lan]",
+		"select * from TestProto where myString NOT like '%!%' escape '!'":                "[edge_1 core-1.lan a*b]",
+		"select * from TestProto where myString like concat(substr(myString, 1, 2), '%')": "[Edge-10% edge_1 core-1.lan a*b]",
+		"select * from TestProto where myString glob 'edge?1*'":                           "[edge_1]",
+		"select * from TestProto where myString glob 'Edge*'":                             "[Edge-10%]",
+		"select * from TestProto where myString glob 'core-1'":                            "[]",
+		"select * from TestProto where myString glob '*.lan'":                             "[core-1.lan]",
+		"select * from TestProto where myString glob 'a?b'":                               "[a*b]",
+		`select * from TestProto where myString glob 'a\*b' or myString glob '\*'`:        "[a*b]",
+		"select * from TestProto where myInt32 > 1 and myString like '%1%'":               "[edge_1 core-1.lan]",
+	} {
+		if !checkFilterNames(query, items, expected, t) {
+			return
+		}
+	}
+	q, _, e := createQuery("select * from TestProto where myInt32 like '1%'")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	if _, e = q.FilterWithError(items, false); e == nil {
+		Log.Fail(t, "Expected an error matching a pattern against an int32")
+	}
+}
+
+// TestStrictEquality verifies that = compares strings exactly in strict mode, while
+// the default mode keeps the wildcard matching of '*'.
+func TestStrictEquality(t *testing.T) {
+	items := likeItems()
+	for query, expected := range map[string][2]string{
+		"select * from TestProto where myString = 'a*b'":                   {"[core-1.lan a*b]", "[a*b]"},
+		"select * from TestProto where myString = '*'":                     {"[Edge-10% edge_1 core-1.lan a*b]", "[]"},
+		"select * from TestProto where myString = 'EDGE_1' or myInt32 = 4": {"[edge_1 a*b]", "[edge_1 a*b]"},
+		"select * from TestProto where not myString = 'edge*'":             {"[core-1.lan a*b]", "[Edge-10% edge_1 core-1.lan a*b]"},
+	} {
+		for i, strict := range []bool{false, true} {
+			q, _, e := createQuery(query)
+			if e != nil {
+				Log.Fail(t, e)
+				return
+			}
+			q.SetStrictEquality(strict)
+			if q.StrictEquality() != strict {
+				Log.Fail(t, "Expected strict equality ", strict)
+				return
+			}
+			names := make([]string, 0)
+			for _, item := range q.Filter(items, false) {
+				names = append(names, item.(*testtypes.TestProto).MyString)
+			}
+			if fmt.Sprint(names) != expected[i] {
+				Log.Fail(t, "Expected ", expected[i], " for ", query, " with strict ", strict, ", got ", names)
+				return
+			}
+		}
+	}
+}
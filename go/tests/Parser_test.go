@@ -15,6 +15,7 @@ limitations under the License.
 package tests
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -235,3 +236,293 @@ func TestParseMapReduce(t *testing.T) {
 		return
 	}
 }
+
+// TestKeywordInsideLiteral verifies that clause keywords inside quoted values do not split the query.
+func TestKeywordInsideLiteral(t *testing.T) {
+	q, e := NewQuery("select * from table1 where name='fromage' and status='paged' and note=\"match-case\"", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if q.Query().RootType != "table1" {
+		Log.Fail(t, "Expected root type table1, got ", q.Query().RootType)
+		return
+	}
+	if q.Query().MatchCase {
+		Log.Fail(t, "Expected match-case inside a literal to be ignored")
+		return
+	}
+	testExpression(q, "(name='fromage' and status='paged' and note=match-case)", t)
+}
+
+// TestKeywordInsidePropertyName verifies that property names containing or equal to keywords parse correctly.
+func TestKeywordInsidePropertyName(t *testing.T) {
+	q, e := NewQuery("select fromDate,pageCount from table1 where limit>5 and ordered=true sort-by fromDate", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	testColumns(q, []string{"fromDate", "pageCount"}, t)
+	testExpression(q, "(limit>5 and ordered=true)", t)
+	if q.Query().Limit != 0 {
+		Log.Fail(t, "Expected no limit, got ", q.Query().Limit)
+		return
+	}
+	if q.Query().SortBy != "fromDate" {
+		Log.Fail(t, "Expected sort-by fromDate, got ", q.Query().SortBy)
+	}
+}
+
+// TestQuotedLiteralEscapes verifies that escaped quotes and AND/OR keywords inside literals are preserved.
+func TestQuotedLiteralEscapes(t *testing.T) {
+	q, e := NewQuery("select * from table1 where name='rock and roll' or title=\"say \\\"hi\\\"\"", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	testExpression(q, "(name='rock and roll' or title=say \"hi\")", t)
+}
+
+// TestUnterminatedLiteral verifies that an unterminated string literal is a parse error.
+func TestUnterminatedLiteral(t *testing.T) {
+	_, e := NewQuery("select * from table1 where name='fromage", Log)
+	if e == nil {
+		Log.Fail(t, "Expected an error for an unterminated literal")
+	}
+}
+
+// TestTokenize verifies the token categories produced by the lexer.
+func TestTokenize(t *testing.T) {
+	tokens, e := Tokenize("select a.b from T where x>=1.5e-3 and y in ['a','b'] sort-by a.b")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := []TokenType{TokenKeyword, TokenIdent, TokenKeyword, TokenIdent, TokenKeyword, TokenIdent, TokenOper,
+		TokenNumber, TokenKeyword, TokenIdent, TokenKeyword, TokenOpen, TokenString, TokenComma, TokenString,
+		TokenClose, TokenKeyword, TokenIdent, TokenEOF}
+	if len(tokens) != len(expected) {
+		Log.Fail(t, "Expected ", len(expected), " tokens, got ", len(tokens))
+		return
+	}
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			Log.Fail(t, "Unexpected token type for '", tok.Text, "' at ", i)
+			return
+		}
+	}
+	if tokens[16].Value != SortBy {
+		Log.Fail(t, "Expected sort-by keyword, got ", tokens[16].Value)
+	}
+}
+
+// TestSyntaxErrorPosition verifies the location, token and expected set of a syntax error.
+func TestSyntaxErrorPosition(t *testing.T) {
+	_, e := NewQuery("select * from table1\nwhere (a=1 or b=2) anf c=3", Log)
+	var se *SyntaxError
+	if !errors.As(e, &se) {
+		Log.Fail(t, "Expected a SyntaxError, got ", e)
+		return
+	}
+	if se.Line != 2 || se.Column != 20 || se.Offset != 40 {
+		Log.Fail(t, "Unexpected location line ", se.Line, " column ", se.Column, " offset ", se.Offset)
+		return
+	}
+	if se.Token != "anf" {
+		Log.Fail(t, "Expected offending token anf, got ", se.Token)
+		return
+	}
+	if len(se.Expected) == 0 || se.Expected[0] != "and" {
+		Log.Fail(t, "Expected and/or in the expected set, got ", se.Expected)
+		return
+	}
+	if se.Snippet() != "where (a=1 or b=2) anf c=3\n                   ^^^" {
+		Log.Fail(t, "Unexpected snippet:\n", se.Snippet())
+	}
+}
+
+// TestSyntaxErrorMissingBracket verifies that a missing close bracket is reported at the end of the query.
+func TestSyntaxErrorMissingBracket(t *testing.T) {
+	text := "select * from table1 where (a=1 or b=2"
+	_, e := NewQuery(text, Log)
+	var se *SyntaxError
+	if !errors.As(e, &se) {
+		Log.Fail(t, "Expected a SyntaxError, got ", e)
+		return
+	}
+	if se.Offset != len(text) || se.Token != "" || se.Expected[0] != ")" {
+		Log.Fail(t, "Unexpected error: ", se.Error())
+	}
+}
+
+// TestSyntaxErrorMissingComparator verifies the expected comparator set when no comparator is found.
+func TestSyntaxErrorMissingComparator(t *testing.T) {
+	_, e := NewQuery("select * from table1 where a 1 and b=2", Log)
+	var se *SyntaxError
+	if !errors.As(e, &se) {
+		Log.Fail(t, "Expected a SyntaxError, got ", e)
+		return
+	}
+	if se.Token != "and" || se.Column != 32 || len(se.Expected) != 11 {
+		Log.Fail(t, "Unexpected error: ", se.Error())
+	}
+}
+
+// TestNotComparison verifies that a negated comparison is parsed into the opposite comparison.
+func TestNotComparison(t *testing.T) {
+	q, e := NewQuery("select * from table1 where not a=1 and b=2", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	testExpression(q, "(a!=1 and b=2)", t)
+	for text, expected := range map[string]string{"a>1": "<=", "a<=1": ">", "a<1": ">=", "a>=1": "<", "a!=1": "=", "a in [1]": " not in "} {
+		q, e = NewQuery("select * from table1 where not "+text, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		if oper := q.Query().Criteria.Condition.Comparator.Oper; oper != expected {
+			Log.Fail(t, "Expected ", expected, " for not ", text, " but got ", oper)
+		}
+	}
+}
+
+// TestNotGroup verifies that negated groups are rewritten by De Morgan's laws.
+func TestNotGroup(t *testing.T) {
+	texts := map[string]string{
+		"a=1 and NOT (b=2 or c=3)":          "(a=1) and ((b!=2 and c!=3))",
+		"not ((a=1 or b=2) and c=3) or d=4": "(((a!=1 and b!=2)) or (c!=3)) or (d=4)",
+		"not not a=1":                       "(a=1)",
+		"x not in [1,2] and not y in [3]":   "(x not in [1,2] and y not in [3])",
+	}
+	for text, expected := range texts {
+		q, e := NewQuery("select * from table1 where "+text, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		testExpression(q, expected, t)
+	}
+}
+
+// TestNotRewrite verifies that a negation parses into the same expression as its
+// explicitly written opposite.
+func TestNotRewrite(t *testing.T) {
+	texts := map[string]string{
+		"not (a=1)":                "(a!=1)",
+		"not (b=2 or c>3)":         "(b!=2 and c<=3)",
+		"not (a=1 and not (b=2))":  "(a!=1 or (b=2))",
+		"not (a in [1,2] or b<=3)": "(a not in [1,2] and b>3)",
+	}
+	for text, opposite := range texts {
+		q, e := NewQuery("select * from table1 where "+text, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		expected, e := NewQuery("select * from table1 where "+opposite, Log)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		testExpression(q, StringExpression(expected.Query().Criteria), t)
+	}
+}
+
+// TestBoolExpressionPrecedence verifies that AND binds tighter than OR and NOT binds tighter than AND.
+func TestBoolExpressionPrecedence(t *testing.T) {
+	texts := map[string]string{
+		"a=1 or b=2 and c=3":              "a=1 or b=2 and c=3",
+		"(a=1 or b=2) and c=3":            "(a=1 or b=2) and c=3",
+		"a=1 and b=2 or c=3 and d=4":      "a=1 and b=2 or c=3 and d=4",
+		"not a=1 and b=2":                 "not a=1 and b=2",
+		"not (a=1 or b=2) or c=3":         "not (a=1 or b=2) or c=3",
+		"a=1 or not (b=2 and c=3) or d=4": "a=1 or not (b=2 and c=3) or d=4",
+	}
+	for text, expected := range texts {
+		expr, e := NewBoolExpression(text)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		if expr.String() != expected {
+			Log.Fail(t, "Expected ", expected, " but got ", expr.String())
+			return
+		}
+	}
+	expr, _ := NewBoolExpression("a=1 or b=2 and c=3")
+	if expr.Operation != BoolOr || len(expr.Operands) != 2 || expr.Operands[1].Operation != BoolAnd {
+		Log.Fail(t, "Expected an OR of a comparison and an AND")
+	}
+}
+
+// TestBoolExpressionLinkedForm verifies the conversion between the boolean tree and the linked form.
+func TestBoolExpressionLinkedForm(t *testing.T) {
+	texts := map[string]string{
+		"a=1 or b=2 and c=3":                         "a=1 or b=2 and c=3",
+		"a=1 and b=2 or c=3":                         "a=1 and b=2 or c=3",
+		"(a=1 or b=2) and c=3":                       "(a=1 or b=2) and c=3",
+		"a=1 or b=2 and (c=3 or d=4) and e=5 or f=6": "a=1 or b=2 and (c=3 or d=4) and e=5 or f=6",
+		"not a=1 or b=2 and not (c=3 and d=4)":       "a!=1 or b=2 and (c!=3 or d!=4)",
+		"not (a=1 and not (b=2 or c<3))":             "(a!=1 or (b=2 or c<3))",
+	}
+	for text, expected := range texts {
+		expr, e := NewBoolExpression(text)
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		back, e := BoolExpressionOf(expr.L8Expression())
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+		if back.String() != expected {
+			Log.Fail(t, "Expected ", expected, " but got ", back.String())
+			return
+		}
+	}
+	q, e := NewQuery("select * from table1 where a=1 or b=2 and (c=3)", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if q.Query().Criteria.Condition.Next != nil {
+		Log.Fail(t, "Expected the condition chain to stop at the precedence boundary")
+	}
+}
+
+// TestSortKeys verifies parsing of multi-column sort-by clauses.
+func TestSortKeys(t *testing.T) {
+	q, e := NewQuery("select * from table1 sort-by region asc, cpu DESC nulls first, name descending", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := []string{"region asc nulls first", "cpu desc nulls first", "name desc nulls last"}
+	keys := q.SortKeys()
+	if len(keys) != len(expected) {
+		Log.Fail(t, "Expected ", len(expected), " sort keys, got ", len(keys))
+		return
+	}
+	for i, key := range keys {
+		if key.String() != expected[i] {
+			Log.Fail(t, "Expected ", expected[i], " but got ", key.String())
+			return
+		}
+	}
+	keys, e = ParseSortBy("a nulls last", false)
+	if e != nil || len(keys) != 1 || keys[0].Descending || keys[0].NullsFirst {
+		Log.Fail(t, "Expected an ascending key with nulls last")
+		return
+	}
+	for _, text := range []string{"a desc b", "a nulls", "a,", "a asc nulls middle"} {
+		_, e = ParseSortBy(text, false)
+		syntaxErr := &SyntaxError{}
+		if !errors.As(e, &syntaxErr) {
+			Log.Fail(t, "Expected a syntax error for ", text)
+			return
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// partialOf aggregates the items into a partial state and sends it through its wire format.
+func partialOf(q *interpreter.Query, items []interface{}, t *testing.T) *structpb.Struct {
+	partial, e := q.AggregatePartial(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return nil
+	}
+	data, e := proto.Marshal(partial)
+	if e != nil {
+		Log.Fail(t, e)
+		return nil
+	}
+	received := &structpb.Struct{}
+	if e = proto.Unmarshal(data, received); e != nil {
+		Log.Fail(t, e)
+		return nil
+	}
+	return received
+}
+
+// sameValue reports whether two aggregate values are equal, allowing floating point
+// results to differ by rounding.
+func sameValue(a, b interface{}) bool {
+	fa, aok := a.(float64)
+	fb, bok := b.(float64)
+	if aok && bok {
+		return math.Abs(fa-fb) <= 1e-9*math.Max(1, math.Abs(fa))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sameRows reports whether two lists of aggregate rows are equal.
+func sameRows(a, b []map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for k, v := range a[i] {
+			if !sameValue(v, b[i][k]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkMergedAggregate verifies that merging the partial states of the items, split
+// across three nodes, gives the same rows and types as aggregating them on a single node.
+func checkMergedAggregate(query string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	items := sortItems()
+	expected, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	merged, e := q.MergePartials(partialOf(q, items[:5], t), partialOf(q, items[5:12], t))
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	result, e := q.Finalize(merged, partialOf(q, items[12:], t))
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	if !sameRows(expected.Rows, result.Rows) || expected.Total != result.Total ||
+		!reflect.DeepEqual(expected.Types, result.Types) {
+		Log.Fail(t, "Merged result differs for ", query, ": ", result.Rows, " expected ", expected.Rows)
+		return false
+	}
+	return true
+}
+
+// TestMergedAggregates verifies that merged partial aggregates equal single-node aggregates.
+func TestMergedAggregates(t *testing.T) {
+	queries := []string{
+		"select myString,count(*),count(distinct myInt32),sum(myInt32),avg(myInt32),min(myInt32),max(myString) " +
+			"from TestProto group-by myString",
+		"select myString,median(myInt32),percentile(myInt32,90),stddev(myInt32),variance(myInt32) from TestProto group-by myString",
+		"select myString,first(myInt32),last(myInt32),collect(myInt32) from TestProto group-by myString",
+		"select count(*),sum(myInt32),avg(myInt32),variance(myInt32),count(distinct myString) from TestProto",
+		"select myString,count(*) from TestProto group-by myString having count > 2 sort-by count desc limit 2 page 1",
+	}
+	for _, query := range queries {
+		if !checkMergedAggregate(query, t) {
+			return
+		}
+	}
+}
+
+// TestMergePartialsValidation verifies that partial states of another query are rejected.
+func TestMergePartialsValidation(t *testing.T) {
+	q1, _, _ := createQuery("select myString,count(*) from TestProto group-by myString")
+	q2, _, _ := createQuery("select myString,sum(myInt32) from TestProto group-by myString")
+	partial := partialOf(q1, sortItems(), t)
+	if _, e := q2.MergePartials(partial); e == nil {
+		Log.Fail(t, "Expected an error for a partial state of another query")
+		return
+	}
+	q3, _, _ := createQuery("select myString from TestProto")
+	if _, e := q3.AggregatePartial(sortItems()); e == nil {
+		Log.Fail(t, "Expected an error for a query without aggregates")
+	}
+}
+
+// TestMergedAggregateOverflow verifies that an overflow on one node or while merging
+// follows the error policy.
+func TestMergedAggregateOverflow(t *testing.T) {
+	q, _, e := createQuery("select sum(myInt64) from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	node := CreateTestModelInstance(0)
+	node.MyInt64 = math.MaxInt64
+	partial := partialOf(q, []interface{}{node}, t)
+	result, e := q.Finalize(partial, partial)
+	if e != nil || len(result.Rows) != 1 || result.Rows[0]["sumMyInt64"] != nil {
+		Log.Fail(t, "Expected a nil sum after an overflow while merging")
+		return
+	}
+	failed := partialOf(q, []interface{}{node, node}, t)
+	result, e = q.Finalize(partial, failed)
+	if e != nil || result.Rows[0]["sumMyInt64"] != nil {
+		Log.Fail(t, "Expected a nil sum after an overflow on a node")
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	if _, e = q.Finalize(partial, failed); e == nil {
+		Log.Fail(t, "Expected the error of the node while merging")
+		return
+	}
+	if _, e = q.Finalize(partial, partial); !errors.Is(e, interpreter.ErrOverflow) {
+		Log.Fail(t, "Expected an overflow error while merging, got ", e)
+		return
+	}
+	if _, e = q.AggregatePartial([]interface{}{node, node}); !errors.Is(e, interpreter.ErrOverflow) {
+		Log.Fail(t, "Expected an overflow error on the node, got ", e)
+	}
+}
+
+// TestPartialEncodingError verifies that a state that cannot be encoded follows the error policy.
+func TestPartialEncodingError(t *testing.T) {
+	q, _, e := createQuery("select collect(mySingle) from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	items := []interface{}{CreateTestModelInstance(1)}
+	if _, e = q.AggregatePartial(items); e != nil {
+		Log.Fail(t, "Expected the error to be kept in the partial state, got ", e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	_, e = q.AggregatePartial(items)
+	var ee *interpreter.EvaluationError
+	if !errors.As(e, &ee) || ee.Operator != "collect(mySingle)" {
+		Log.Fail(t, "Expected an evaluation error for the partial state, got ", e)
+	}
+}
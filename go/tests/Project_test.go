@@ -0,0 +1,220 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// projectItem returns an object with two models and two tags to project.
+func projectItem() *testtypes.TestProto {
+	node := CreateTestModelInstance(1)
+	node.MyString = "host1"
+	node.MyStringSlice = []string{"a", "b"}
+	node.MyModelSlice = []*testtypes.TestProtoSub{{MyString: "m1", MyInt64: 1}, {MyString: "m2", MyInt64: 2}}
+	return node
+}
+
+// TestParseAliases verifies the parsing and validation of column aliases.
+func TestParseAliases(t *testing.T) {
+	q, e := parser.NewQuery("select myString as host, myInt32 as 'model id' from TestProto", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if q.Query().Properties[0] != "myString as host" || q.Query().Properties[1] != "myInt32 as 'model id'" {
+		Log.Fail(t, "Unexpected canonical columns ", q.Query().Properties)
+		return
+	}
+	if parser.ColumnName(q.Query().Properties[1]) != "model id" {
+		Log.Fail(t, "Unexpected column name ", parser.ColumnName(q.Query().Properties[1]))
+		return
+	}
+	invalid := map[string]string{
+		"select myString as host, myInt32 as host from TestProto": "host",
+		"select myString as myInt32, myInt32 from TestProto":      "myInt32",
+		"select myString as from TestProto":                       "as",
+		"select myString as a.b from TestProto":                   "a.b",
+	}
+	for query, token := range invalid {
+		_, e = parser.NewQuery(query, Log)
+		var syntaxError *parser.SyntaxError
+		if !errors.As(e, &syntaxError) || syntaxError.Token != token || syntaxError.Offset != strings.Index(query, token) {
+			Log.Fail(t, "Expected a syntax error at the first ", token, " of ", query, ", got ", e)
+			return
+		}
+	}
+}
+
+// TestAggregateAliases verifies aliases of aggregates and group-by columns, and their use in having and sort-by.
+func TestAggregateAliases(t *testing.T) {
+	items := distinctItems()
+	if !checkExecuteRows("select myString as region, count(*) as total from TestProto group-by myString having total > 2 sort-by region",
+		items, []string{"region", "total"}, []string{"A,4"}, t) {
+		return
+	}
+	if !checkExecuteRows("select myString as region, count(*) as total from TestProto group-by myString sort-by total",
+		items, []string{"region", "total"}, []string{"B,2", "A,4"}, t) {
+		return
+	}
+	checkExecuteRows("select distinct myString as region, count(*) as n from TestProto sort-by myString",
+		items, []string{"region", "n"}, []string{"A,4", "B,2"}, t)
+}
+
+// TestProject verifies the flat row of a projected object and its JSON column order.
+func TestProject(t *testing.T) {
+	q, _, e := createQuery("select myString as host, myInt32, mySingle.myString as single from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	item := projectItem()
+	item.MySingle = &testtypes.TestProtoSub{MyString: "s"}
+	row, e := q.Project(item)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if fmt.Sprint(row.Columns) != "[host myInt32 single]" {
+		Log.Fail(t, "Unexpected columns ", row.Columns)
+		return
+	}
+	if v, ok := row.Get("host"); !ok || v != "host1" {
+		Log.Fail(t, "Unexpected host ", v)
+		return
+	}
+	data, e := json.Marshal(row)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := fmt.Sprintf(`{"host":"host1","myInt32":%d,"single":"s"}`, item.MyInt32)
+	if string(data) != expected {
+		Log.Fail(t, "Expected ", expected, ", got ", string(data))
+		return
+	}
+	aggregate, _, _ := createQuery("select count(*) from TestProto")
+	if _, e = aggregate.Project(item); e == nil {
+		Log.Fail(t, "Expected an error projecting an aggregate query")
+	}
+}
+
+// TestProjectRepeated verifies the array, join and expand modes of repeated paths.
+func TestProjectRepeated(t *testing.T) {
+	q, _, e := createQuery("select myString as host, myModelSlice.myString as model, myModelSlice.myInt64 as id, myStringSlice as tag from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	item := projectItem()
+	row, e := q.Project(item)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if fmt.Sprint(row.Values) != "[host1 [m1 m2] [1 2] [a b]]" {
+		Log.Fail(t, "Unexpected array values ", row.Values)
+		return
+	}
+	q.SetRepeatedMode(interpreter.RepeatedJoin)
+	q.SetJoinSeparator("|")
+	if row, e = q.Project(item); e != nil || fmt.Sprint(row.Values) != "[host1 m1|m2 1|2 a|b]" {
+		Log.Fail(t, "Unexpected joined values ", row.Values, e)
+		return
+	}
+	q.SetRepeatedMode(interpreter.RepeatedExpand)
+	if _, e = q.Project(item); e == nil {
+		Log.Fail(t, "Expected an error projecting an expanded object into a single row")
+		return
+	}
+	rows, e := q.ProjectRows(item)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	got := make([]string, 0, len(rows))
+	for _, r := range rows {
+		got = append(got, fmt.Sprint(r.Values))
+	}
+	expected := "[[host1 m1 1 a] [host1 m1 1 b] [host1 m2 2 a] [host1 m2 2 b]]"
+	if fmt.Sprint(got) != expected {
+		Log.Fail(t, "Expected ", expected, ", got ", got)
+		return
+	}
+	item.MyStringSlice = nil
+	if rows, e = q.ProjectRows(item); e != nil || len(rows) != 2 || rows[0].Values[3] != nil {
+		Log.Fail(t, "Unexpected rows for an empty repeated column ", rows, e)
+	}
+}
+
+// TestProjectExpandAncestor verifies that only the repeated columns under the same
+// repeated field are expanded together, whatever the number of their values.
+func TestProjectExpandAncestor(t *testing.T) {
+	q, _, e := createQuery("select myStringSlice, myInt32Slice from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetRepeatedMode(interpreter.RepeatedExpand)
+	item := projectItem()
+	item.MyInt32Slice = []int32{1, 2}
+	if rows := expandedValues(q, item, t); rows != "[[a 1] [a 2] [b 1] [b 2]]" {
+		Log.Fail(t, "Expected a cartesian product of sibling slices, got ", rows)
+		return
+	}
+	item.MyInt32Slice = []int32{1, 2, 3}
+	if rows := expandedValues(q, item, t); rows != "[[a 1] [a 2] [a 3] [b 1] [b 2] [b 3]]" {
+		Log.Fail(t, "Expected a cartesian product of sibling slices, got ", rows)
+		return
+	}
+	q, _, e = createQuery("select myModelSlice.myString, myStringSlice, myModelSlice.myInt64 from TestProto")
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetRepeatedMode(interpreter.RepeatedExpand)
+	if rows := expandedValues(q, item, t); rows != "[[m1 a 1] [m1 b 1] [m2 a 2] [m2 b 2]]" {
+		Log.Fail(t, "Expected the model fields to be zipped, got ", rows)
+		return
+	}
+	item.MyModelSlice = append(item.MyModelSlice, &testtypes.TestProtoSub{MyString: "m3", MyInt64: 3})
+	item.MyStringSlice = []string{"a", "b", "c"}
+	if rows := expandedValues(q, item, t); rows != "[[m1 a 1] [m1 b 1] [m1 c 1] [m2 a 2] [m2 b 2] [m2 c 2] [m3 a 3] [m3 b 3] [m3 c 3]]" {
+		Log.Fail(t, "Expected a slice of the same size not to be zipped with the models, got ", rows)
+	}
+}
+
+// expandedValues returns the values of the rows the object expands into.
+func expandedValues(q *interpreter.Query, item *testtypes.TestProto, t *testing.T) string {
+	rows, e := q.ProjectRows(item)
+	if e != nil {
+		Log.Fail(t, e)
+		return ""
+	}
+	values := make([]string, 0, len(rows))
+	for _, r := range rows {
+		values = append(values, fmt.Sprint(r.Values))
+	}
+	return fmt.Sprint(values)
+}
@@ -0,0 +1,323 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+	"github.com/saichler/l8types/go/ifs"
+	"github.com/saichler/l8types/go/testtypes"
+)
+
+// aboveAggregator counts the values above a threshold.
+type aboveAggregator struct {
+	threshold int64 // The threshold
+	count     int64 // The number of values above the threshold
+}
+
+func (this *aboveAggregator) Add(value interface{}) error {
+	if value != nil && value.(int64) > this.threshold {
+		this.count++
+	}
+	return nil
+}
+
+func (this *aboveAggregator) Result() interface{} {
+	return this.count
+}
+
+// extensionRegistry returns resources that introspected TestProto and a registry of the
+// test extensions: the comparators divisible_by and has_length, the functions is_even,
+// clamp and join_all and the aggregate function count_above.
+func extensionRegistry(t *testing.T) (ifs.IResources, *interpreter.Registry) {
+	res, _ := CreateResources(25000, 2, ifs.Trace_Level)
+	res.Introspector().Inspect(&testtypes.TestProto{})
+	r := interpreter.NewRegistry()
+	errs := []error{
+		r.RegisterComparator("divisible_by", &interpreter.UserComparator{
+			Left: interpreter.IntArg, Right: interpreter.IntArg,
+			Compare: func(left, right interface{}) (bool, error) {
+				if right.(int64) == 0 {
+					return false, errors.New("Division by zero")
+				}
+				return left.(int64)%right.(int64) == 0, nil
+			}}),
+		r.RegisterComparator("has_length", &interpreter.UserComparator{
+			Left: interpreter.AnyArg, Right: interpreter.IntArg,
+			Compare: func(left, right interface{}) (bool, error) {
+				v := reflect.ValueOf(left)
+				return v.Kind() == reflect.Slice && int64(v.Len()) == right.(int64), nil
+			}}),
+		r.RegisterFunction("is_even", &interpreter.UserFunction{
+			Args: []interpreter.ArgType{interpreter.IntArg}, Result: interpreter.BoolArg,
+			Call: func(args []interface{}) (interface{}, error) {
+				if args[0] == nil {
+					return nil, nil
+				}
+				return args[0].(int64)%2 == 0, nil
+			}}),
+		r.RegisterFunction("Clamp", &interpreter.UserFunction{
+			Args:   []interpreter.ArgType{interpreter.FloatArg, interpreter.FloatArg, interpreter.FloatArg},
+			Result: interpreter.FloatArg,
+			Call: func(args []interface{}) (interface{}, error) {
+				v, low, high := args[0].(float64), args[1].(float64), args[2].(float64)
+				if v < low {
+					return low, nil
+				}
+				if v > high {
+					return high, nil
+				}
+				return v, nil
+			}}),
+		r.RegisterFunction("join_all", &interpreter.UserFunction{
+			Args: []interpreter.ArgType{interpreter.StringArg}, Variadic: true, Result: interpreter.StringArg,
+			Call: func(args []interface{}) (interface{}, error) {
+				return fmt.Sprint(args...), nil
+			}}),
+		r.RegisterAggregate("count_above", &interpreter.UserAggregate{
+			Field: interpreter.IntArg, Args: []interpreter.ArgType{interpreter.IntArg},
+			New: func(args []interface{}) (interpreter.Aggregator, error) {
+				return &aboveAggregator{threshold: args[0].(int64)}, nil
+			}}),
+	}
+	for _, e := range errs {
+		if e != nil {
+			Log.Fail(t, e)
+		}
+	}
+	return res, r
+}
+
+// checkExtendedFilter filters the items by a query with the extensions of the registry and verifies the
+// MyString values of the matches.
+func checkExtendedFilter(res ifs.IResources, r *interpreter.Registry, query string, items []interface{}, expected string,
+	t *testing.T) bool {
+	q, e := interpreter.NewQueryWithRegistry(query, res, r)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	names := make([]string, 0)
+	for _, item := range q.Filter(items, false) {
+		names = append(names, reflect.ValueOf(item).Elem().FieldByName("MyString").String())
+	}
+	if fmt.Sprint(names) != expected {
+		Log.Fail(t, "Expected ", expected, " for ", query, ", got ", names)
+		return false
+	}
+	return true
+}
+
+// TestRegisterExtensions verifies the validation of extension names and definitions.
+func TestRegisterExtensions(t *testing.T) {
+	_, r := extensionRegistry(t)
+	compare := &interpreter.UserComparator{Compare: func(left, right interface{}) (bool, error) { return true, nil }}
+	for _, name := range []string{"lower", "count", "bucket", "and", "in", "1st", "a-b", "", "divisible_by", "clamp"} {
+		if e := r.RegisterComparator(name, compare); e == nil {
+			Log.Fail(t, "Expected an error registering ", name)
+			return
+		}
+	}
+	if r.RegisterComparator("no_compare", &interpreter.UserComparator{}) == nil ||
+		r.RegisterFunction("no_call", &interpreter.UserFunction{}) == nil ||
+		r.RegisterAggregate("no_new", nil) == nil ||
+		r.RegisterFunction("no_args", &interpreter.UserFunction{Variadic: true,
+			Call: func(args []interface{}) (interface{}, error) { return nil, nil }}) == nil {
+		Log.Fail(t, "Expected errors registering incomplete extensions")
+		return
+	}
+}
+
+// TestUserComparator verifies filtering by a user-defined comparator and the checks of its operands.
+func TestUserComparator(t *testing.T) {
+	res, r := extensionRegistry(t)
+	items := arithmeticItems()
+	for query, expected := range map[string]string{
+		"select * from TestProto where myInt32 divisible_by 2":                       "[n2 n4]",
+		"select * from TestProto where myInt64 DIVISIBLE_BY 20 or myInt32 = 1":       "[n1 n2 n4]",
+		"select * from TestProto where not myInt32 divisible_by 2 and myInt32 > 1":   "[n3]",
+		"select * from TestProto where myInt32 not divisible_by 2":                   "[n1 n3]",
+		"select * from TestProto where not (myInt32 not divisible_by 2)":             "[n2 n4]",
+		"select * from TestProto where (myInt32 + 1) divisible_by 3":                 "[n2]",
+		"select * from TestProto where myInt32 divisible_by '4'":                     "[n4]",
+		"select * from TestProto where myInt64 divisible_by myInt32 and myInt32 > 3": "[n4]",
+	} {
+		if !checkExtendedFilter(res, r, query, items, expected, t) {
+			return
+		}
+	}
+	// Repeated operands are passed as they are rather than value by value
+	for i, item := range items {
+		item.(*testtypes.TestProto).MyInt32Slice = make([]int32, i+1)
+	}
+	if !checkExtendedFilter(res, r, "select * from TestProto where myInt32Slice has_length 3", items, "[n3]", t) {
+		return
+	}
+	q, e := parser.NewQuery("select * from TestProto where myInt32 divisible_by 2 and myString = n2", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if where := parser.StringExpression(q.Query().Criteria); where != "(myint32 divisible_by 2 and mystring=n2)" {
+		Log.Fail(t, "Unexpected canonical where ", where)
+		return
+	}
+	for _, query := range []string{
+		"select * from TestProto where myInt32 divisible_by abc",
+		"select * from TestProto where myInt32 multiple_of 2",
+	} {
+		if _, e := interpreter.NewQueryWithRegistry(query, res, r); e == nil {
+			Log.Fail(t, "Expected an error for ", query)
+			return
+		}
+	}
+	if _, e := interpreter.NewQueryWithRegistry("select * from TestProto where myInt32 divisible_by 2", res,
+		interpreter.NewRegistry()); e == nil {
+		Log.Fail(t, "Expected the comparator of a registry to be unknown to other registries")
+	}
+	if _, e := interpreter.NewQuery("select * from TestProto where myInt32 divisible_by 2", res); e == nil {
+		Log.Fail(t, "Expected the comparator to be unknown without a registry")
+	}
+}
+
+// TestUserFunction verifies user-defined functions as predicates, operands and columns.
+func TestUserFunction(t *testing.T) {
+	res, r := extensionRegistry(t)
+	items := arithmeticItems()
+	for query, expected := range map[string]string{
+		"select * from TestProto where is_even(myInt32)":                      "[n2 n4]",
+		"select * from TestProto where not is_even(myInt32 + 1)":              "[n2 n4]",
+		"select * from TestProto where clamp(myFloat64, 1, 1.5) = 1.5":        "[n3 n4]",
+		"select * from TestProto where join_all(myString, '-', 'x') = 'n2-x'": "[n2]",
+	} {
+		if !checkExtendedFilter(res, r, query, items, expected, t) {
+			return
+		}
+	}
+	q, e := interpreter.NewQueryWithRegistry("select myString, clamp(myInt32, 2, 3) as c from TestProto "+
+		"sort-by clamp(myFloat64, 0, 1) desc, myString desc", res, r)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(result.Rows) != 4 || result.Rows[0]["myString"] != "n4" || result.Rows[0]["c"] != 3.0 ||
+		result.Rows[3]["myString"] != "n1" || result.Rows[3]["c"] != 2.0 {
+		Log.Fail(t, "Unexpected rows ", result.Rows)
+		return
+	}
+	for _, query := range []string{
+		"select * from TestProto where clamp(myFloat64, 1) > 0",
+		"select * from TestProto where clamp(myFloat64, 'low', 2) > 0",
+		"select * from TestProto where join_all() = x",
+		"select * from TestProto where no_such_function(myInt32)",
+	} {
+		if _, e := interpreter.NewQueryWithRegistry(query, res, r); e == nil {
+			Log.Fail(t, "Expected an error for ", query)
+			return
+		}
+	}
+	q, e = interpreter.NewQueryWithRegistry("select * from TestProto where is_even(myString)", res, r)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	q.SetErrorPolicy(interpreter.AbortOnError)
+	if _, e = q.FilterWithError(items, false); e == nil {
+		Log.Fail(t, "Expected an error for a string argument of is_even")
+	}
+}
+
+// TestUserAggregate verifies a user-defined aggregate function with its alias and HAVING.
+func TestUserAggregate(t *testing.T) {
+	res, r := extensionRegistry(t)
+	items := append(arithmeticItems(), arithmeticItems()...)
+	q, e := interpreter.NewQueryWithRegistry("select myString, count_above(myInt32, 2), count(*) from TestProto group-by myString "+
+		"having count_above(myInt32, 2) > 0 sort-by myString", res, r)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(q.Query().Aggregates) != 2 || q.Query().Aggregates[0].Alias != "countAbove2MyInt32" {
+		Log.Fail(t, "Unexpected aggregates ", q.Query().Aggregates)
+		return
+	}
+	result, e := q.Execute(items)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	rows := make([]string, 0)
+	for _, row := range result.Rows {
+		rows = append(rows, fmt.Sprint(row["myString"], ",", row["countAbove2MyInt32"]))
+	}
+	if fmt.Sprint(rows) != "[n3,2 n4,2]" {
+		Log.Fail(t, "Unexpected rows ", rows)
+		return
+	}
+	for _, query := range []string{
+		"select myString, count_above(myInt32) from TestProto group-by myString",
+		"select myString, count_above(myInt32, many) from TestProto group-by myString",
+		"select distinct myString, count_above(myInt32, 2) from TestProto",
+	} {
+		if _, e := interpreter.NewQueryWithRegistry(query, res, r); e == nil {
+			Log.Fail(t, "Expected an error for ", query)
+			return
+		}
+	}
+}
+
+// TestRegistryConcurrency verifies concurrent registrations and query creations over the same registry.
+func TestRegistryConcurrency(t *testing.T) {
+	res, r := extensionRegistry(t)
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- r.RegisterFunction(fmt.Sprint("fn", i), &interpreter.UserFunction{
+				Args: []interpreter.ArgType{interpreter.AnyArg},
+				Call: func(args []interface{}) (interface{}, error) { return args[0], nil }})
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, e := interpreter.NewQueryWithRegistry("select * from TestProto where is_even(myInt32)", res, r)
+			errs <- e
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		if e != nil {
+			Log.Fail(t, e)
+			return
+		}
+	}
+	if _, e := interpreter.NewQueryWithRegistry("select fn7(myString) from TestProto", res, r); e != nil {
+		Log.Fail(t, e)
+	}
+}
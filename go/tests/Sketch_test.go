@@ -0,0 +1,141 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/interpreter"
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+)
+
+// sketchValues returns the numbers 0 to n-1 in a fixed pseudo-random order.
+func sketchValues(n int) []interface{} {
+	values := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		values[i] = int64((i * 7919) % n)
+	}
+	return values
+}
+
+// mergedAccumulator accumulates the values on the given number of nodes and merges their states.
+func mergedAccumulator(fn string, values []interface{}, nodes int, t *testing.T) *interpreter.Accumulator {
+	merged := interpreter.NewAccumulator(fn)
+	size := (len(values) + nodes - 1) / nodes
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+		state, e := accumulate(fn, values[start:end]...).Partial()
+		if e != nil {
+			Log.Fail(t, e)
+			return nil
+		}
+		merged.Merge(state)
+	}
+	return merged
+}
+
+// TestApproxCountDistinct verifies the estimate of approx_count_distinct and its merged state.
+func TestApproxCountDistinct(t *testing.T) {
+	values := sketchValues(100000)
+	values = append(values, values[:50000]...)
+	for fn, bound := range map[string]float64{"approx_count_distinct": 0.01, "approx_count_distinct:0.05": 0.05} {
+		acc := accumulate(fn, values...)
+		estimate := acc.Result().(int64)
+		if math.Abs(float64(estimate)-100000) > 3*bound*100000 {
+			Log.Fail(t, "Estimate ", estimate, " of ", fn, " is out of bounds")
+			return
+		}
+		merged := mergedAccumulator(fn, values, 4, t)
+		if merged.Err() != nil || merged.Result() != estimate {
+			Log.Fail(t, "Expected merged estimate ", estimate, " of ", fn, ", got ", merged.Result(), " ", merged.Err())
+			return
+		}
+	}
+	if !checkAccumulator("approx_count_distinct", int64(3), int64(0), t, "a", "b", "a", nil, "c") {
+		return
+	}
+	state, _ := accumulate("approx_count_distinct:0.05", "a").Partial()
+	acc := accumulate("approx_count_distinct", "b")
+	acc.Merge(state)
+	if acc.Err() == nil {
+		Log.Fail(t, "Expected an error merging sketches of different precisions")
+	}
+}
+
+// TestApproxPercentile verifies the estimate of approx_percentile and its merged state.
+func TestApproxPercentile(t *testing.T) {
+	values := sketchValues(100000)
+	for _, p := range []float64{1, 25, 50, 90, 99.9} {
+		fn := "approx_percentile:" + strconv.FormatFloat(p, 'g', -1, 64)
+		exact := p / 100 * 99999
+		for _, acc := range []*interpreter.Accumulator{accumulate(fn, values...), mergedAccumulator(fn, values, 7, t)} {
+			estimate, ok := acc.Result().(float64)
+			if !ok || math.Abs(estimate-exact) > 0.01*100000 {
+				Log.Fail(t, "Estimate ", acc.Result(), " of ", fn, " is out of bounds, expected about ", exact)
+				return
+			}
+		}
+	}
+	// Fewer values than the compression are kept exactly
+	small := []interface{}{int32(10), int32(20), int32(30), int32(40), int32(50), int32(60)}
+	for _, p := range []string{"0", "10", "50", "95", "100"} {
+		exact := accumulate("percentile:"+p, small...).Result()
+		if !checkAccumulator("approx_percentile:"+p, exact, float64(0), t, small...) {
+			return
+		}
+	}
+	if !checkAccumulator("approx_percentile:50,0.1", nil, float64(0), t) {
+		return
+	}
+	state, _ := accumulate("approx_percentile:50,0.1", 1).Partial()
+	acc := accumulate("approx_percentile:50", 2)
+	acc.Merge(state)
+	if acc.Err() == nil {
+		Log.Fail(t, "Expected an error merging sketches of different error bounds")
+	}
+}
+
+// TestApproxAggregateQueries verifies parsing and map-reduce execution of approximate aggregates.
+func TestApproxAggregateQueries(t *testing.T) {
+	q, e := parser.NewQuery("select approx_count_distinct(myInt32),approx_count_distinct(myString, 0.05),"+
+		"approx_percentile(myInt32, 95),approx_percentile(myInt32, 50, 0.001) from TestProto", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	expected := []string{"approxCountDistinctMyInt32", "approxCountDistinct0_05MyString",
+		"approxPercentile95MyInt32", "approxPercentile50_0_001MyInt32"}
+	for i, agg := range q.Query().Aggregates {
+		if agg.Alias != expected[i] {
+			Log.Fail(t, "Expected alias ", expected[i], ", got ", agg.Alias)
+			return
+		}
+	}
+	for _, text := range []string{"approx_count_distinct(myInt32, 1.5)", "approx_count_distinct(myInt32, 0.1, 2)",
+		"approx_percentile(myInt32)", "approx_percentile(myInt32, 50, 0)"} {
+		if _, e = parser.NewQuery("select "+text+" from TestProto", Log); e == nil {
+			Log.Fail(t, "Expected an error for ", text)
+			return
+		}
+	}
+	checkMergedAggregate("select myString,approx_count_distinct(myInt32),approx_percentile(myInt32,50) "+
+		"from TestProto group-by myString", t)
+}
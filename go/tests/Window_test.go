@@ -0,0 +1,232 @@
+/*
+© 2025 Sharon Aicler (saichler@gmail.com)
+
+Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+You may obtain a copy of the License at:
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8ql/go/gsql/parser"
+	. "github.com/saichler/l8test/go/infra/t_resources"
+)
+
+// windowItems returns polling samples with a device in MyString, a time in MyInt64 and a value in MyInt32.
+func windowItems() []interface{} {
+	samples := []struct {
+		device string
+		time   int64
+		value  int32
+	}{{"a", 1, 10}, {"b", 1, 5}, {"a", 2, 13}, {"a", 3, 13}, {"b", 2, 9}, {"a", 4, 20}}
+	items := make([]interface{}, 0, len(samples))
+	for i, sample := range samples {
+		node := CreateTestModelInstance(i)
+		node.MyString = sample.device
+		node.MyInt64 = sample.time
+		node.MyInt32 = sample.value
+		items = append(items, node)
+	}
+	return items
+}
+
+// checkWindow executes the query over the window items and verifies the values of the
+// given column of the result rows, and that there is one row per object.
+func checkWindow(query string, column string, expected []string, t *testing.T) bool {
+	q, _, e := createQuery(query)
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	result, e := q.Execute(windowItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return false
+	}
+	if len(result.Rows) != len(result.Objects) {
+		Log.Fail(t, "Expected a row per object for ", query, ", got ", len(result.Rows), " rows and ", len(result.Objects), " objects")
+		return false
+	}
+	values := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values = append(values, fmt.Sprint(row[column]))
+	}
+	if strings.Join(values, " ") != strings.Join(expected, " ") {
+		Log.Fail(t, "Expected ", expected, " for ", column, " of ", query, ", got ", values)
+		return false
+	}
+	return true
+}
+
+// TestParseWindowFunctions verifies the parsing, canonical form and validation of window functions.
+func TestParseWindowFunctions(t *testing.T) {
+	valid := map[string]string{
+		"row_number()":                       "row_number()",
+		"RANK() over (sort-by myInt32 desc)": "rank() over (sort-by myInt32 desc nulls last)",
+		"lag(myInt32,2) over (partition by myString, myBool sort-by myInt64)": "lag(myInt32, 2) over (partition by myString, myBool sort-by myInt64 asc nulls first)",
+		"lead(myInt32, 1)":                          "lead(myInt32)",
+		"avg(myInt32) over (rows 3 preceding)":      "avg(myInt32) over (rows 3 preceding)",
+		"count(*) over ()":                          "count(*) over ()",
+		"sum(myInt32) over (partition by myString)": "sum(myInt32) over (partition by myString)",
+	}
+	for text, expected := range valid {
+		window, ok, e := parser.ParseWindowFunction(text)
+		if !ok || e != nil {
+			Log.Fail(t, "Expected a window function for ", text, ", got ", e)
+			return
+		}
+		if window.String() != expected {
+			Log.Fail(t, "Expected ", expected, " for ", text, ", got ", window.String())
+			return
+		}
+	}
+	for _, text := range []string{"sum(myInt32)", "myInt32", "median(myInt32) over ()", "bucket(myInt64, 5m)"} {
+		if _, ok, _ := parser.ParseWindowFunction(text); ok {
+			Log.Fail(t, "Expected no window function for ", text)
+			return
+		}
+	}
+	window, _, _ := parser.ParseWindowFunction("lag(myInt32, 3) over (partition by myString sort-by myInt64 rows 2 preceding)")
+	if window.Alias != "lag3MyInt32" || window.Offset != 3 || window.Preceding != 2 || len(window.PartitionBy) != 1 ||
+		len(window.SortBy) != 1 || window.SortBy[0].Property != "myInt64" {
+		Log.Fail(t, "Unexpected parsed window function ", window)
+		return
+	}
+	invalid := []string{"row_number(myInt32)", "lag()", "lag(myInt32, -1)", "lead(myInt32, x)",
+		"sum(myInt32) over (partition myString)", "rank() over (sort-by myInt32 rows x preceding)",
+		"rank() over (rows 2)", "rank() over (sort-by myInt32) extra", "row_number() over partition",
+		"sum(*) over ()"}
+	for _, text := range invalid {
+		if _, ok, e := parser.ParseWindowFunction(text); !ok || e == nil {
+			Log.Fail(t, "Expected an error for ", text)
+			return
+		}
+	}
+	invalidQueries := map[string]string{
+		"select row_number(),row_number() over (sort-by myInt64) from TestProto": "row_number",
+		"select count(*),row_number() from TestProto":                            "row_number",
+		"select myString,lag(myInt32, x) over (sort-by myInt64) from TestProto":  "x",
+		"select myString,lag(myInt32, 1, 2) from TestProto":                      "2",
+		"select myString,lead(myInt32 + 1) from TestProto":                       "myInt32",
+	}
+	for query, token := range invalidQueries {
+		_, e := parser.NewQuery(query, Log)
+		var syntaxError *parser.SyntaxError
+		if !errors.As(e, &syntaxError) || syntaxError.Token != token || syntaxError.Offset != strings.LastIndex(query, token) {
+			Log.Fail(t, "Expected a syntax error at the last ", token, " of ", query, ", got ", e)
+			return
+		}
+	}
+	if _, e := parser.NewQuery("select myString,lag(myInt32 from TestProto", Log); e == nil {
+		Log.Fail(t, "Expected an error for a missing close bracket")
+		return
+	}
+	q, e := parser.NewQuery("select myString,sum(myInt32) over (sort-by myInt64) from TestProto", Log)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if len(q.Query().Aggregates) != 0 || q.Query().Properties[1] != "sum(myInt32) over (sort-by myInt64 asc nulls first)" {
+		Log.Fail(t, "Expected a window function column, got ", q.Query().Properties, q.Query().Aggregates)
+	}
+}
+
+// TestWindowRanking verifies row_number, rank and dense_rank within partitions.
+func TestWindowRanking(t *testing.T) {
+	from := " from TestProto sort-by myString,myInt64"
+	if !checkWindow("select myString,row_number() over (partition by myString sort-by myInt64)"+from,
+		"rowNumber", []string{"1", "2", "3", "4", "1", "2"}, t) {
+		return
+	}
+	if !checkWindow("select row_number()"+from, "rowNumber", []string{"1", "2", "3", "4", "5", "6"}, t) {
+		return
+	}
+	if !checkWindow("select rank() over (partition by myString sort-by myInt32)"+from,
+		"rank", []string{"1", "2", "2", "4", "1", "2"}, t) {
+		return
+	}
+	checkWindow("select dense_rank() over (partition by myString sort-by myInt32)"+from,
+		"denseRank", []string{"1", "2", "2", "3", "1", "2"}, t)
+}
+
+// TestWindowLagLead verifies the values of preceding and following objects, and that
+// they are computed before paging.
+func TestWindowLagLead(t *testing.T) {
+	from := " from TestProto sort-by myString,myInt64"
+	if !checkWindow("select myInt32,lag(myInt32) over (partition by myString sort-by myInt64)"+from,
+		"lagMyInt32", []string{"<nil>", "10", "13", "13", "<nil>", "5"}, t) {
+		return
+	}
+	if !checkWindow("select lead(myInt32, 2) over (sort-by myInt64)"+from,
+		"lead2MyInt32", []string{"13", "13", "<nil>", "<nil>", "9", "20"}, t) {
+		return
+	}
+	if !checkWindow("select myInt32,lag(myInt32) over (partition by myString sort-by myInt64)"+from+" limit 2 page 1",
+		"lagMyInt32", []string{"13", "13"}, t) {
+		return
+	}
+	checkWindow("select myInt32,lag(myInt32) over (partition by myString sort-by myInt64)"+from+" limit 2 page 1",
+		"myInt32", []string{"13", "20"}, t)
+}
+
+// TestWindowAggregates verifies running, moving and whole partition aggregates.
+func TestWindowAggregates(t *testing.T) {
+	from := " from TestProto sort-by myString,myInt64"
+	if !checkWindow("select sum(myInt32) over (partition by myString sort-by myInt64)"+from,
+		"sumMyInt32", []string{"10", "23", "36", "56", "5", "14"}, t) {
+		return
+	}
+	if !checkWindow("select avg(myInt32) over (partition by myString sort-by myInt64 rows 1 preceding)"+from,
+		"avgMyInt32", []string{"10", "11.5", "13", "16.5", "5", "7"}, t) {
+		return
+	}
+	if !checkWindow("select count(*) over (partition by myString)"+from,
+		"count", []string{"4", "4", "4", "4", "2", "2"}, t) {
+		return
+	}
+	if !checkWindow("select max(myInt32) over (sort-by myInt64)"+from,
+		"maxMyInt32", []string{"10", "13", "13", "20", "10", "13"}, t) {
+		return
+	}
+	q, _, e := createQuery("select myString,sum(myInt32) over (partition by myString sort-by myInt64)" + from)
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	result, e := q.Execute(windowItems())
+	if e != nil {
+		Log.Fail(t, e)
+		return
+	}
+	if result.Types["sumMyInt32"] == nil || result.Types["sumMyInt32"].Kind().String() != "int64" ||
+		result.Types["myString"] == nil || result.Rows[0]["myString"] != "a" || result.Total != 6 {
+		Log.Fail(t, "Unexpected window result types ", result.Types, " or rows ", result.Rows)
+	}
+}
+
+// TestWindowComputedSortKey verifies windows sorted by expressions of the object.
+func TestWindowComputedSortKey(t *testing.T) {
+	from := " from TestProto sort-by myString,myInt64"
+	if !checkWindow("select row_number() over (partition by myString sort-by -myInt64)"+from,
+		"rowNumber", []string{"4", "3", "2", "1", "2", "1"}, t) {
+		return
+	}
+	if !checkWindow("select rank() over (sort-by myInt32 * 2 desc)"+from,
+		"rank", []string{"4", "2", "2", "1", "6", "5"}, t) {
+		return
+	}
+	checkWindow("select dense_rank() over (sort-by lower(myString))"+from,
+		"denseRank", []string{"1", "1", "1", "1", "2", "2"}, t)
+}